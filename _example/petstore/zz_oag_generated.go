@@ -1,21 +1,21 @@
 package petstore
 
 import (
-	bytes "bytes"
-	context "context"
-	json "encoding/json"
-	http "net/http"
-	url "net/url"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
 )
 
 // This file is automatically generated by oag (https://github.com/jbowes/oag)
 // DO NOT EDIT
 
-const baseURL = "https://petstore.swagger.io/api"
+const baseURL = "http://petstore.swagger.io/api"
 
 // Pet is a data type for API communication.
 type Pet struct {
-	ID   int     `json:"id"`
+	ID   int64   `json:"id"`
 	Name string  `json:"name"`
 	Tag  *string `json:"tag"` // Optional
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"strings"
 
@@ -11,28 +12,76 @@ import (
 
 // Config is the toplevel configuration for running oag
 type Config struct {
-	Document string `yaml:"document"`
-	Output   string `yaml:"output"`
+	Document string   `yaml:"document"`
+	Overlays []string `yaml:"overlays"`
+	Output   string   `yaml:"output"`
 	Package  struct {
 		Path string `yaml:"path"`
 		Name string `yaml:"name"`
 	} `yaml:"package"`
 
 	Boilerplate Boilerplate       `yaml:"boilerplate"`
+	Grouping    Grouping          `yaml:"grouping"`
+	Naming      string            `yaml:"naming"` // NamingPath or NamingOperationID
+	Pagination  Pagination        `yaml:"pagination"`
+	ListField   string            `yaml:"list_field"` // property of object responses holding the items of a list
 	Types       map[string]string `yaml:"types"`
 
 	StringFormats map[string]string `yaml:"string_formats"`
+	NumberFormats map[string]string `yaml:"number_formats"`
+
+	Validate        bool `yaml:"validate"`         // check request values before sending them
+	ResponseHeaders bool `yaml:"response_headers"` // return the parsed headers of responses
+	RangeIterators  bool `yaml:"range_iterators"`  // add range-over-func methods to iterators, for Go 1.23
 }
 
 // Boilerplate defines the options for boilerplate code generation
 type Boilerplate struct {
 	ClientPrefix string `yaml:"client_prefix"`
 
-	BaseURL  pkg.Visibility `yaml:"base_url"`
-	Backend  pkg.Visibility `yaml:"backend"`
-	Endpoint pkg.Visibility `yaml:"endpoint"`
+	BaseURL         pkg.Visibility `yaml:"base_url"`
+	Backend         pkg.Visibility `yaml:"backend"`
+	Endpoint        pkg.Visibility `yaml:"endpoint"`
+	ValidationError pkg.Visibility `yaml:"validation_error"`
+	Date            pkg.Visibility `yaml:"date"`
+}
+
+// Grouping defines how operations are grouped into clients
+type Grouping struct {
+	By     string `yaml:"by"`     // GroupByPath or GroupByTag
+	Prefix string `yaml:"prefix"` // path prefix to ignore, ie /v1
+	Nested bool   `yaml:"nested"` // nest clients for resources under other resources
+}
+
+// The ways operations can be grouped into clients.
+const (
+	GroupByPath = "path" // by the first segment of their path, the default
+	GroupByTag  = "tag"  // by their first tag, or else their path
+)
+
+// The ways methods can be named.
+const (
+	NamingPath        = "path"         // by HTTP method and path, the default
+	NamingOperationID = "operation_id" // by operationId, or else their path
+)
+
+// Pagination defines how iterators fetch the pages of a list after the first
+type Pagination struct {
+	Strategy string `yaml:"strategy"` // PageLink, PageCursor, PageOffset or PageNumber
+	Param    string `yaml:"param"`    // query parameter for the position of a page
+	Limit    string `yaml:"limit"`    // optional query parameter for the size of a page
+	Field    string `yaml:"field"`    // response field holding the next cursor
 }
 
+// The ways iterators can follow pages. Without a strategy, iterators hold a
+// single page.
+const (
+	PageLink   = "link"   // by the rel=next URL of the Link header
+	PageCursor = "cursor" // by a cursor in the response body
+	PageOffset = "offset" // by the offset of the first item in the page
+	PageNumber = "page"   // by the page number, starting from 1
+)
+
 // Load loads the configuration
 func Load(cfgFile string) (*Config, error) {
 	b, err := ioutil.ReadFile(cfgFile)
@@ -42,10 +91,16 @@ func Load(cfgFile string) (*Config, error) {
 
 	cfg := Config{
 		Output: "zz_oag_generated.go",
+		Grouping: Grouping{
+			By: GroupByPath,
+		},
+		Naming: NamingPath,
 		Boilerplate: Boilerplate{
-			BaseURL:  pkg.Private,
-			Backend:  pkg.Public,
-			Endpoint: pkg.Private,
+			BaseURL:         pkg.Private,
+			Backend:         pkg.Public,
+			Endpoint:        pkg.Private,
+			ValidationError: pkg.Public,
+			Date:            pkg.Public,
 		},
 	}
 
@@ -53,6 +108,34 @@ func Load(cfgFile string) (*Config, error) {
 		return nil, err
 	}
 
+	switch cfg.Grouping.By {
+	case GroupByPath, GroupByTag:
+	default:
+		return nil, fmt.Errorf("unknown grouping %q, expected %s or %s", cfg.Grouping.By, GroupByPath, GroupByTag)
+	}
+
+	switch cfg.Naming {
+	case NamingPath, NamingOperationID:
+	default:
+		return nil, fmt.Errorf("unknown naming %q, expected %s or %s", cfg.Naming, NamingPath, NamingOperationID)
+	}
+
+	switch cfg.Pagination.Strategy {
+	case "", PageLink:
+	case PageCursor:
+		if cfg.Pagination.Field == "" {
+			cfg.Pagination.Field = "next_cursor"
+		}
+		fallthrough
+	case PageOffset, PageNumber:
+		if cfg.Pagination.Param == "" {
+			cfg.Pagination.Param = cfg.Pagination.Strategy
+		}
+	default:
+		return nil, fmt.Errorf("unknown pagination strategy %q, expected %s, %s, %s or %s",
+			cfg.Pagination.Strategy, PageLink, PageCursor, PageOffset, PageNumber)
+	}
+
 	if cfg.Package.Name == "" {
 		parts := strings.Split(cfg.Package.Path, "/")
 		cfg.Package.Name = parts[len(parts)-1]
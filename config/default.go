@@ -15,14 +15,59 @@ package:
   # Optional: define a package name if it is different from the import path
   # name: {{.Name}}
 
+# Optional overlays or JSON patches to apply to the document, in order.
+# overlays:
+#   - ./openapi-overlay.yaml
+
+# Optional: group operations into clients by their first tag, rather than the
+# first segment of their path, ignore a prefix at the start of paths, and nest
+# clients for resources under other resources.
+# grouping:
+#   by: tag
+#   prefix: /api/v1
+#   nested: true
+
+# Optional: name methods after their operationId, rather than their path.
+# naming: operation_id
+
 # Optional mapping of definitions to types.
 # types:
 #   SomeDefinedType: github.com/org/package.TypeName
 
-# Optional mapping of formats for strings to types. Types must implement
-# the encoding.TextMarshaler/encoding.TextUnmarshaler interfaces.
+# Optional mapping of formats for strings to types, replacing the defaults,
+# such as time.Time for the date-time format. Types must implement the
+# encoding.TextMarshaler/encoding.TextUnmarshaler interfaces.
 # string_formats:
 #   telephone: github.com/org/package.TelephoneNumber
+
+# Optional mapping of formats for integers and numbers to types, replacing the
+# defaults, such as int64 for the int64 format. Types from other packages must
+# implement the encoding.TextMarshaler/encoding.TextUnmarshaler interfaces.
+# number_formats:
+#   int64: int
+#   decimal: github.com/org/package.Decimal
+
+# Optional: check request values against the limits of their schemas, such as
+# maxLength or minimum, before sending requests.
+# validate: true
+
+# Optional: return the parsed headers of successful responses, after the body.
+# response_headers: true
+
+# Optional: the property of object responses holding the items of a list,
+# which is otherwise detected as their only array of objects.
+# list_field: data
+
+# Optional: follow the pages of lists in iterators, by the Link header (link),
+# a cursor in the response body (cursor), or offset or page query parameters.
+# pagination:
+#   strategy: offset
+#   param: offset
+#   limit: limit
+
+# Optional: add All and Collect methods to iterators, for range-over-func
+# loops. Requires Go 1.23 or later.
+# range_iterators: true
 `))
 
 // WriteDefaultConfig writes a default configuration to the given io.Writer.
@@ -0,0 +1,159 @@
+// Package diag collects diagnostics about problems found in an openapi
+// document, so they may be reported together rather than one at a time.
+package diag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity is the severity of a Diagnostic
+type Severity int
+
+// Severities of diagnostics. Errors prevent generating a client, while
+// warnings note parts of the document that were skipped.
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+
+	return "error"
+}
+
+// Pos is a position in a source file. Line and Column start at 1, and are 0
+// when unknown.
+type Pos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// IsValid reports if the position has a known line.
+func (p Pos) IsValid() bool { return p.Line > 0 }
+
+func (p Pos) String() string {
+	s := p.File
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+
+	return s
+}
+
+// Diagnostic is a single problem found in a document.
+type Diagnostic struct {
+	Severity Severity
+	Pos      Pos
+	Pointer  string // JSON pointer to the problem, ie #/paths/~1pets/get
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	var parts []string
+	if pos := d.Pos.String(); pos != "" {
+		parts = append(parts, pos)
+	}
+	parts = append(parts, d.Severity.String())
+	if d.Pointer != "" {
+		parts = append(parts, d.Pointer)
+	}
+	parts = append(parts, d.Message)
+
+	return strings.Join(parts, ": ")
+}
+
+// List is a list of diagnostics. The zero value is an empty list, ready to
+// use. A List implements the error interface, for use when it holds errors.
+type List []Diagnostic
+
+// Errorf adds an error at the given JSON pointer to the list.
+func (l *List) Errorf(pointer, format string, args ...interface{}) {
+	l.add(Error, pointer, format, args...)
+}
+
+// Warnf adds a warning at the given JSON pointer to the list.
+func (l *List) Warnf(pointer, format string, args ...interface{}) {
+	l.add(Warning, pointer, format, args...)
+}
+
+func (l *List) add(s Severity, pointer, format string, args ...interface{}) {
+	*l = append(*l, Diagnostic{
+		Severity: s,
+		Pointer:  pointer,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// HasErrors reports if any diagnostic in the list is an error.
+func (l List) HasErrors() bool {
+	for _, d := range l {
+		if d.Severity == Error {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Locator finds the source position of the value at a JSON pointer.
+type Locator interface {
+	Locate(pointer string) Pos
+}
+
+// Locate sets the position of all diagnostics without a known position, using
+// their JSON pointer.
+func (l List) Locate(loc Locator) {
+	for i := range l {
+		if !l[i].Pos.IsValid() {
+			l[i].Pos = loc.Locate(l[i].Pointer)
+		}
+	}
+}
+
+// Sort sorts the list by file and position. Diagnostics without a known line
+// are kept in the order they were added, after those with one.
+func (l List) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Pos, l[j].Pos
+		switch {
+		case a.File != b.File:
+			return a.File < b.File
+		case a.IsValid() != b.IsValid():
+			return a.IsValid()
+		case a.Line != b.Line:
+			return a.Line < b.Line
+		default:
+			return a.Column < b.Column
+		}
+	})
+}
+
+// Error implements the error interface, listing every diagnostic on its own
+// line.
+func (l List) Error() string {
+	lines := make([]string, len(l))
+	for i, d := range l {
+		lines[i] = d.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Err returns the list as an error if it contains any errors, or nil
+// otherwise.
+func (l List) Err() error {
+	if !l.HasErrors() {
+		return nil
+	}
+
+	return l
+}
@@ -0,0 +1,81 @@
+package diag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiagnosticString(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   Diagnostic
+		out  string
+	}{
+		{"message only", Diagnostic{Message: "bad"}, "error: bad"},
+		{"pointer", Diagnostic{Pointer: "#/paths/~1pets", Message: "bad"}, "error: #/paths/~1pets: bad"},
+		{"file", Diagnostic{Pos: Pos{File: "spec.yaml"}, Message: "bad"}, "spec.yaml: error: bad"},
+		{
+			"position",
+			Diagnostic{Severity: Warning, Pos: Pos{File: "spec.yaml", Line: 12, Column: 3}, Pointer: "#/a", Message: "bad"},
+			"spec.yaml:12:3: warning: #/a: bad",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := tc.in.String(); out != tc.out {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestListErr(t *testing.T) {
+	var l List
+	if l.Err() != nil {
+		t.Error("expected no error for empty list")
+	}
+
+	l.Warnf("#/a", "skipped %s", "a")
+	if l.Err() != nil {
+		t.Error("expected no error for warnings")
+	}
+
+	l.Errorf("#/b", "bad %s", "b")
+	err := l.Err()
+	if err == nil {
+		t.Fatal("expected error but got none")
+	}
+
+	expected := "warning: #/a: skipped a\nerror: #/b: bad b"
+	if err.Error() != expected {
+		t.Error("got:", err.Error(), "expected:", expected)
+	}
+}
+
+type fileLocator string
+
+func (f fileLocator) Locate(string) Pos { return Pos{File: string(f)} }
+
+func TestListSort(t *testing.T) {
+	l := List{
+		{Pointer: "#/c"},
+		{Pos: Pos{File: "a.yaml", Line: 3, Column: 1}},
+		{Pos: Pos{File: "a.yaml", Line: 1, Column: 5}},
+		{Pointer: "#/b"},
+		{Pos: Pos{File: "a.yaml", Line: 1, Column: 2}},
+	}
+	l.Locate(fileLocator("a.yaml"))
+	l.Sort()
+
+	expected := List{
+		{Pos: Pos{File: "a.yaml", Line: 1, Column: 2}},
+		{Pos: Pos{File: "a.yaml", Line: 1, Column: 5}},
+		{Pos: Pos{File: "a.yaml", Line: 3, Column: 1}},
+		{Pos: Pos{File: "a.yaml"}, Pointer: "#/c"},
+		{Pos: Pos{File: "a.yaml"}, Pointer: "#/b"},
+	}
+	if !reflect.DeepEqual(l, expected) {
+		t.Error("got:", l, "expected:", expected)
+	}
+}
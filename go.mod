@@ -27,5 +27,6 @@ require (
 	golang.org/x/sys v0.0.0-20210809222454-d867a43fc93e // indirect
 	golang.org/x/text v0.3.7 // indirect
 	google.golang.org/protobuf v1.27.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 	mvdan.cc/unparam v0.0.0-20210701114405-894c3c7ee6a6 // indirect
 )
@@ -42,16 +42,22 @@ func generateClient() error {
 		return err
 	}
 
-	doc, err := openapi.LoadFile(cfg.Document)
+	doc, src, err := openapi.LoadFile(cfg.Document, cfg.Overlays...)
 	if err != nil {
 		return err
 	}
 
-	code, err := translator.Translate(doc, cfg.Package.Path, cfg.Package.Name, cfg.Types, cfg.StringFormats)
-	if err != nil {
+	code, diags := translator.Translate(doc, cfg)
+	diags.Locate(src)
+	diags.Sort()
+	if err = diags.Err(); err != nil {
 		return err
 	}
 
+	for _, d := range diags { // only warnings remain
+		fmt.Println(d)
+	}
+
 	code = mutator.Mutate(code)
 
 	o, err := ioutil.ReadFile(cfg.Output)
@@ -60,7 +66,7 @@ func generateClient() error {
 	}
 
 	var buf bytes.Buffer
-	if err = writer.Write(&buf, code, &cfg.Boilerplate); err != nil {
+	if err = writer.Write(&buf, code, cfg); err != nil {
 		return err
 	}
 
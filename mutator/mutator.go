@@ -48,11 +48,13 @@ func combineErrorsWithDefault(p *pkg.Package) *pkg.Package {
 
 // inlinePrimitiveTypes takes any non-struct type declarations and inlines their
 // use as the original type in function parameters, return types, and structs.
+// Enums keep their declared type, for their values, and polymorphic types for
+// their variants.
 //
 // Removal of the type declaration is handled in subsequent mutations.
 func inlinePrimitiveTypes(p *pkg.Package) *pkg.Package {
 	for _, d := range p.TypeDecls {
-		if _, ok := d.Type.(*pkg.StructType); ok {
+		if _, ok := d.Type.(*pkg.StructType); ok || isNamed(&d) {
 			continue
 		}
 
@@ -96,6 +98,11 @@ func inlineResponseStructs(p *pkg.Package) *pkg.Package {
 	}
 
 	for _, d := range p.TypeDecls {
+		// Union variants keep their names, for their methods.
+		if _, ok := d.Type.(*pkg.UnionType); ok {
+			continue
+		}
+
 		di := pkg.IdentType{Name: d.Name}
 		if pc, ok := ctxs[di]; ok {
 			d.Type = recurseType(d.Type, decl, func(t pkg.Type, c typeContext) pkg.Type {
@@ -107,7 +114,7 @@ func inlineResponseStructs(p *pkg.Package) *pkg.Package {
 					return t
 				}
 
-				if i, ok := t.(*pkg.IdentType); ok {
+				if i, ok := t.(*pkg.IdentType); ok && !isNamedIdent(p, i) {
 					if cc, ok := ctxs[*i]; ok && pc.n >= cc.n {
 						return resolve(p, i)
 					}
@@ -289,6 +296,14 @@ func reachDecls(p *pkg.Package, fn func(typeContext, *pkg.IdentType) bool) {
 					eachIdent(d.Type, func(ci *pkg.IdentType) {
 						stack = append(stack, stackItem{ci, item.c | decl})
 					})
+
+					// Variants are reached through their interface.
+					if d.Discriminator != nil {
+						stack = append(stack, stackItem{&pkg.IdentType{Name: d.Discriminator.Base}, item.c | decl})
+						for _, v := range d.Discriminator.Variants {
+							stack = append(stack, stackItem{&pkg.IdentType{Name: v.Type}, item.c | decl})
+						}
+					}
 					return
 				}
 			}
@@ -299,6 +314,9 @@ func reachDecls(p *pkg.Package, fn func(typeContext, *pkg.IdentType) bool) {
 					eachIdent(pi.Return, func(ci *pkg.IdentType) {
 						stack = append(stack, stackItem{ci, item.c | iter})
 					})
+					if pi.Envelope != nil {
+						stack = append(stack, stackItem{pi.Envelope, item.c | iter})
+					}
 					return
 				}
 			}
@@ -354,6 +372,10 @@ func recurseType(typ pkg.Type, parentCtx typeContext, fn func(pkg.Type, typeCont
 		t.Type = recurseType(t.Type, parentCtx|iter, fn)
 	case *pkg.PointerType:
 		t.Type = recurseType(t.Type, parentCtx, fn)
+	case *pkg.UnionType:
+		for i := range t.Variants {
+			t.Variants[i].Type = recurseType(t.Variants[i].Type, parentCtx, fn)
+		}
 	}
 
 	return fn(typ, parentCtx)
@@ -368,6 +390,51 @@ func eachIdent(typ pkg.Type, fn func(*pkg.IdentType)) {
 	})
 }
 
+// isNamed reports if d is an enum, polymorphic or union type, which must be
+// used by name rather than inlined.
+func isNamed(d *pkg.TypeDecl) bool {
+	_, union := d.Type.(*pkg.UnionType)
+	return len(d.Enum) > 0 || d.Discriminator != nil || union
+}
+
+// isNamedIdent reports if i is declared as an enum or polymorphic type, or as
+// a struct with polymorphic fields, which are decoded by a method of the struct.
+func isNamedIdent(p *pkg.Package, i *pkg.IdentType) bool {
+	d := findDecl(p, i)
+	if d == nil {
+		return false
+	}
+	if isNamed(d) {
+		return true
+	}
+
+	st, ok := d.Type.(*pkg.StructType)
+	if !ok {
+		return false
+	}
+
+	polymorphic := false
+	for _, f := range st.Fields {
+		eachIdent(f.Type, func(fi *pkg.IdentType) {
+			if fd := findDecl(p, fi); fd != nil && fd.Discriminator != nil {
+				polymorphic = true
+			}
+		})
+	}
+
+	return polymorphic
+}
+
+func findDecl(p *pkg.Package, i *pkg.IdentType) *pkg.TypeDecl {
+	for j := range p.TypeDecls {
+		if d := &p.TypeDecls[j]; d.Name == i.Name && i.Qualifier == "" {
+			return d
+		}
+	}
+
+	return nil
+}
+
 func resolve(p *pkg.Package, i *pkg.IdentType) pkg.Type {
 	for _, d := range p.TypeDecls {
 		di := pkg.IdentType{Name: d.Name}
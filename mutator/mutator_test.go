@@ -70,6 +70,74 @@ func TestInlinePrimitiveTypes(t *testing.T) {
 				},
 			},
 		},
+		{"keep enum",
+			pkg.Package{
+				TypeDecls: []pkg.TypeDecl{
+					{Name: "Status", Type: &pkg.IdentType{Name: "string"}, Enum: []pkg.ConstDecl{
+						{Name: "StatusOn", Type: &pkg.IdentType{Name: "Status"}, Value: "on"},
+					}},
+					{Name: "Thing", Type: &pkg.StructType{
+						Fields: []pkg.Field{{Type: &pkg.IdentType{Name: "Status"}}},
+					}},
+				},
+			},
+			pkg.Package{
+				TypeDecls: []pkg.TypeDecl{
+					{Name: "Status", Type: &pkg.IdentType{Name: "string"}, Enum: []pkg.ConstDecl{
+						{Name: "StatusOn", Type: &pkg.IdentType{Name: "Status"}, Value: "on"},
+					}},
+					{Name: "Thing", Type: &pkg.StructType{
+						Fields: []pkg.Field{{Type: &pkg.IdentType{Name: "Status"}}},
+					}},
+				},
+			},
+		},
+
+		{"keep polymorphic",
+			pkg.Package{
+				TypeDecls: []pkg.TypeDecl{
+					{Name: "Event", Type: &pkg.InterfaceType{}, Discriminator: &pkg.Discriminator{Property: "kind", Base: "EventBase"}},
+					{Name: "Thing", Type: &pkg.StructType{
+						Fields: []pkg.Field{{Type: &pkg.IdentType{Name: "Event"}}},
+					}},
+				},
+			},
+			pkg.Package{
+				TypeDecls: []pkg.TypeDecl{
+					{Name: "Event", Type: &pkg.InterfaceType{}, Discriminator: &pkg.Discriminator{Property: "kind", Base: "EventBase"}},
+					{Name: "Thing", Type: &pkg.StructType{
+						Fields: []pkg.Field{{Type: &pkg.IdentType{Name: "Event"}}},
+					}},
+				},
+			},
+		},
+
+		{"keep union and inline in variants",
+			pkg.Package{
+				TypeDecls: []pkg.TypeDecl{
+					{Name: "ID", Type: &pkg.IdentType{Name: "string"}},
+					{Name: "Ref", Type: &pkg.UnionType{Variants: []pkg.UnionVariant{
+						{Name: "ID", Type: &pkg.IdentType{Name: "ID"}},
+						{Name: "Thing", Type: &pkg.IdentType{Name: "Thing"}},
+					}}},
+					{Name: "Thing", Type: &pkg.StructType{
+						Fields: []pkg.Field{{Type: &pkg.IdentType{Name: "Ref"}}},
+					}},
+				},
+			},
+			pkg.Package{
+				TypeDecls: []pkg.TypeDecl{
+					{Name: "ID", Type: &pkg.IdentType{Name: "string"}},
+					{Name: "Ref", Type: &pkg.UnionType{Variants: []pkg.UnionVariant{
+						{Name: "ID", Type: &pkg.IdentType{Name: "string"}},
+						{Name: "Thing", Type: &pkg.IdentType{Name: "Thing"}},
+					}}},
+					{Name: "Thing", Type: &pkg.StructType{
+						Fields: []pkg.Field{{Type: &pkg.IdentType{Name: "Ref"}}},
+					}},
+				},
+			},
+		},
 
 		{"inline in iter return",
 			pkg.Package{
@@ -510,3 +578,63 @@ func TestHoistEmbeddedStructFields(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveUnusedDecls(t *testing.T) {
+	event := pkg.TypeDecl{Name: "Event", Type: &pkg.InterfaceType{}, Discriminator: &pkg.Discriminator{
+		Property: "kind",
+		Base:     "EventBase",
+		Variants: []pkg.Variant{{Value: "click", Type: "ClickEvent"}},
+	}}
+	base := pkg.TypeDecl{Name: "EventBase", Type: &pkg.StructType{
+		Fields: []pkg.Field{{ID: "Kind", Type: &pkg.IdentType{Name: "string"}}},
+	}}
+	click := pkg.TypeDecl{Name: "ClickEvent", Type: &pkg.StructType{
+		Fields: []pkg.Field{{Type: &pkg.IdentType{Name: "EventBase"}}},
+	}}
+	unused := pkg.TypeDecl{Name: "Unused", Type: &pkg.StructType{}}
+
+	tcs := []struct {
+		name string
+		in   pkg.Package
+		out  []pkg.TypeDecl
+	}{
+		{"empty", pkg.Package{}, []pkg.TypeDecl{}},
+		{"unreachable",
+			pkg.Package{TypeDecls: []pkg.TypeDecl{unused}},
+			[]pkg.TypeDecl{},
+		},
+		{"variants reached through interface",
+			pkg.Package{
+				TypeDecls: []pkg.TypeDecl{base, click, event, unused},
+				Clients: []pkg.Client{{Methods: []pkg.Method{{
+					Return: []pkg.Type{&pkg.IdentType{Name: "Event"}},
+				}}}},
+			},
+			[]pkg.TypeDecl{base, click, event},
+		},
+		{"envelope reached through iter",
+			pkg.Package{
+				TypeDecls: []pkg.TypeDecl{base, unused},
+				Iters: []pkg.Iter{{
+					Name:     "EventBaseIter",
+					Return:   &pkg.IdentType{Name: "string"},
+					Envelope: &pkg.IdentType{Name: "EventBase"},
+					Field:    "Kind",
+				}},
+				Clients: []pkg.Client{{Methods: []pkg.Method{{
+					Return: []pkg.Type{&pkg.IterType{Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "EventBaseIter"}}}},
+				}}}},
+			},
+			[]pkg.TypeDecl{base},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out := removeUnusedDecls(&tc.in)
+			if !reflect.DeepEqual(out.TypeDecls, tc.out) {
+				t.Error("got:", out.TypeDecls, "expected:", tc.out)
+			}
+		})
+	}
+}
@@ -0,0 +1,896 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jbowes/oag/openapi/jsonpointer"
+	"github.com/jbowes/oag/openapi/v2"
+	"github.com/jbowes/oag/openapi/v3"
+)
+
+const defaultMediaType = "application/json"
+
+// ConvertV2 upgrades an OpenAPI 2.0 document to an equivalent OpenAPI 3.0
+// document, following the mapping described at
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md
+//
+// Body and formData parameters become request bodies, response schemas become
+// content for each of the produced media types, and all local references are
+// rewritten to point into the components section.
+func ConvertV2(d *v2.Document) (*v3.Document, error) {
+	out, _, err := convertV2(d)
+	return out, err
+}
+
+// convertV2 converts d, additionally returning where values that moved in the
+// conversion came from, as JSON pointers into d keyed by their new location.
+func convertV2(d *v2.Document) (*v3.Document, map[string]string, error) {
+	c := &converter{doc: d, moved: make(map[string]string)}
+
+	out := &v3.Document{
+		OpenAPI:       "3.0.3",
+		Servers:       convertServers(d),
+		Security:      d.Security,
+		Documentation: convertDocumentation(d.Documentation),
+		Paths:         make(map[string]v3.PathItem, len(d.Paths)),
+	}
+
+	if d.Info != nil {
+		out.Info = &v3.Info{
+			Title:          d.Info.Title,
+			Description:    d.Info.Description,
+			TermsOfService: d.Info.TermsOfService,
+			Version:        d.Info.Version,
+		}
+		if d.Info.Contact != nil {
+			out.Info.Contact = &v3.Contact{
+				Name:  d.Info.Contact.Name,
+				URL:   d.Info.Contact.URL,
+				Email: d.Info.Contact.Email,
+			}
+		}
+		if d.Info.License != nil {
+			out.Info.License = &v3.License{Name: d.Info.License.Name, URL: d.Info.License.URL}
+		}
+	}
+
+	for _, t := range d.Tags {
+		out.Tags = append(out.Tags, v3.Tag{
+			Name:          t.Name,
+			Description:   t.Description,
+			Documentation: convertDocumentation(t.Documentation),
+		})
+	}
+
+	for path, pi := range d.Paths {
+		cpi, err := c.convertPathItem(path, &pi)
+		if err != nil {
+			return nil, nil, err
+		}
+		out.Paths[path] = *cpi
+	}
+
+	var err error
+	out.Components, err = c.convertComponents()
+	return out, c.moved, err
+}
+
+type converter struct {
+	doc   *v2.Document
+	moved map[string]string
+}
+
+// move records that the value at the reference tokens to in the converted
+// document came from the value at from.
+func (c *converter) move(to, from []string) {
+	c.moved[jsonpointer.Format(to...)] = jsonpointer.Format(from...)
+}
+
+// moveContent records that the schema of each media type of the content at
+// to came from the schema at from.
+func (c *converter) moveContent(to []string, types []string, from []string) {
+	for _, mt := range types {
+		c.move(append(to[:len(to):len(to)], "content", mt, "schema"), from)
+	}
+}
+
+// moveParameter records that the parameter at to came from the parameter at
+// from. OpenAPI 2.0 parameters that aren't in the body declare their schema
+// directly.
+func (c *converter) moveParameter(to, from []string) {
+	c.move(to, from)
+	c.move(append(to[:len(to):len(to)], "schema"), from)
+}
+
+func convertServers(d *v2.Document) []v3.Server {
+	host := ""
+	if d.Host != nil {
+		host = *d.Host
+	}
+
+	basePath := ""
+	if d.BasePath != nil {
+		basePath = *d.BasePath
+	}
+
+	if host == "" {
+		if basePath == "" {
+			return nil
+		}
+		return []v3.Server{{URL: basePath}}
+	}
+
+	schemes := append([]string{}, d.Schemes...)
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+
+	// Prefer https when it is available, keeping the declared order otherwise.
+	sort.SliceStable(schemes, func(i, j int) bool { return schemes[i] == "https" && schemes[j] != "https" })
+
+	servers := make([]v3.Server, len(schemes))
+	for i, s := range schemes {
+		servers[i] = v3.Server{URL: s + "://" + host + basePath}
+	}
+
+	return servers
+}
+
+func convertDocumentation(d *v2.ExternalDocumentation) *v3.ExternalDocumentation {
+	if d == nil {
+		return nil
+	}
+
+	return &v3.ExternalDocumentation{Description: d.Description, URL: d.URL}
+}
+
+// convertRef rewrites a local OpenAPI 2.0 reference to its OpenAPI 3.0
+// location. References to other files are left untouched.
+func convertRef(ref string) string {
+	for _, p := range []struct{ from, to string }{
+		{"#/definitions/", "#/components/schemas/"},
+		{"#/parameters/", "#/components/parameters/"},
+		{"#/responses/", "#/components/responses/"},
+	} {
+		if strings.HasPrefix(ref, p.from) {
+			return p.to + ref[len(p.from):]
+		}
+	}
+
+	return ref
+}
+
+func refName(ref string) string {
+	parts := strings.Split(ref, "/")
+	return parts[len(parts)-1]
+}
+
+func (c *converter) convertComponents() (*v3.Components, error) {
+	d := c.doc
+	if d.Definitions == nil && d.Parameters == nil && d.Responses == nil && d.SecurityDefinitions == nil {
+		return nil, nil
+	}
+
+	comp := &v3.Components{}
+
+	if d.Definitions != nil {
+		schemas := make(v3.SchemaMap, len(*d.Definitions))
+		for i, def := range *d.Definitions {
+			s, err := convertSchema(def.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("definition %s: %s", def.Name, err)
+			}
+			schemas[i].Name = def.Name
+			schemas[i].Schema = s
+			c.move([]string{"components", "schemas", def.Name}, []string{"definitions", def.Name})
+		}
+		comp.Schemas = &schemas
+	}
+
+	if d.Parameters != nil {
+		for name, p := range *d.Parameters {
+			switch p.GetIn() {
+			case "body":
+				rb, err := convertBodyParameter(p.(*v2.BodyParameter), d.Consumes)
+				if err != nil {
+					return nil, fmt.Errorf("parameter %s: %s", name, err)
+				}
+				if comp.RequestBodies == nil {
+					comp.RequestBodies = make(map[string]v3.RequestBody)
+				}
+				comp.RequestBodies[name] = *rb
+
+				to := []string{"components", "requestBodies", name}
+				c.move(to, []string{"parameters", name})
+				c.moveContent(to, mediaTypes(d.Consumes), []string{"parameters", name, "schema"})
+			case "formData":
+				// formData parameters are merged into a request body wherever
+				// they are referenced, and have no component equivalent.
+			default:
+				cp, err := convertParameter(p)
+				if err != nil {
+					return nil, fmt.Errorf("parameter %s: %s", name, err)
+				}
+				if comp.Parameters == nil {
+					comp.Parameters = make(map[string]v3.Parameter)
+				}
+				comp.Parameters[name] = *cp
+				c.moveParameter([]string{"components", "parameters", name}, []string{"parameters", name})
+			}
+		}
+	}
+
+	if d.Responses != nil {
+		comp.Responses = make(map[string]v3.Response, len(*d.Responses))
+		for name, r := range *d.Responses {
+			cr, err := convertResponse(&r, d.Produces)
+			if err != nil {
+				return nil, fmt.Errorf("response %s: %s", name, err)
+			}
+			comp.Responses[name] = *cr
+
+			to := []string{"components", "responses", name}
+			c.move(to, []string{"responses", name})
+			c.moveContent(to, mediaTypes(d.Produces), []string{"responses", name, "schema"})
+		}
+	}
+
+	if d.SecurityDefinitions != nil {
+		comp.SecuritySchemes = make(v3.SecuritySchemeMap, len(*d.SecurityDefinitions))
+		for name, s := range *d.SecurityDefinitions {
+			comp.SecuritySchemes[name] = convertSecurityScheme(s)
+		}
+	}
+
+	return comp, nil
+}
+
+func (c *converter) convertPathItem(path string, pi *v2.PathItem) (*v3.PathItem, error) {
+	out := &v3.PathItem{}
+	if pi.Reference != nil {
+		ref := convertRef(*pi.Reference)
+		out.Reference = &ref
+	}
+
+	var err error
+	for i, p := range pi.Parameters {
+		if p, err = c.deref(p); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+
+		switch p.GetIn() {
+		case "body", "formData":
+			// These can only be expressed per operation, so they are copied
+			// into each operation below.
+			continue
+		}
+
+		cp, err := convertParameter(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		c.moveParameter(
+			[]string{"paths", path, "parameters", strconv.Itoa(len(out.Parameters))},
+			[]string{"paths", path, "parameters", strconv.Itoa(i)},
+		)
+		out.Parameters = append(out.Parameters, *cp)
+	}
+
+	for _, o := range []struct {
+		name string
+		in   *v2.Operation
+		out  **v3.Operation
+	}{
+		{"get", pi.Get, &out.Get},
+		{"put", pi.Put, &out.Put},
+		{"post", pi.Post, &out.Post},
+		{"delete", pi.Delete, &out.Delete},
+		{"options", pi.Options, &out.Options},
+		{"head", pi.Head, &out.Head},
+		{"patch", pi.Patch, &out.Patch},
+	} {
+		if o.in == nil {
+			continue
+		}
+
+		if *o.out, err = c.convertOperation([]string{"paths", path, o.name}, o.in, pi.Parameters); err != nil {
+			return nil, fmt.Errorf("%s %s: %s", strings.ToUpper(o.name), path, err)
+		}
+	}
+
+	return out, nil
+}
+
+// deref resolves a parameter reference if it points to a body or formData
+// parameter, as these become part of a request body rather than remaining
+// references. Other parameters are returned as is.
+func (c *converter) deref(p v2.Parameter) (v2.Parameter, error) {
+	ref, ok := p.(*v2.ReferenceParamter)
+	if !ok {
+		return p, nil
+	}
+
+	if !strings.HasPrefix(ref.Reference, "#/parameters/") {
+		return p, nil
+	}
+
+	if c.doc.Parameters == nil {
+		return nil, fmt.Errorf("unresolved parameter reference %s", ref.Reference)
+	}
+
+	rp, ok := (*c.doc.Parameters)[refName(ref.Reference)]
+	if !ok {
+		return nil, fmt.Errorf("unresolved parameter reference %s", ref.Reference)
+	}
+
+	switch rp.GetIn() {
+	case "body", "formData":
+		return rp, nil
+	default:
+		return p, nil
+	}
+}
+
+// convertOperation converts the operation o, found at the reference tokens at.
+func (c *converter) convertOperation(at []string, o *v2.Operation, pathParams v2.Parameters) (*v3.Operation, error) {
+	out := &v3.Operation{
+		Tags:          o.Tags,
+		Summary:       o.Summary,
+		Description:   o.Description,
+		Documentation: convertDocumentation(o.Documentation),
+		OperationID:   o.OperationID,
+		Deprecated:    o.Deprecated,
+	}
+
+	if o.Security != nil {
+		sec := o.Security
+		out.Security = &sec
+	}
+
+	consumes := o.Consumes
+	if consumes == nil {
+		consumes = c.doc.Consumes
+	}
+	produces := o.Produces
+	if produces == nil {
+		produces = c.doc.Produces
+	}
+
+	// param is a parameter, and the reference tokens it was found at.
+	type param struct {
+		v2.Parameter
+		from []string
+	}
+
+	var form []param
+	var err error
+
+	// Path level body and formData parameters apply unless overridden by name.
+	params := make([]param, 0, len(pathParams)+len(o.Parameters))
+	for i, p := range o.Parameters {
+		if p, err = c.deref(p); err != nil {
+			return nil, err
+		}
+		params = append(params, param{p, append(at[:len(at):len(at)], "parameters", strconv.Itoa(i))})
+	}
+	for i, pp := range pathParams {
+		if pp, err = c.deref(pp); err != nil {
+			return nil, err
+		}
+
+		switch pp.GetIn() {
+		case "body", "formData":
+		default:
+			continue
+		}
+
+		overridden := false
+		for _, p := range params {
+			if p.GetName() == pp.GetName() && p.GetIn() == pp.GetIn() {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			params = append(params, param{pp, []string{at[0], at[1], "parameters", strconv.Itoa(i)}})
+		}
+	}
+
+	body := append(at[:len(at):len(at)], "requestBody")
+	for _, p := range params {
+		switch p.GetIn() {
+		case "body":
+			if out.RequestBody, err = convertBodyParameter(p.Parameter.(*v2.BodyParameter), consumes); err != nil {
+				return nil, err
+			}
+			c.move(body, p.from)
+			c.moveContent(body, mediaTypes(consumes), append(p.from, "schema"))
+		case "formData":
+			form = append(form, p)
+		default:
+			cp, err := convertParameter(p.Parameter)
+			if err != nil {
+				return nil, err
+			}
+			c.moveParameter(append(at[:len(at):len(at)], "parameters", strconv.Itoa(len(out.Parameters))), p.from)
+			out.Parameters = append(out.Parameters, *cp)
+		}
+	}
+
+	if len(form) > 0 {
+		fps := make([]v2.Parameter, len(form))
+		for i, p := range form {
+			fps[i] = p.Parameter
+		}
+
+		if out.RequestBody, err = convertFormParameters(fps, consumes); err != nil {
+			return nil, err
+		}
+
+		for mt := range out.RequestBody.Content {
+			for _, p := range form {
+				c.move(append(body, "content", mt, "schema", "properties", p.GetName()), p.from)
+			}
+		}
+	}
+
+	if o.Responses != nil {
+		out.Responses = &v3.Responses{}
+		if o.Responses.Default != nil {
+			if out.Responses.Default, err = convertResponse(o.Responses.Default, produces); err != nil {
+				return nil, err
+			}
+
+			from := append(at[:len(at):len(at)], "responses", "default")
+			c.moveContent(from, mediaTypes(produces), append(from, "schema"))
+		}
+
+		for code, r := range o.Responses.Codes {
+			cr, err := convertResponse(&r, produces)
+			if err != nil {
+				return nil, err
+			}
+
+			if out.Responses.Codes == nil {
+				out.Responses.Codes = make(map[int]v3.Response)
+			}
+			out.Responses.Codes[code] = *cr
+
+			from := append(at[:len(at):len(at)], "responses", strconv.Itoa(code))
+			c.moveContent(from, mediaTypes(produces), append(from, "schema"))
+		}
+	}
+
+	return out, nil
+}
+
+func mediaTypes(types []string) []string {
+	if len(types) == 0 {
+		return []string{defaultMediaType}
+	}
+
+	return types
+}
+
+func convertBodyParameter(p *v2.BodyParameter, consumes []string) (*v3.RequestBody, error) {
+	s, err := convertSchema(p.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := &v3.RequestBody{
+		Description: p.Description,
+		Required:    p.Required,
+		Content:     make(map[string]v3.MediaType),
+	}
+	for _, mt := range mediaTypes(consumes) {
+		rb.Content[mt] = v3.MediaType{Schema: s}
+	}
+
+	return rb, nil
+}
+
+// convertFormParameters collects formData parameters into the properties of a
+// single form request body.
+func convertFormParameters(params []v2.Parameter, consumes []string) (*v3.RequestBody, error) {
+	mt := "application/x-www-form-urlencoded"
+	for _, c := range consumes {
+		if c == "multipart/form-data" {
+			mt = c
+		}
+	}
+
+	props := make(v3.SchemaMap, 0, len(params))
+	var required []string
+	for _, p := range params {
+		var s v3.Schema
+		if _, ok := p.(*v2.FileParameter); ok {
+			mt = "multipart/form-data"
+
+			binary := "binary"
+			s = &v3.StringSchema{
+				SchemaFields: v3.SchemaFields{Description: p.GetDescription()},
+				StringFields: v3.StringFields{Format: &binary},
+			}
+		} else {
+			var err error
+			if s, err = parameterSchema(p); err != nil {
+				return nil, err
+			}
+		}
+
+		props = append(props, struct {
+			Name   string
+			Schema v3.Schema
+		}{p.GetName(), s})
+
+		if p.IsRequired() {
+			required = append(required, p.GetName())
+		}
+	}
+
+	schema := &v3.ObjectSchema{Properties: &props}
+	if required != nil {
+		schema.Required = &required
+	}
+
+	return &v3.RequestBody{
+		Required: required != nil,
+		Content:  map[string]v3.MediaType{mt: {Schema: schema}},
+	}, nil
+}
+
+func convertParameter(p v2.Parameter) (*v3.Parameter, error) {
+	if ref, ok := p.(*v2.ReferenceParamter); ok {
+		return &v3.Parameter{Reference: convertRef(ref.Reference)}, nil
+	}
+
+	out := &v3.Parameter{
+		Name:        p.GetName(),
+		In:          p.GetIn(),
+		Description: p.GetDescription(),
+		Required:    p.IsRequired(),
+	}
+
+	var err error
+	if out.Schema, err = parameterSchema(p); err != nil {
+		return nil, err
+	}
+
+	switch t := p.(type) {
+	case *v2.StringParameter:
+		out.AllowEmptyValue = t.AllowEmptyValue
+	case *v2.NumberParameter:
+		out.AllowEmptyValue = t.AllowEmptyValue
+	case *v2.IntegerParameter:
+		out.AllowEmptyValue = t.AllowEmptyValue
+	case *v2.BooleanParameter:
+		out.AllowEmptyValue = t.AllowEmptyValue
+	case *v2.ArrayParameter:
+		out.AllowEmptyValue = t.AllowEmptyValue
+		out.Style, out.Explode = convertCollectionFormat(t.In, t.CollectionFormat)
+	}
+
+	return out, nil
+}
+
+// convertCollectionFormat maps an OpenAPI 2.0 collectionFormat to the
+// equivalent OpenAPI 3.0 style and explode values.
+//
+// OpenAPI 3.0 has no equivalent of tsv, so the non-standard tabDelimited
+// style is used for it.
+func convertCollectionFormat(in string, cf *string) (*string, *bool) {
+	format := "csv" // the default
+	if cf != nil {
+		format = *cf
+	}
+
+	style := "form"
+	explode := false
+	switch format {
+	case "csv":
+		if in != "query" {
+			style = "simple"
+		}
+	case "ssv":
+		style = "spaceDelimited"
+	case "tsv":
+		style = "tabDelimited"
+	case "pipes":
+		style = "pipeDelimited"
+	case "multi":
+		explode = true
+	default:
+		style = format
+	}
+
+	return &style, &explode
+}
+
+func parameterSchema(p v2.Parameter) (v3.Schema, error) {
+	fields := v3.SchemaFields{Description: p.GetDescription()}
+
+	switch t := p.(type) {
+	case *v2.StringParameter:
+		return &v3.StringSchema{SchemaFields: fields, StringFields: convertStringItem(&t.StringItem)}, nil
+	case *v2.NumberParameter:
+		return &v3.NumberSchema{SchemaFields: fields, NumberFields: convertNumberItem(&t.NumberItem)}, nil
+	case *v2.IntegerParameter:
+		return &v3.IntegerSchema{SchemaFields: fields, IntegerFields: convertIntegerItem(&t.IntegerItem)}, nil
+	case *v2.BooleanParameter:
+		return &v3.BooleanSchema{SchemaFields: fields, BooleanFields: convertBooleanItem(&t.BooleanItem)}, nil
+	case *v2.ArrayParameter:
+		s, err := convertItems(&t.ArrayItem)
+		if err != nil {
+			return nil, err
+		}
+		s.(*v3.ArraySchema).SchemaFields = fields
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported parameter type for %s", p.GetName())
+	}
+}
+
+func convertItems(i v2.Items) (v3.Schema, error) {
+	switch t := i.(type) {
+	case *v2.StringItem:
+		return &v3.StringSchema{StringFields: convertStringItem(t)}, nil
+	case *v2.NumberItem:
+		return &v3.NumberSchema{NumberFields: convertNumberItem(t)}, nil
+	case *v2.IntegerItem:
+		return &v3.IntegerSchema{IntegerFields: convertIntegerItem(t)}, nil
+	case *v2.BooleanItem:
+		return &v3.BooleanSchema{BooleanFields: convertBooleanItem(t)}, nil
+	case *v2.ArrayItem:
+		items, err := convertItems(t.Items)
+		if err != nil {
+			return nil, err
+		}
+		return &v3.ArraySchema{ArrayFields: convertArrayFields(&t.ArrayFields), Items: items}, nil
+	default:
+		return nil, fmt.Errorf("unsupported item type %T", i)
+	}
+}
+
+func convertStringItem(i *v2.StringItem) v3.StringFields {
+	return v3.StringFields{
+		Format:    i.Format,
+		Default:   i.Default,
+		Enum:      i.Enum,
+		MaxLength: i.MaxLength,
+		MinLength: i.MinLength,
+		Pattern:   i.Pattern,
+	}
+}
+
+func convertNumberItem(i *v2.NumberItem) v3.NumberFields {
+	return v3.NumberFields{
+		Format:           i.Format,
+		Default:          i.Default,
+		Enum:             i.Enum,
+		Maximum:          i.Maximum,
+		ExclusiveMaximum: i.ExclusiveMaximum,
+		Minimum:          i.Minimum,
+		ExclusiveMinimum: i.ExclusiveMinimum,
+		MultipleOf:       i.MultipleOf,
+	}
+}
+
+func convertIntegerItem(i *v2.IntegerItem) v3.IntegerFields {
+	return v3.IntegerFields{
+		Format:           i.Format,
+		Default:          i.Default,
+		Enum:             i.Enum,
+		Maximum:          i.Maximum,
+		ExclusiveMaximum: i.ExclusiveMaximum,
+		Minimum:          i.Minimum,
+		ExclusiveMinimum: i.ExclusiveMinimum,
+		MultipleOf:       i.MultipleOf,
+	}
+}
+
+func convertBooleanItem(i *v2.BooleanItem) v3.BooleanFields {
+	return v3.BooleanFields{Default: i.Default, Enum: i.Enum}
+}
+
+func convertArrayFields(a *v2.ArrayFields) v3.ArrayFields {
+	return v3.ArrayFields{
+		Default:     a.Default,
+		Enum:        a.Enum,
+		MaxItems:    a.MaxItems,
+		MinItems:    a.MinItems,
+		UniqueItems: a.UniqueItems,
+	}
+}
+
+func convertResponse(r *v2.Response, produces []string) (*v3.Response, error) {
+	if r.Reference != "" {
+		return &v3.Response{Reference: convertRef(r.Reference)}, nil
+	}
+
+	out := &v3.Response{Description: r.Description}
+
+	if r.Schema != nil {
+		s, err := convertSchema(r.Schema)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Content = make(map[string]v3.MediaType)
+		for _, mt := range mediaTypes(produces) {
+			out.Content[mt] = v3.MediaType{Schema: s, Example: r.Examples[mt]}
+		}
+	}
+
+	for name, h := range r.Headers {
+		ch, err := convertHeader(h)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %s", name, err)
+		}
+
+		if out.Headers == nil {
+			out.Headers = make(map[string]v3.Header)
+		}
+		out.Headers[name] = *ch
+	}
+
+	return out, nil
+}
+
+func convertHeader(h v2.Header) (*v3.Header, error) {
+	out := &v3.Header{Description: h.GetDescription()}
+
+	switch t := h.(type) {
+	case *v2.StringHeader:
+		out.Schema = &v3.StringSchema{StringFields: convertStringItem(&t.StringItem)}
+	case *v2.NumberHeader:
+		out.Schema = &v3.NumberSchema{NumberFields: convertNumberItem(&t.NumberItem)}
+	case *v2.IntegerHeader:
+		out.Schema = &v3.IntegerSchema{IntegerFields: convertIntegerItem(&t.IntegerItem)}
+	case *v2.BooleanHeader:
+		out.Schema = &v3.BooleanSchema{BooleanFields: convertBooleanItem(&t.BooleanItem)}
+	case *v2.ArrayHeader:
+		var err error
+		if out.Schema, err = convertItems(&t.ArrayItem); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported header type %T", h)
+	}
+
+	return out, nil
+}
+
+func convertSchemaFields(f *v2.SchemaFields) v3.SchemaFields {
+	return v3.SchemaFields{
+		Title:         f.Title,
+		Description:   f.Description,
+		Documentation: convertDocumentation(f.Documentation),
+		Example:       f.Example,
+		ReadOnly:      f.ReadOnly,
+		XML:           f.XML,
+	}
+}
+
+func convertSchema(s v2.Schema) (v3.Schema, error) {
+	switch t := s.(type) {
+	case nil:
+		return nil, nil
+	case *v2.ReferenceSchema:
+		return &v3.ReferenceSchema{Reference: convertRef(t.Reference)}, nil
+	case *v2.AllOfSchema:
+		out := &v3.AllOfSchema{SchemaFields: convertSchemaFields(&t.SchemaFields)}
+		for _, s := range t.AllOf {
+			cs, err := convertSchema(s)
+			if err != nil {
+				return nil, err
+			}
+			out.AllOf = append(out.AllOf, cs)
+		}
+		return out, nil
+	case *v2.ObjectSchema:
+		out := &v3.ObjectSchema{
+			SchemaFields:            convertSchemaFields(&t.SchemaFields),
+			Required:                t.Required,
+			AnyAdditionalProperties: t.AnyAdditionalProperties,
+			MinProperties:           t.MinProperties,
+			MaxProperties:           t.MaxProperties,
+		}
+
+		if t.Descriminator != nil {
+			out.Discriminator = &v3.Discriminator{PropertyName: *t.Descriminator}
+		}
+
+		if t.Properties != nil {
+			props := make(v3.SchemaMap, len(*t.Properties))
+			for i, p := range *t.Properties {
+				cs, err := convertSchema(p.Schema)
+				if err != nil {
+					return nil, err
+				}
+				props[i].Name = p.Name
+				props[i].Schema = cs
+			}
+			out.Properties = &props
+		}
+
+		var err error
+		out.AdditionalProperties, err = convertSchema(t.AdditionalProperties)
+		return out, err
+	case *v2.NullSchema:
+		return &v3.NullSchema{SchemaFields: convertSchemaFields(&t.SchemaFields)}, nil
+	case *v2.FileSchema:
+		binary := "binary"
+		return &v3.StringSchema{
+			SchemaFields: convertSchemaFields(&t.SchemaFields),
+			StringFields: v3.StringFields{Format: &binary},
+		}, nil
+	case *v2.StringSchema:
+		return &v3.StringSchema{
+			SchemaFields: convertSchemaFields(&t.SchemaFields),
+			StringFields: convertStringItem(&t.StringItem),
+		}, nil
+	case *v2.NumberSchema:
+		return &v3.NumberSchema{
+			SchemaFields: convertSchemaFields(&t.SchemaFields),
+			NumberFields: convertNumberItem(&t.NumberItem),
+		}, nil
+	case *v2.IntegerSchema:
+		return &v3.IntegerSchema{
+			SchemaFields:  convertSchemaFields(&t.SchemaFields),
+			IntegerFields: convertIntegerItem(&t.IntegerItem),
+		}, nil
+	case *v2.BooleanSchema:
+		return &v3.BooleanSchema{
+			SchemaFields:  convertSchemaFields(&t.SchemaFields),
+			BooleanFields: convertBooleanItem(&t.BooleanItem),
+		}, nil
+	case *v2.ArraySchema:
+		items, err := convertSchema(t.Items)
+		if err != nil {
+			return nil, err
+		}
+		return &v3.ArraySchema{
+			SchemaFields: convertSchemaFields(&t.SchemaFields),
+			ArrayFields:  convertArrayFields(&t.ArrayFields),
+			Items:        items,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported schema type %T", s)
+	}
+}
+
+func convertSecurityScheme(s v2.SecurityScheme) v3.SecurityScheme {
+	fields := v3.SecuritySchemeFields{Description: s.GetDescription()}
+
+	switch t := s.(type) {
+	case *v2.BasicSecurityScheme:
+		return &v3.HTTPSecurityScheme{SecuritySchemeFields: fields, Scheme: "basic"}
+	case *v2.APIKeySecurityScheme:
+		return &v3.APIKeySecurityScheme{SecuritySchemeFields: fields, Name: t.Name, In: t.In}
+	case *v2.OAuth2SecurityScheme:
+		flow := &v3.OAuthFlow{
+			AuthorizationURL: t.AuthorizationURL,
+			TokenURL:         t.TokenURL,
+			Scopes:           t.Scopes,
+		}
+
+		out := &v3.OAuth2SecurityScheme{SecuritySchemeFields: fields}
+		switch t.Flow {
+		case "implicit":
+			out.Flows.Implicit = flow
+		case "password":
+			out.Flows.Password = flow
+		case "application":
+			out.Flows.ClientCredentials = flow
+		case "accessCode":
+			out.Flows.AuthorizationCode = flow
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,294 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jbowes/oag/openapi/v2"
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/lithammer/dedent"
+	"gopkg.in/yaml.v3"
+)
+
+func convert(t *testing.T, in string) *v3.Document {
+	var d v2.Document
+	if err := yaml.Unmarshal([]byte(dedent.Dedent(in)), &d); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	out, err := ConvertV2(&d)
+	if err != nil {
+		t.Fatal("could not convert. got error:", err)
+	}
+
+	return out
+}
+
+func TestConvertServers(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   string
+		out  []v3.Server
+	}{
+		{"no host", `basePath: /api`, []v3.Server{{URL: "/api"}}},
+		{"no schemes", `host: example.com`, []v3.Server{{URL: "https://example.com"}}},
+		{
+			"prefer https",
+			`
+            host: example.com
+            basePath: /v1
+            schemes: [http, https]
+            `,
+			[]v3.Server{{URL: "https://example.com/v1"}, {URL: "http://example.com/v1"}},
+		},
+		{
+			"http only",
+			`
+            host: example.com
+            schemes: [http]
+            `,
+			[]v3.Server{{URL: "http://example.com"}},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out := convert(t, tc.in)
+			if !reflect.DeepEqual(out.Servers, tc.out) {
+				t.Error("got:", out.Servers, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestConvertOperation(t *testing.T) {
+	out := convert(t, `
+    consumes: [application/json]
+    produces: [application/json]
+    paths:
+      /pets/{id}:
+        parameters:
+          - name: id
+            in: path
+            required: true
+            type: string
+        put:
+          parameters:
+            - name: tags
+              in: query
+              type: array
+              items:
+                type: string
+              collectionFormat: multi
+            - $ref: '#/parameters/Pet'
+            - $ref: '#/parameters/Limit'
+          responses:
+            200:
+              description: ok
+              schema:
+                $ref: '#/definitions/Pet'
+            default:
+              $ref: '#/responses/Error'
+    parameters:
+      Pet:
+        name: pet
+        in: body
+        required: true
+        schema:
+          $ref: '#/definitions/Pet'
+      Limit:
+        name: limit
+        in: query
+        type: integer
+    definitions:
+      Pet:
+        type: object
+    `)
+
+	str := &v3.StringSchema{}
+	form, exploded := "form", true
+	pet := &v3.ReferenceSchema{Reference: "#/components/schemas/Pet"}
+
+	expected := v3.PathItem{
+		Parameters: []v3.Parameter{
+			{Name: "id", In: "path", Required: true, Schema: str},
+		},
+		Put: &v3.Operation{
+			Parameters: []v3.Parameter{
+				{
+					Name: "tags", In: "query", Style: &form, Explode: &exploded,
+					Schema: &v3.ArraySchema{Items: str},
+				},
+				{Reference: "#/components/parameters/Limit"},
+			},
+			RequestBody: &v3.RequestBody{
+				Required: true,
+				Content:  map[string]v3.MediaType{"application/json": {Schema: pet}},
+			},
+			Responses: &v3.Responses{
+				Default: &v3.Response{Reference: "#/components/responses/Error"},
+				Codes: map[int]v3.Response{
+					200: {
+						Description: "ok",
+						Content:     map[string]v3.MediaType{"application/json": {Schema: pet}},
+					},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(out.Paths["/pets/{id}"], expected) {
+		t.Error("got:", out.Paths["/pets/{id}"], "expected:", expected)
+	}
+
+	if _, ok := out.Components.RequestBodies["Pet"]; !ok {
+		t.Error("body parameter not converted to a request body. got:", out.Components.RequestBodies)
+	}
+	if _, ok := out.Components.Parameters["Limit"]; !ok {
+		t.Error("parameter not converted. got:", out.Components.Parameters)
+	}
+	if s := *out.Components.Schemas; len(s) != 1 || s[0].Name != "Pet" {
+		t.Error("definition not converted. got:", s)
+	}
+}
+
+func TestConvertFormParameters(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   string
+		mt   string
+	}{
+		{"urlencoded", `
+            - name: name
+              in: formData
+              required: true
+              type: string
+            `, "application/x-www-form-urlencoded"},
+		{"file", `
+            - name: name
+              in: formData
+              required: true
+              type: string
+            - name: upload
+              in: formData
+              type: file
+            `, "multipart/form-data"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var params v2.Parameters
+			if err := yaml.Unmarshal([]byte(dedent.Dedent(tc.in)), &params); err != nil {
+				t.Fatal("could not unmarshal. got error:", err)
+			}
+
+			out, err := convertFormParameters(params, nil)
+			if err != nil {
+				t.Fatal("could not convert. got error:", err)
+			}
+
+			mt, ok := out.Content[tc.mt]
+			if !ok || len(out.Content) != 1 {
+				t.Fatal("wrong media type. got:", out.Content, "expected:", tc.mt)
+			}
+
+			s := mt.Schema.(*v3.ObjectSchema)
+			if len(*s.Properties) != len(params) {
+				t.Error("wrong properties. got:", *s.Properties)
+			}
+			if !out.Required || !reflect.DeepEqual(*s.Required, []string{"name"}) {
+				t.Error("wrong required properties. got:", *s.Required)
+			}
+		})
+	}
+}
+
+func TestConvertFileResponse(t *testing.T) {
+	var r v2.Response
+	in := dedent.Dedent(`
+        description: the export
+        schema:
+          type: file
+        `)
+	if err := yaml.Unmarshal([]byte(in), &r); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	out, err := convertResponse(&r, []string{"application/octet-stream"})
+	if err != nil {
+		t.Fatal("could not convert. got error:", err)
+	}
+
+	s, ok := out.Content["application/octet-stream"].Schema.(*v3.StringSchema)
+	if !ok || s.Format == nil || *s.Format != "binary" {
+		t.Error("file not converted to binary string. got:", out.Content)
+	}
+}
+
+func TestConvertCollectionFormat(t *testing.T) {
+	tcs := []struct {
+		in      string
+		cf      string
+		style   string
+		explode bool
+	}{
+		{"query", "", "form", false},
+		{"query", "csv", "form", false},
+		{"path", "csv", "simple", false},
+		{"header", "", "simple", false},
+		{"query", "ssv", "spaceDelimited", false},
+		{"query", "tsv", "tabDelimited", false},
+		{"query", "pipes", "pipeDelimited", false},
+		{"query", "multi", "form", true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.in+" "+tc.cf, func(t *testing.T) {
+			var cf *string
+			if tc.cf != "" {
+				cf = &tc.cf
+			}
+
+			style, explode := convertCollectionFormat(tc.in, cf)
+			if *style != tc.style || *explode != tc.explode {
+				t.Error("got:", *style, *explode, "expected:", tc.style, tc.explode)
+			}
+		})
+	}
+}
+
+func TestConvertSecurityScheme(t *testing.T) {
+	d := dedent.Dedent(`
+    basicType:
+      type: basic
+    apiKeyType:
+      type: apiKey
+      name: Authorization
+      in: header
+    oauthType:
+      type: oauth2
+      flow: application
+      tokenUrl: "http://whereever.place"
+      scopes: {}
+	`)
+
+	var in v2.SecuritySchemeMap
+	if err := yaml.Unmarshal([]byte(d), &in); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	out := make(v3.SecuritySchemeMap)
+	for k, v := range in {
+		out[k] = convertSecurityScheme(v)
+	}
+
+	if s, ok := out["basicType"].(*v3.HTTPSecurityScheme); !ok || s.Scheme != "basic" {
+		t.Error("basic not converted. got:", out["basicType"])
+	}
+	if s, ok := out["apiKeyType"].(*v3.APIKeySecurityScheme); !ok || s.Name != "Authorization" || s.In != "header" {
+		t.Error("apiKey not converted. got:", out["apiKeyType"])
+	}
+	if s, ok := out["oauthType"].(*v3.OAuth2SecurityScheme); !ok || s.Flows.ClientCredentials == nil {
+		t.Error("oauth2 not converted. got:", out["oauthType"])
+	}
+}
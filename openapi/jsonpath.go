@@ -0,0 +1,454 @@
+package openapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// match is a value selected by a JSONPath query.
+type match struct {
+	at []string // reference tokens of the value
+	n  *yaml.Node
+}
+
+// selector selects values from those held by a map or list.
+type selector func(m match) []match
+
+// segment is a single step of a JSONPath query.
+type segment struct {
+	selectors  []selector
+	descendant bool // apply selectors to m and all its descendants, ie ..name
+}
+
+// parseJSONPath parses a JSONPath query, as used by overlay action targets.
+//
+// Only a subset of RFC 9535 is supported: member names in dot or bracket
+// notation, list indices, wildcards, descendants, and filters comparing a
+// single value to a literal, ie $.paths[?@.get.operationId == 'listPets'].
+// Slices, functions, and filters combining expressions are not.
+func parseJSONPath(s string) ([]segment, error) {
+	p := &pathParser{s: s}
+	if !p.consume("$") {
+		return nil, p.errorf("query must start with '$'")
+	}
+
+	var segs []segment
+	for !p.done() {
+		seg, err := p.segment()
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, seg)
+	}
+
+	return segs, nil
+}
+
+// selectPath returns the values of doc selected by the query segs, in
+// document order.
+func selectPath(doc *yaml.Node, segs []segment) []match {
+	ms := []match{{n: doc}}
+	for _, seg := range segs {
+		var next []match
+		for _, m := range ms {
+			from := []match{m}
+			if seg.descendant {
+				from = descendants(m)
+			}
+
+			for _, f := range from {
+				for _, sel := range seg.selectors {
+					next = append(next, sel(f)...)
+				}
+			}
+		}
+		ms = next
+	}
+
+	return ms
+}
+
+// children returns the values held by the map or list m.
+func children(m match) []match {
+	n := m.n
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	var out []match
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			out = append(out, match{appendToken(m.at, n.Content[i].Value), n.Content[i+1]})
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			out = append(out, match{appendToken(m.at, strconv.Itoa(i)), item})
+		}
+	}
+
+	return out
+}
+
+// descendants returns m and every value nested within it, in document order.
+func descendants(m match) []match {
+	out := []match{m}
+	for _, c := range children(m) {
+		out = append(out, descendants(c)...)
+	}
+
+	return out
+}
+
+func appendToken(at []string, tok string) []string {
+	return append(at[:len(at):len(at)], tok)
+}
+
+func selectName(name string) selector {
+	return func(m match) []match {
+		if v := valueOf(resolveAlias(m.n), name); v != nil {
+			return []match{{appendToken(m.at, name), v}}
+		}
+		return nil
+	}
+}
+
+func selectIndex(i int) selector {
+	return func(m match) []match {
+		n := resolveAlias(m.n)
+		if n.Kind != yaml.SequenceNode {
+			return nil
+		}
+
+		j := i
+		if j < 0 {
+			j += len(n.Content)
+		}
+		if j < 0 || j >= len(n.Content) {
+			return nil
+		}
+
+		return []match{{appendToken(m.at, strconv.Itoa(j)), n.Content[j]}}
+	}
+}
+
+func selectFilter(keep func(*yaml.Node) bool) selector {
+	return func(m match) []match {
+		var out []match
+		for _, c := range children(m) {
+			if keep(c.n) {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.AliasNode {
+		return n.Alias
+	}
+
+	return n
+}
+
+type pathParser struct {
+	s string
+	i int
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid JSONPath %q at offset %d: %s", p.s, p.i, fmt.Sprintf(format, args...))
+}
+
+func (p *pathParser) done() bool { return p.i >= len(p.s) }
+
+func (p *pathParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *pathParser) consume(tok string) bool {
+	if strings.HasPrefix(p.s[p.i:], tok) {
+		p.i += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) skipSpace() {
+	for !p.done() && strings.IndexByte(" \t\n\r", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+func (p *pathParser) segment() (segment, error) {
+	var seg segment
+	switch {
+	case p.consume(".."):
+		seg.descendant = true
+		if p.peek() == '[' {
+			break
+		}
+		fallthrough
+	case p.consume("."):
+		if p.consume("*") {
+			seg.selectors = []selector{children}
+			return seg, nil
+		}
+
+		name, err := p.name()
+		if err != nil {
+			return seg, err
+		}
+		seg.selectors = []selector{selectName(name)}
+		return seg, nil
+	case p.peek() != '[':
+		return seg, p.errorf("unexpected %q", p.peek())
+	}
+
+	var err error
+	seg.selectors, err = p.bracket()
+	return seg, err
+}
+
+// name parses a member name in dot notation.
+func (p *pathParser) name() (string, error) {
+	start := p.i
+	for !p.done() {
+		r, size := utf8.DecodeRuneInString(p.s[p.i:])
+		if r != '_' && r != '-' && r < utf8.RuneSelf && !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			break
+		}
+		p.i += size
+	}
+
+	if p.i == start {
+		return "", p.errorf("expected a member name")
+	}
+
+	return p.s[start:p.i], nil
+}
+
+// bracket parses a comma separated list of selectors in brackets.
+func (p *pathParser) bracket() ([]selector, error) {
+	p.consume("[")
+
+	var sels []selector
+	for {
+		p.skipSpace()
+
+		var sel selector
+		switch c := p.peek(); {
+		case c == '\'' || c == '"':
+			name, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			sel = selectName(name)
+		case c == '*':
+			p.i++
+			sel = children
+		case c == '?':
+			p.i++
+			keep, err := p.filter()
+			if err != nil {
+				return nil, err
+			}
+			sel = selectFilter(keep)
+		case c == '-' || '0' <= c && c <= '9':
+			i, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			sel = selectIndex(i)
+		default:
+			return nil, p.errorf("unsupported selector")
+		}
+		sels = append(sels, sel)
+
+		p.skipSpace()
+		switch {
+		case p.consume(","):
+		case p.consume("]"):
+			return sels, nil
+		default:
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *pathParser) index() (int, error) {
+	start := p.i
+	p.consume("-")
+	for !p.done() && '0' <= p.s[p.i] && p.s[p.i] <= '9' {
+		p.i++
+	}
+
+	if p.peek() == ':' {
+		return 0, p.errorf("slices are not supported")
+	}
+
+	i, err := strconv.Atoi(p.s[start:p.i])
+	if err != nil {
+		return 0, p.errorf("bad index %q", p.s[start:p.i])
+	}
+
+	return i, nil
+}
+
+// str parses a quoted string.
+func (p *pathParser) str() (string, error) {
+	quote := p.s[p.i]
+	p.i++
+
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c != '\\':
+			b.WriteByte(c)
+		case p.done():
+		default:
+			e := p.s[p.i]
+			p.i++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '/', '\'', '"':
+				b.WriteByte(e)
+			default:
+				return "", p.errorf("unsupported escape \\%c", e)
+			}
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+// filter parses a filter expression, following its '?'. The expression may
+// test for a value, ie @.required, or compare it to a literal, ie
+// @.in == 'header'.
+func (p *pathParser) filter() (func(*yaml.Node) bool, error) {
+	p.skipSpace()
+	paren := p.consume("(")
+	p.skipSpace()
+	not := p.consume("!")
+
+	if !p.consume("@") {
+		return nil, p.errorf("filters must start with '@'")
+	}
+
+	var sels []selector
+	for !p.done() && (p.peek() == '.' || p.peek() == '[') {
+		var sel selector
+		if p.consume(".") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			sel = selectName(name)
+		} else {
+			p.i++
+			p.skipSpace()
+			switch c := p.peek(); {
+			case c == '\'' || c == '"':
+				name, err := p.str()
+				if err != nil {
+					return nil, err
+				}
+				sel = selectName(name)
+			case c == '-' || '0' <= c && c <= '9':
+				i, err := p.index()
+				if err != nil {
+					return nil, err
+				}
+				sel = selectIndex(i)
+			default:
+				return nil, p.errorf("unsupported filter query")
+			}
+			p.skipSpace()
+			if !p.consume("]") {
+				return nil, p.errorf("expected ']'")
+			}
+		}
+		sels = append(sels, sel)
+	}
+
+	find := func(n *yaml.Node) *yaml.Node {
+		m := match{n: n}
+		for _, sel := range sels {
+			ms := sel(m)
+			if len(ms) == 0 {
+				return nil
+			}
+			m = ms[0]
+		}
+		return m.n
+	}
+
+	p.skipSpace()
+	var keep func(*yaml.Node) bool
+	switch {
+	case not:
+		keep = func(n *yaml.Node) bool { return find(n) == nil }
+	case p.consume("=="), p.consume("!="):
+		negate := p.s[p.i-2] == '!'
+		p.skipSpace()
+		lit, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		keep = func(n *yaml.Node) bool {
+			v := find(n)
+			return (v != nil && equalNodes(v, lit)) != negate
+		}
+	default:
+		keep = func(n *yaml.Node) bool { return find(n) != nil }
+	}
+
+	p.skipSpace()
+	if paren && !p.consume(")") {
+		return nil, p.errorf("expected ')'")
+	}
+
+	return keep, nil
+}
+
+// literal parses a string, number, boolean or null literal.
+func (p *pathParser) literal() (*yaml.Node, error) {
+	if c := p.peek(); c == '\'' || c == '"' {
+		s, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}, nil
+	}
+
+	start := p.i
+	for !p.done() && strings.IndexByte(" \t\n\r,)]", p.s[p.i]) < 0 {
+		p.i++
+	}
+
+	n := &yaml.Node{Kind: yaml.ScalarNode, Value: p.s[start:p.i]}
+	switch n.ShortTag() {
+	case "!!int", "!!float", "!!bool", "!!null":
+		return n, nil
+	default:
+		p.i = start
+		return nil, p.errorf("expected a literal")
+	}
+}
@@ -0,0 +1,103 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lithammer/dedent"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jbowes/oag/openapi/jsonpointer"
+)
+
+func TestSelectPath(t *testing.T) {
+	doc := dedent.Dedent(`
+        paths:
+          /pets:
+            get:
+              operationId: listPets
+              parameters:
+                - name: limit
+                  in: query
+                - name: X-Trace
+                  in: header
+                  required: true
+          /pets/{id}:
+            get:
+              operationId: getPet
+            delete:
+              operationId: deletePet
+        `)
+
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &n); err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name string
+		in   string
+		out  []string
+	}{
+		{"root", "$", []string{""}},
+		{"dot names", "$.paths", []string{"/paths"}},
+		{"bracket names", `$.paths['/pets']["get"]`, []string{"/paths/~1pets/get"}},
+		{"wildcard", "$.paths['/pets/{id}'].*", []string{"/paths/~1pets~1{id}/get", "/paths/~1pets~1{id}/delete"}},
+		{"index", "$.paths['/pets'].get.parameters[1]", []string{"/paths/~1pets/get/parameters/1"}},
+		{"negative index", "$.paths['/pets'].get.parameters[-2]", []string{"/paths/~1pets/get/parameters/0"}},
+		{"multiple selectors", "$.paths['/pets/{id}']['delete', 'get']", []string{"/paths/~1pets~1{id}/delete", "/paths/~1pets~1{id}/get"}},
+		{"descendants", "$..operationId", []string{
+			"/paths/~1pets/get/operationId",
+			"/paths/~1pets~1{id}/get/operationId",
+			"/paths/~1pets~1{id}/delete/operationId",
+		}},
+		{"filter comparison", "$.paths.*.get.parameters[?@.in == 'header']", []string{"/paths/~1pets/get/parameters/1"}},
+		{"filter not equal", `$.paths.*.get.parameters[?(@.in != "header")]`, []string{"/paths/~1pets/get/parameters/0"}},
+		{"filter existence", "$..parameters[?@.required]", []string{"/paths/~1pets/get/parameters/1"}},
+		{"filter nonexistence", "$..parameters[?!@.required]", []string{"/paths/~1pets/get/parameters/0"}},
+		{"filter literal", "$..parameters[?@.required == true].name", []string{"/paths/~1pets/get/parameters/1/name"}},
+		{"filter nested", "$.paths[?@.get.operationId == 'getPet']", []string{"/paths/~1pets~1{id}"}},
+		{"no match", "$.paths['/users']", nil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			segs, err := parseJSONPath(tc.in)
+			if err != nil {
+				t.Fatal("could not parse. got error:", err)
+			}
+
+			var out []string
+			for _, m := range selectPath(n.Content[0], segs) {
+				out = append(out, jsonpointer.Format(m.at...))
+			}
+
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   string
+	}{
+		{"no root", "paths"},
+		{"missing name", "$."},
+		{"unterminated bracket", "$['paths'"},
+		{"unterminated string", "$['paths"},
+		{"slice", "$.tags[0:2]"},
+		{"function", "$.tags[?length(@) > 1]"},
+		{"bad literal", "$.tags[?@.name == nope]"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseJSONPath(tc.in); err == nil {
+				t.Error("expected error but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Package jsonpointer parses and formats JSON Pointers, according to
+// https://tools.ietf.org/html/rfc6901
+package jsonpointer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Parse parses a JSON Pointer into its reference tokens, with any escaped
+// characters restored. The empty pointer, referencing the whole document,
+// returns no tokens.
+//
+// Pointers in their URI fragment form, starting with '#', are percent-decoded
+// before parsing.
+func Parse(s string) ([]string, error) {
+	if strings.HasPrefix(s, "#") {
+		var err error
+		if s, err = url.PathUnescape(s[1:]); err != nil {
+			return nil, fmt.Errorf("invalid JSON pointer %q: %s", s, err)
+		}
+	}
+
+	if s == "" {
+		return nil, nil
+	}
+
+	if s[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with '/'", s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, tok := range tokens {
+		var err error
+		if tokens[i], err = Unescape(tok); err != nil {
+			return nil, fmt.Errorf("invalid JSON pointer %q: %s", s, err)
+		}
+	}
+
+	return tokens, nil
+}
+
+// Format formats reference tokens as a JSON Pointer, escaping them as needed.
+func Format(tokens ...string) string {
+	var b strings.Builder
+	for _, tok := range tokens {
+		b.WriteByte('/')
+		b.WriteString(Escape(tok))
+	}
+
+	return b.String()
+}
+
+var escaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// Escape escapes a single reference token, replacing '~' with "~0" and '/'
+// with "~1".
+func Escape(tok string) string {
+	if !strings.ContainsAny(tok, "~/") {
+		return tok
+	}
+
+	return escaper.Replace(tok)
+}
+
+// Unescape restores the characters escaped in a single reference token.
+func Unescape(tok string) (string, error) {
+	if !strings.Contains(tok, "~") {
+		return tok, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(tok); i++ {
+		if tok[i] != '~' {
+			b.WriteByte(tok[i])
+			continue
+		}
+
+		if i+1 == len(tok) {
+			return "", fmt.Errorf("incomplete escape in %q", tok)
+		}
+
+		i++
+		switch tok[i] {
+		case '0':
+			b.WriteByte('~')
+		case '1':
+			b.WriteByte('/')
+		default:
+			return "", fmt.Errorf("invalid escape ~%c in %q", tok[i], tok)
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,65 @@
+package jsonpointer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   string
+		out  []string
+		err  bool
+	}{
+		{"whole document", "", nil, false},
+		{"whole document fragment", "#", nil, false},
+		{"simple", "/components/schemas/Pet", []string{"components", "schemas", "Pet"}, false},
+		{"empty token", "/", []string{""}, false},
+		{"escapes", "/paths/~1pets~1{id}/a~0b", []string{"paths", "/pets/{id}", "a~b"}, false},
+		{"escape order", "/~01", []string{"~1"}, false},
+		{"fragment", "#/definitions/a%20b", []string{"definitions", "a b"}, false},
+		{"no leading slash", "definitions", nil, true},
+		{"bad escape", "/a~2", nil, true},
+		{"incomplete escape", "/a~", nil, true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := Parse(tc.in)
+			if tc.err {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("could not parse. got error:", err)
+			}
+
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   []string
+		out  string
+	}{
+		{"whole document", nil, ""},
+		{"simple", []string{"components", "schemas", "Pet"}, "/components/schemas/Pet"},
+		{"escapes", []string{"paths", "/pets/{id}", "a~b"}, "/paths/~1pets~1{id}/a~0b"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := Format(tc.in...); out != tc.out {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
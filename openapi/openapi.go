@@ -3,31 +3,172 @@
 //
 // It provides methods for loading files of version 2 and up, and converting them
 // to v3.
-//
-// XXX: actually define v3.
-// XXX: actually convert v2 to v3
 package openapi
 
 import (
-	"io/ioutil"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 
-	"github.com/go-yaml/yaml"
+	"github.com/jbowes/oag/diag"
+	"github.com/jbowes/oag/openapi/jsonpointer"
 	"github.com/jbowes/oag/openapi/v2"
+	"github.com/jbowes/oag/openapi/v3"
 )
 
 // LoadFile loads the OpenAPI file at the given path, returning the document
-// definition.
-func LoadFile(path string) (*v2.Document, error) {
-	d, err := ioutil.ReadFile(path)
+// definition, and the source of its values. OpenAPI 2.0 documents are
+// converted to OpenAPI 3.0.
+//
+// References to other files are resolved relative to the referencing file,
+// and included in the returned document.
+//
+// Each of the given overlays, either OpenAPI Overlay 1.0 documents or JSON
+// Patches, is then applied to the document in order, before it is decoded.
+// Overlays apply to the document as written, so target OpenAPI 2.0 documents
+// before their conversion.
+func LoadFile(path string, overlays ...string) (*v3.Document, *Source, error) {
+	n, pos, err := bundle(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	for _, o := range overlays {
+		if n, err = applyOverlay(n, pos, o); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	var doc v2.Document
-	err = yaml.Unmarshal(d, &doc)
+	src := &Source{file: path, pos: pos}
+	doc, moved, err := load(n, src)
 	if err != nil {
+		return nil, nil, err
+	}
+	src.moved = moved
+
+	return doc, src, nil
+}
+
+// Load loads an OpenAPI document from its YAML or JSON encoded form. The
+// version of the document is detected from its swagger or openapi key, and
+// OpenAPI 2.0 documents are converted to OpenAPI 3.0.
+func Load(d []byte) (*v3.Document, error) {
+	var n yaml.Node
+	if err := yaml.Unmarshal(d, &n); err != nil {
 		return nil, err
 	}
 
-	return &doc, err
+	doc, _, err := load(&n, &Source{})
+	return doc, err
+}
+
+// load decodes a parsed document like Load, also returning where any values
+// moved from when converting from OpenAPI 2.0. Errors decoding it are
+// reported together, located by src.
+func load(n *yaml.Node, src *Source) (*v3.Document, map[string]string, error) {
+	var version struct {
+		Swagger string
+		OpenAPI string `yaml:"openapi"`
+	}
+	if err := n.Decode(&version); err != nil {
+		return nil, nil, decodeDiags(err, n, src)
+	}
+
+	switch {
+	case version.Swagger == "2.0":
+		var doc v2.Document
+		if err := n.Decode(&doc); err != nil {
+			return nil, nil, decodeDiags(err, n, src)
+		}
+		return convertV2(&doc)
+	case strings.HasPrefix(version.OpenAPI, "3.0."), strings.HasPrefix(version.OpenAPI, "3.1."):
+		var doc v3.Document
+		if err := n.Decode(&doc); err != nil {
+			return nil, nil, decodeDiags(err, n, src)
+		}
+		return &doc, nil, nil
+	case version.Swagger != "":
+		return nil, nil, fmt.Errorf("unsupported swagger version %q", version.Swagger)
+	case version.OpenAPI != "":
+		return nil, nil, fmt.Errorf("unsupported openapi version %q", version.OpenAPI)
+	default:
+		return nil, nil, errors.New("document does not declare a swagger or openapi version")
+	}
+}
+
+// errorPos matches the errors decoding a document, which are positioned
+// by line, and by column when they are found by the v2 or v3 packages.
+var errorPos = regexp.MustCompile(`^line (\d+)(?:, column (\d+))?: (.*)$`)
+
+// decodeDiags returns the errors of a failed decode of n as diagnostics. Each
+// is located in its file by src, through the value of n at its position.
+// Errors that stopped decoding are returned as they are.
+func decodeDiags(err error, n *yaml.Node, src *Source) error {
+	te, ok := err.(*yaml.TypeError)
+	if !ok {
+		return err
+	}
+
+	diags := make(diag.List, len(te.Errors))
+	for i, e := range te.Errors {
+		diags[i].Message = e
+
+		m := errorPos.FindStringSubmatch(e)
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		column, _ := strconv.Atoi(m[2])
+
+		at, v := nodeAt(n, nil, line, column)
+		pos := src.Locate("#" + jsonpointer.Format(at...))
+		pos.Line, pos.Column = line, column
+		if v != nil {
+			pos.Column = v.Column
+		}
+		diags[i].Pos, diags[i].Message = pos, m[3]
+	}
+	diags.Sort()
+
+	return diags
+}
+
+// nodeAt returns the first value within n, in document order, at the given
+// line and column, along with the reference tokens of where it is. Without a
+// column, the first value on the line is returned.
+func nodeAt(n *yaml.Node, at []string, line, column int) ([]string, *yaml.Node) {
+	if n.Line == line && (column == 0 || n.Column == column) {
+		return at, n
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			if tokens, v := nodeAt(c, at, line, column); v != nil {
+				return tokens, v
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			kat := append(at[:len(at):len(at)], n.Content[i].Value)
+			if tokens, v := nodeAt(n.Content[i], kat, line, column); v != nil {
+				return tokens, v
+			}
+			if tokens, v := nodeAt(n.Content[i+1], kat, line, column); v != nil {
+				return tokens, v
+			}
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			if tokens, v := nodeAt(c, append(at[:len(at):len(at)], strconv.Itoa(i)), line, column); v != nil {
+				return tokens, v
+			}
+		}
+	}
+
+	return at, nil
 }
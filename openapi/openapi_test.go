@@ -0,0 +1,238 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lithammer/dedent"
+
+	"github.com/jbowes/oag/diag"
+)
+
+func TestLoad(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   string
+		err  bool
+	}{
+		{"swagger 2.0", `
+            swagger: "2.0"
+            info:
+              title: test
+              version: "1"
+            paths: {}
+            `, false},
+		{"openapi 3.0", `
+            openapi: 3.0.3
+            info:
+              title: test
+              version: "1"
+            paths: {}
+            `, false},
+		{"openapi 3.1", `
+            openapi: 3.1.0
+            info:
+              title: test
+              version: "1"
+            `, false},
+		{"unsupported swagger", `swagger: "1.2"`, true},
+		{"unsupported openapi", `openapi: 4.0.0`, true},
+		{"no version", `info: {}`, true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := Load([]byte(dedent.Dedent(tc.in)))
+			if tc.err {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("could not load. got error:", err)
+			}
+
+			if doc.Info == nil || doc.Info.Title != "test" {
+				t.Error("document not loaded. got:", doc)
+			}
+		})
+	}
+}
+
+// syntheticSpec generates a large document, shaped like the specs of big API
+// providers. Each of its n schemas nests objects depth levels deep, and is
+// used by one operation. It is encoded as JSON, as such specs usually are.
+func syntheticSpec(n, depth int, swagger bool) []byte {
+	schemaRef := "#/components/schemas/"
+	if swagger {
+		schemaRef = "#/definitions/"
+	}
+
+	var object func(i, depth int) map[string]interface{}
+	object = func(i, depth int) map[string]interface{} {
+		props := map[string]interface{}{
+			"id":      map[string]interface{}{"type": "string", "format": "uuid"},
+			"count":   map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0},
+			"score":   map[string]interface{}{"type": "number", "description": "A score."},
+			"enabled": map[string]interface{}{"type": "boolean", "default": true},
+			"tags": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string", "enum": []string{"a", "b", "c"}},
+			},
+			"labels": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"related": map[string]interface{}{"$ref": fmt.Sprintf("%sSchema%d", schemaRef, (i+1)%n)},
+		}
+		if depth > 0 {
+			props["child"] = object(i, depth-1)
+		}
+
+		return map[string]interface{}{
+			"type":        "object",
+			"description": fmt.Sprintf("Schema %d, at depth %d.", i, depth),
+			"required":    []string{"id"},
+			"properties":  props,
+		}
+	}
+
+	schemas := make(map[string]interface{}, n)
+	paths := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("Schema%d", i)
+		schemas[name] = object(i, depth)
+
+		ref := map[string]interface{}{"$ref": schemaRef + name}
+		op := map[string]interface{}{"operationId": "get" + name}
+		if swagger {
+			op["responses"] = map[string]interface{}{
+				"200": map[string]interface{}{"description": "ok", "schema": ref},
+			}
+		} else {
+			op["responses"] = map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "ok",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": ref},
+					},
+				},
+			}
+		}
+		paths[fmt.Sprintf("/things%d/{id}", i)] = map[string]interface{}{"get": op}
+	}
+
+	doc := map[string]interface{}{
+		"info":  map[string]interface{}{"title": "synthetic", "version": "1"},
+		"paths": paths,
+	}
+	if swagger {
+		doc["swagger"] = "2.0"
+		doc["definitions"] = schemas
+	} else {
+		doc["openapi"] = "3.0.3"
+		doc["components"] = map[string]interface{}{"schemas": schemas}
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+var benchmarkSizes = []struct{ schemas, depth int }{
+	{100, 2},
+	{100, 8},
+	{1000, 2},
+}
+
+func TestLoadFileDecodeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"openapi.yaml": `
+            openapi: 3.0.3
+            paths:
+              /pets:
+                get:
+                  responses:
+                    2X0:
+                      description: ok
+                      content:
+                        application/json:
+                          schema:
+                            $ref: schemas/pet.yaml
+            components:
+              schemas:
+                Owner:
+                  type: strin
+                Toy:
+                  type: object
+                  properties:
+                    name:
+                      type: string
+                      maxLength: many
+            `,
+		"schemas/pet.yaml": `
+            type: object
+            properties:
+              name:
+                type: strang
+            `,
+	})
+
+	_, _, err := LoadFile(filepath.Join(dir, "openapi.yaml"))
+
+	root, pet := filepath.Join(dir, "openapi.yaml"), filepath.Join(dir, "schemas", "pet.yaml")
+	expected := diag.List{
+		{Pos: diag.Pos{File: root, Line: 7, Column: 9}, Message: "bad response code: 2X0"},
+		{Pos: diag.Pos{File: root, Line: 16, Column: 13}, Message: "bad schema type: strin"},
+		{Pos: diag.Pos{File: root, Line: 22, Column: 11}, Message: "cannot unmarshal !!str `many` into int64"},
+		{Pos: diag.Pos{File: pet, Line: 5, Column: 11}, Message: "bad schema type: strang"},
+	}
+	if !reflect.DeepEqual(err, expected) {
+		t.Error("got:", err, "expected:", expected)
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	for _, swagger := range []bool{false, true} {
+		version := "openapi 3.0"
+		if swagger {
+			version = "swagger 2.0"
+		}
+
+		for _, size := range benchmarkSizes {
+			d := syntheticSpec(size.schemas, size.depth, swagger)
+			b.Run(fmt.Sprintf("%s/schemas=%d/depth=%d", version, size.schemas, size.depth), func(b *testing.B) {
+				b.SetBytes(int64(len(d)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := Load(d); err != nil {
+						b.Fatal("could not load. got error:", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkLoadFile(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		d := syntheticSpec(size.schemas, size.depth, false)
+		path := filepath.Join(writeFiles(b, map[string]string{"openapi.json": string(d)}), "openapi.json")
+		b.Run(fmt.Sprintf("schemas=%d/depth=%d", size.schemas, size.depth), func(b *testing.B) {
+			b.SetBytes(int64(len(d)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := LoadFile(path); err != nil {
+					b.Fatal("could not load. got error:", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jbowes/oag/diag"
+	"github.com/jbowes/oag/openapi/jsonpointer"
+)
+
+// applyOverlay applies the changes in file to the document doc, returning the
+// changed document. file may hold an OpenAPI Overlay 1.0 document, or an RFC
+// 6902 JSON Patch.
+//
+// doc is not modified, as its values may be shared. Values added from file
+// have their source positions recorded in pos.
+func applyOverlay(doc *yaml.Node, pos map[string]diag.Pos, file string) (*yaml.Node, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var n yaml.Node
+	if err = yaml.Unmarshal(b, &n); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	if len(n.Content) == 0 {
+		return nil, fmt.Errorf("%s: empty document", file)
+	}
+
+	p := &patcher{doc: doc, pos: pos, file: file}
+	switch root := n.Content[0]; {
+	case root.Kind == yaml.SequenceNode:
+		p.patch(root)
+	case valueOf(root, "overlay") != nil:
+		p.overlay(root)
+	default:
+		return nil, fmt.Errorf("%s: not an overlay or JSON Patch document", file)
+	}
+
+	return p.doc, p.diags.Err()
+}
+
+// patcher applies changes to a document. Problems are reported by their
+// position in the overlay or patch file.
+type patcher struct {
+	doc   *yaml.Node
+	pos   map[string]diag.Pos
+	file  string
+	diags diag.List
+}
+
+func (p *patcher) errorf(n *yaml.Node, at []string, format string, args ...interface{}) {
+	p.diags = append(p.diags, diag.Diagnostic{
+		Severity: diag.Error,
+		Pos:      diag.Pos{File: p.file, Line: n.Line, Column: n.Column},
+		Pointer:  "#" + jsonpointer.Format(at...),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// mark records the source position of the entry n, as the value v at the
+// reference tokens at, along with the values v holds.
+func (p *patcher) mark(at []string, n, v *yaml.Node) {
+	p.pos[jsonpointer.Format(at...)] = diag.Pos{File: p.file, Line: n.Line, Column: n.Column}
+
+	switch v = resolveAlias(v); v.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(v.Content); i += 2 {
+			p.mark(appendToken(at, v.Content[i].Value), v.Content[i], v.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for i, item := range v.Content {
+			p.mark(appendToken(at, strconv.Itoa(i)), item, item)
+		}
+	}
+}
+
+// overlay applies each action of an OpenAPI Overlay, in order. Targets that
+// match no values are reported as errors, rather than ignored, as they
+// usually mean the document has changed underneath the overlay.
+func (p *patcher) overlay(root *yaml.Node) {
+	var o struct {
+		Overlay string
+		Actions []yaml.Node
+	}
+	if err := root.Decode(&o); err != nil {
+		p.errorf(root, nil, "%s", err)
+		return
+	}
+
+	if !strings.HasPrefix(o.Overlay, "1.") {
+		p.errorf(valueOf(root, "overlay"), []string{"overlay"}, "unsupported overlay version %q", o.Overlay)
+		return
+	}
+
+	for i := range o.Actions {
+		p.action(&o.Actions[i], []string{"actions", strconv.Itoa(i)})
+	}
+}
+
+func (p *patcher) action(n *yaml.Node, at []string) {
+	var a struct {
+		Target string
+		Update yaml.Node
+		Remove bool
+	}
+	if err := n.Decode(&a); err != nil {
+		p.errorf(n, at, "%s", err)
+		return
+	}
+
+	tn := valueOf(n, "target")
+	if tn == nil {
+		p.errorf(n, at, "action has no target")
+		return
+	}
+	tat := appendToken(at, "target")
+
+	segs, err := parseJSONPath(a.Target)
+	if err != nil {
+		p.errorf(tn, tat, "%s", err)
+		return
+	}
+
+	ms := selectPath(p.doc, segs)
+	if len(ms) == 0 {
+		p.errorf(tn, tat, "target %s matched nothing", a.Target)
+		return
+	}
+
+	switch {
+	case a.Remove:
+		// Remove later values first, so earlier matches in the same list
+		// keep their index.
+		for i := len(ms) - 1; i >= 0; i-- {
+			if p.doc, err = remove(p.doc, ms[i].at); err != nil {
+				p.errorf(tn, tat, "cannot remove %s: %s", jsonpointer.Format(ms[i].at...), err)
+				return
+			}
+		}
+	case a.Update.Kind != 0:
+		for _, m := range ms {
+			if err = p.update(m.at, &a.Update); err != nil {
+				p.errorf(tn, tat, "cannot update %s: %s", jsonpointer.Format(m.at...), err)
+				return
+			}
+		}
+	default:
+		p.errorf(n, at, "action has no update, and does not remove its target")
+	}
+}
+
+// update merges the overlay update v into the value at the reference tokens
+// at. Maps are merged, with nested maps merged in turn, and other values from
+// v replacing those in the document. If the value is a list, v is appended to
+// it.
+func (p *patcher) update(at []string, v *yaml.Node) error {
+	var err error
+	p.doc, err = edit(p.doc, at, func(n *yaml.Node) (*yaml.Node, error) {
+		switch {
+		case n.Kind == yaml.SequenceNode:
+			p.mark(appendToken(at, strconv.Itoa(len(n.Content))), v, v)
+
+			out := copyNode(n)
+			out.Content = append(out.Content, v)
+			return out, nil
+		case n.Kind == yaml.MappingNode && resolveAlias(v).Kind == yaml.MappingNode:
+			v = resolveAlias(v)
+			for i := 0; i+1 < len(v.Content); i += 2 {
+				p.mark(appendToken(at, v.Content[i].Value), v.Content[i], v.Content[i+1])
+			}
+			return merge(n, v), nil
+		case n.Kind == yaml.MappingNode:
+			return nil, errors.New("update for an object must be an object")
+		default:
+			return nil, errors.New("target is not an object or array")
+		}
+	})
+
+	return err
+}
+
+// merge returns a copy of the map n, with the entries of the map v merged in.
+func merge(n, v *yaml.Node) *yaml.Node {
+	out := copyNode(n)
+	for i := 0; i+1 < len(v.Content); i += 2 {
+		key, value := v.Content[i], v.Content[i+1]
+
+		j := entryIndex(out, key.Value)
+		switch {
+		case j < 0:
+			out.Content = append(out.Content, key, value)
+		case resolveAlias(out.Content[j]).Kind == yaml.MappingNode && resolveAlias(value).Kind == yaml.MappingNode:
+			out.Content[j] = merge(resolveAlias(out.Content[j]), resolveAlias(value))
+		default:
+			out.Content[j] = value
+		}
+	}
+
+	return out
+}
+
+// patch applies the operations of a JSON Patch, in order, stopping at the
+// first that fails.
+func (p *patcher) patch(root *yaml.Node) {
+	for i, n := range root.Content {
+		if err := p.op(n); err != nil {
+			p.errorf(n, []string{strconv.Itoa(i)}, "%s", err)
+			return
+		}
+	}
+}
+
+func (p *patcher) op(n *yaml.Node) error {
+	var op struct {
+		Op    string
+		Path  string
+		From  string
+		Value yaml.Node
+	}
+	if err := n.Decode(&op); err != nil {
+		return err
+	}
+
+	path, err := jsonpointer.Parse(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value.Kind == 0 {
+			return fmt.Errorf("%s operation has no value", op.Op)
+		}
+	case "move", "copy":
+		from, err := jsonpointer.Parse(op.From)
+		if err != nil {
+			return err
+		}
+
+		v := find(p.doc, from)
+		if v == nil {
+			return fmt.Errorf("from %s does not exist", op.From)
+		}
+
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return fmt.Errorf("cannot move %s into itself", op.From)
+			}
+			if p.doc, err = remove(p.doc, from); err != nil {
+				return err
+			}
+		}
+
+		p.copyPos(from, path)
+		p.doc, err = add(p.doc, path, v)
+		return err
+	case "remove":
+		p.doc, err = remove(p.doc, path)
+		return err
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	switch op.Op {
+	case "add":
+		p.doc, err = add(p.doc, path, &op.Value)
+	case "replace":
+		p.doc, err = edit(p.doc, path, func(*yaml.Node) (*yaml.Node, error) { return &op.Value, nil })
+		if err != nil {
+			err = fmt.Errorf("path %s does not exist", op.Path)
+		}
+	case "test":
+		if v := find(p.doc, path); v == nil || !equalNodes(v, &op.Value) {
+			err = fmt.Errorf("test of %s failed", op.Path)
+		}
+	}
+
+	if err == nil && op.Op != "test" {
+		if len(path) > 0 && path[len(path)-1] == "-" {
+			parent := path[:len(path)-1]
+			path = appendToken(parent, strconv.Itoa(len(resolveAlias(find(p.doc, parent)).Content)-1))
+		}
+		p.mark(path, &op.Value, &op.Value)
+	}
+
+	return err
+}
+
+// copyPos records the source positions of the value at the reference tokens
+// from, and the values it holds, as those of the value at to.
+func (p *patcher) copyPos(from, to []string) {
+	f, t := jsonpointer.Format(from...), jsonpointer.Format(to...)
+	for ptr, pos := range p.pos {
+		if ptr == f || strings.HasPrefix(ptr, f+"/") {
+			p.pos[t+ptr[len(f):]] = pos
+		}
+	}
+}
+
+// find returns the value at the reference tokens at in n, or nil if there is
+// none.
+func find(n *yaml.Node, at []string) *yaml.Node {
+	for _, tok := range at {
+		n = resolveAlias(n)
+
+		i := entryIndex(n, tok)
+		if i < 0 {
+			return nil
+		}
+		n = n.Content[i]
+	}
+
+	return n
+}
+
+// entryIndex returns the index into n.Content of the value for tok in the map
+// or list n, or -1 if there is none.
+func entryIndex(n *yaml.Node, tok string) int {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == tok {
+				return i + 1
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(tok); err == nil && i >= 0 && i < len(n.Content) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+var errNotFound = errors.New("value does not exist")
+
+// edit returns a copy of n, with the value at the reference tokens at
+// replaced by the result of fn. The maps and lists holding the value are
+// copied rather than changed, as values may be shared through aliases.
+func edit(n *yaml.Node, at []string, fn func(*yaml.Node) (*yaml.Node, error)) (*yaml.Node, error) {
+	n = resolveAlias(n)
+	if len(at) == 0 {
+		return fn(n)
+	}
+
+	i := entryIndex(n, at[0])
+	if i < 0 {
+		return nil, errNotFound
+	}
+
+	v, err := edit(n.Content[i], at[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	out := copyNode(n)
+	out.Content[i] = v
+	return out, nil
+}
+
+// add returns a copy of doc with v added at the reference tokens at. A value
+// already in a map is replaced, while a list has v inserted at the index, or
+// appended for the index "-".
+func add(doc *yaml.Node, at []string, v *yaml.Node) (*yaml.Node, error) {
+	if len(at) == 0 {
+		return v, nil
+	}
+
+	tok := at[len(at)-1]
+	out, err := edit(doc, at[:len(at)-1], func(n *yaml.Node) (*yaml.Node, error) {
+		out := copyNode(n)
+		switch n.Kind {
+		case yaml.MappingNode:
+			if i := entryIndex(n, tok); i >= 0 {
+				out.Content[i] = v
+			} else {
+				out.Content = append(out.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tok}, v)
+			}
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(tok)
+			switch {
+			case tok == "-":
+				i = len(n.Content)
+			case err != nil || i < 0 || i > len(n.Content):
+				return nil, fmt.Errorf("bad list index %q", tok)
+			}
+			out.Content = append(out.Content[:i], append([]*yaml.Node{v}, n.Content[i:]...)...)
+		default:
+			return nil, fmt.Errorf("%s is not an object or array", jsonpointer.Format(at[:len(at)-1]...))
+		}
+		return out, nil
+	})
+	if err == errNotFound {
+		err = fmt.Errorf("%s does not exist", jsonpointer.Format(at[:len(at)-1]...))
+	}
+
+	return out, err
+}
+
+// remove returns a copy of doc without the value at the reference tokens at.
+//
+// XXX positions recorded for later items of a list aren't moved down.
+func remove(doc *yaml.Node, at []string) (*yaml.Node, error) {
+	if len(at) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+
+	tok := at[len(at)-1]
+	out, err := edit(doc, at[:len(at)-1], func(n *yaml.Node) (*yaml.Node, error) {
+		i := entryIndex(n, tok)
+		if i < 0 {
+			return nil, errNotFound
+		}
+
+		out := copyNode(n)
+		if n.Kind == yaml.MappingNode {
+			out.Content = append(out.Content[:i-1], out.Content[i+1:]...)
+		} else {
+			out.Content = append(out.Content[:i], out.Content[i+1:]...)
+		}
+		return out, nil
+	})
+	if err == errNotFound {
+		err = fmt.Errorf("%s does not exist", jsonpointer.Format(at...))
+	}
+
+	return out, err
+}
+
+// equalNodes reports if a and b hold the same value. Numbers are compared by
+// value, and maps regardless of the order of their keys.
+func equalNodes(a, b *yaml.Node) bool {
+	a, b = resolveAlias(a), resolveAlias(b)
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+
+	switch a.Kind {
+	case yaml.ScalarNode:
+		at, bt := a.ShortTag(), b.ShortTag()
+		if isNumber(at) && isNumber(bt) {
+			x, errX := strconv.ParseFloat(a.Value, 64)
+			y, errY := strconv.ParseFloat(b.Value, 64)
+			return errX == nil && errY == nil && x == y
+		}
+		return at == bt && a.Value == b.Value
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(a.Content); i += 2 {
+			j := entryIndex(b, a.Content[i].Value)
+			if j < 0 || !equalNodes(a.Content[i+1], b.Content[j]) {
+				return false
+			}
+		}
+	case yaml.SequenceNode:
+		for i := range a.Content {
+			if !equalNodes(a.Content[i], b.Content[i]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func isNumber(tag string) bool { return tag == "!!int" || tag == "!!float" }
@@ -0,0 +1,368 @@
+package openapi
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lithammer/dedent"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jbowes/oag/diag"
+)
+
+const overlayDoc = `
+    openapi: 3.0.3
+    paths:
+      /pets:
+        get:
+          parameters:
+            - name: limit
+              in: query
+            - name: X-Trace
+              in: header
+    components:
+      schemas:
+        Pet:
+          type: object
+          properties:
+            id:
+              type: integer
+`
+
+func TestApplyOverlay(t *testing.T) {
+	tcs := []struct {
+		name    string
+		overlay string
+		out     string
+	}{
+		{
+			"overlay update",
+			`
+            overlay: 1.0.0
+            info:
+              title: fix pets
+              version: "1"
+            actions:
+              - target: $.components.schemas.Pet
+                update:
+                  required: [id]
+                  properties:
+                    id:
+                      type: string
+            `,
+			`
+            openapi: 3.0.3
+            paths:
+              /pets:
+                get:
+                  parameters:
+                    - name: limit
+                      in: query
+                    - name: X-Trace
+                      in: header
+            components:
+              schemas:
+                Pet:
+                  type: object
+                  properties:
+                    id:
+                      type: string
+                  required: [id]
+            `,
+		},
+		{
+			"overlay update list",
+			`
+            overlay: 1.0.0
+            actions:
+              - target: $.paths['/pets'].get.parameters
+                update:
+                  name: offset
+                  in: query
+            `,
+			`
+            openapi: 3.0.3
+            paths:
+              /pets:
+                get:
+                  parameters:
+                    - name: limit
+                      in: query
+                    - name: X-Trace
+                      in: header
+                    - name: offset
+                      in: query
+            components:
+              schemas:
+                Pet:
+                  type: object
+                  properties:
+                    id:
+                      type: integer
+            `,
+		},
+		{
+			"overlay remove",
+			`
+            overlay: 1.0.0
+            actions:
+              - target: $.paths.*.*.parameters[?@.in == 'header']
+                remove: true
+              - target: $.components
+                remove: true
+            `,
+			`
+            openapi: 3.0.3
+            paths:
+              /pets:
+                get:
+                  parameters:
+                    - name: limit
+                      in: query
+            `,
+		},
+		{
+			"json patch",
+			`
+            - op: test
+              path: /components/schemas/Pet/properties/id/type
+              value: integer
+            - op: replace
+              path: /components/schemas/Pet/properties/id/type
+              value: string
+            - op: add
+              path: /components/schemas/Pet/required
+              value: [id]
+            - op: remove
+              path: /paths/~1pets/get/parameters/1
+            - op: add
+              path: /paths/~1pets/get/parameters/0
+              value:
+                name: offset
+                in: query
+            - op: move
+              from: /paths/~1pets
+              path: /paths/~1animals
+            - op: copy
+              from: /components/schemas/Pet
+              path: /components/schemas/Animal
+            `,
+			`
+            openapi: 3.0.3
+            paths:
+              /animals:
+                get:
+                  parameters:
+                    - name: offset
+                      in: query
+                    - name: limit
+                      in: query
+            components:
+              schemas:
+                Pet:
+                  type: object
+                  properties:
+                    id:
+                      type: string
+                  required: [id]
+                Animal:
+                  type: object
+                  properties:
+                    id:
+                      type: string
+                  required: [id]
+            `,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"overlay.yaml": tc.overlay})
+
+			var n yaml.Node
+			if err := yaml.Unmarshal([]byte(dedent.Dedent(overlayDoc)), &n); err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := applyOverlay(n.Content[0], make(map[string]diag.Pos), filepath.Join(dir, "overlay.yaml"))
+			if err != nil {
+				t.Fatal("could not apply overlay. got error:", err)
+			}
+
+			var buf bytes.Buffer
+			enc := yaml.NewEncoder(&buf)
+			enc.SetIndent(2)
+			if err = enc.Encode(out); err != nil {
+				t.Fatal(err)
+			}
+
+			if expected := strings.TrimPrefix(dedent.Dedent(tc.out), "\n"); buf.String() != expected {
+				t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+			}
+
+			var orig bytes.Buffer
+			if err = yaml.NewEncoder(&orig).Encode(n.Content[0]); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(orig.String(), "X-Trace") {
+				t.Error("original document was modified")
+			}
+		})
+	}
+}
+
+func TestApplyOverlayErrors(t *testing.T) {
+	tcs := []struct {
+		name    string
+		overlay string
+		out     string
+	}{
+		{
+			"unmatched targets",
+			`
+            overlay: 1.0.0
+            actions:
+              - target: $.paths['/users']
+                remove: true
+              - target: $.components.schemas.Pet
+                update:
+                  required: [id]
+              - target: $.components.schemas.Order
+                update:
+                  required: [id]
+            `,
+			"overlay.yaml:4:13: error: #/actions/0/target: target $.paths['/users'] matched nothing\n" +
+				"overlay.yaml:9:13: error: #/actions/2/target: target $.components.schemas.Order matched nothing",
+		},
+		{
+			"bad target",
+			`
+            overlay: 1.0.0
+            actions:
+              - target: $.tags[0:1]
+                remove: true
+            `,
+			`overlay.yaml:4:13: error: #/actions/0/target: invalid JSONPath "$.tags[0:1]" at offset 8: slices are not supported`,
+		},
+		{
+			"bad update",
+			`
+            overlay: 1.0.0
+            actions:
+              - target: $.openapi
+                update: 3.1.0
+            `,
+			"overlay.yaml:4:13: error: #/actions/0/target: cannot update /openapi: target is not an object or array",
+		},
+		{
+			"unsupported version",
+			`
+            overlay: 2.0.0
+            actions: []
+            `,
+			`overlay.yaml:2:10: error: #/overlay: unsupported overlay version "2.0.0"`,
+		},
+		{
+			"patch path not found",
+			`
+            - op: add
+              path: /components/schemas/Pet/required
+              value: [id]
+            - op: replace
+              path: /components/schemas/Order/type
+              value: object
+            - op: remove
+              path: /info
+            `,
+			"overlay.yaml:5:3: error: #/1: path /components/schemas/Order/type does not exist",
+		},
+		{
+			"patch test failed",
+			`
+            - op: test
+              path: /openapi
+              value: 3.1.0
+            `,
+			"overlay.yaml:2:3: error: #/0: test of /openapi failed",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"overlay.yaml": tc.overlay})
+
+			var n yaml.Node
+			if err := yaml.Unmarshal([]byte(dedent.Dedent(overlayDoc)), &n); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := applyOverlay(n.Content[0], make(map[string]diag.Pos), filepath.Join(dir, "overlay.yaml"))
+			if err == nil {
+				t.Fatal("expected error but got none")
+			}
+
+			if out := strings.ReplaceAll(err.Error(), dir+string(filepath.Separator), ""); out != tc.out {
+				t.Errorf("got:\n%s\nexpected:\n%s", out, tc.out)
+			}
+		})
+	}
+}
+
+func TestLoadFileOverlays(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"openapi.yaml": `
+            swagger: "2.0"
+            info:
+              title: test
+              version: "1"
+            paths: {}
+            definitions:
+              Pet:
+                type: object
+            `,
+		"required.yaml": `
+            overlay: 1.0.0
+            actions:
+              - target: $.definitions.Pet
+                update:
+                  required: [id]
+                  properties:
+                    id:
+                      type: string
+            `,
+		"patch.yaml": `
+            - op: add
+              path: /definitions/Pet/properties/name
+              value:
+                type: string
+            `,
+	})
+
+	doc, src, err := LoadFile(filepath.Join(dir, "openapi.yaml"), filepath.Join(dir, "required.yaml"), filepath.Join(dir, "patch.yaml"))
+	if err != nil {
+		t.Fatal("could not load. got error:", err)
+	}
+
+	if names := schemaNames(doc); len(names) != 1 || names[0] != "Pet" {
+		t.Fatal("got schemas:", names)
+	}
+
+	tcs := []struct {
+		in   string
+		file string
+		line int
+	}{
+		{"#/components/schemas/Pet/properties/id", "required.yaml", 8},
+		{"#/components/schemas/Pet/properties/name", "patch.yaml", 5},
+		{"#/components/schemas/Pet", "openapi.yaml", 8},
+	}
+
+	for _, tc := range tcs {
+		pos := src.Locate(tc.in)
+		if pos.File != filepath.Join(dir, tc.file) || pos.Line != tc.line {
+			t.Error("got:", pos, "expected:", tc.file, tc.line, "for", tc.in)
+		}
+	}
+}
@@ -0,0 +1,597 @@
+package openapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jbowes/oag/diag"
+	"github.com/jbowes/oag/openapi/jsonpointer"
+)
+
+// bundle loads the document at path, along with any files it references, and
+// returns a single self contained document node, with the source positions
+// of its values by JSON pointer.
+//
+// Schemas referenced from other files are added to the document's schema
+// definitions, and references to them are rewritten as local references. All
+// other external references, such as path items or parameters, are replaced
+// by the referenced value.
+func bundle(path string) (*yaml.Node, map[string]diag.Pos, error) {
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := &resolver{
+		root:    root,
+		dir:     filepath.Dir(path),
+		files:   make(map[string]*yaml.Node),
+		schemas: make(map[target]*external),
+		pos:     make(map[string]diag.Pos),
+	}
+
+	doc, err := r.load(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r.mark(nil, root, doc)
+	if doc, err = r.walk(doc, root, other, nil); err != nil {
+		return nil, nil, err
+	}
+
+	if len(r.schemas) > 0 {
+		r.addSchemas(doc)
+	}
+
+	return doc, r.pos, nil
+}
+
+// target is the location of a referenced value.
+type target struct {
+	file    string // absolute path
+	pointer string // JSON pointer within file
+}
+
+func (t target) String() string { return t.file + "#" + t.pointer }
+
+// external is a schema defined in a file other than the root document.
+type external struct {
+	target
+	name  string
+	value *yaml.Node
+	sites []*yaml.Node // $ref values to rewrite once named
+
+	pos map[string]diag.Pos // source positions, relative to the schema
+}
+
+type resolver struct {
+	root  string
+	dir   string                // directory of the root document, as given
+	files map[string]*yaml.Node // parsed files, by absolute path
+
+	schemas map[target]*external
+	stack   []target // inlined references being resolved
+
+	pos map[string]diag.Pos // source positions, by JSON pointer
+}
+
+// context is the kind of value being walked, determining how any references
+// within it are resolved.
+type context uint8
+
+const (
+	other      context = iota // an OpenAPI object
+	otherMap                  // a map of names to OpenAPI objects
+	components                // the components object
+	schema                    // a schema
+	schemaMap                 // a map of names to schemas
+	schemaList                // a list of schemas
+	data                      // literal data, such as examples, that isn't walked
+)
+
+var (
+	otherContexts = map[string]context{
+		"components":  components,
+		"definitions": schemaMap,
+		"schema":      schema,
+
+		"paths":               otherMap,
+		"webhooks":            otherMap,
+		"parameters":          otherMap,
+		"responses":           otherMap,
+		"headers":             otherMap,
+		"content":             otherMap,
+		"encoding":            otherMap,
+		"links":               otherMap,
+		"callbacks":           otherMap,
+		"variables":           otherMap,
+		"examples":            otherMap,
+		"securityDefinitions": otherMap,
+
+		"example":  data,
+		"default":  data,
+		"enum":     data,
+		"value":    data,
+		"security": data,
+	}
+
+	schemaContexts = map[string]context{
+		"properties":        schemaMap,
+		"patternProperties": schemaMap,
+		"definitions":       schemaMap,
+		"$defs":             schemaMap,
+		"dependentSchemas":  schemaMap,
+
+		"items":                 schema,
+		"additionalItems":       schema,
+		"additionalProperties":  schema,
+		"unevaluatedItems":      schema,
+		"unevaluatedProperties": schema,
+		"propertyNames":         schema,
+		"contains":              schema,
+		"not":                   schema,
+		"if":                    schema,
+		"then":                  schema,
+		"else":                  schema,
+
+		"allOf":       schemaList,
+		"anyOf":       schemaList,
+		"oneOf":       schemaList,
+		"prefixItems": schemaList,
+	}
+)
+
+func (r *resolver) load(file string) (*yaml.Node, error) {
+	if doc, ok := r.files[file]; ok {
+		return doc, nil
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var n yaml.Node
+	if err = yaml.Unmarshal(b, &n); err != nil {
+		return nil, fmt.Errorf("%s: %s", r.display(file), err)
+	}
+	if len(n.Content) == 0 {
+		return nil, fmt.Errorf("%s: empty document", r.display(file))
+	}
+
+	doc := n.Content[0]
+	r.files[file] = doc
+	return doc, nil
+}
+
+// display returns the name of file to use in positions, relative to the root
+// document as it was given.
+func (r *resolver) display(file string) string {
+	rel, err := filepath.Rel(filepath.Dir(r.root), file)
+	if err != nil {
+		return file
+	}
+
+	return filepath.Join(r.dir, rel)
+}
+
+// mark records the source position of the value at the reference tokens at.
+func (r *resolver) mark(at []string, file string, n *yaml.Node) {
+	r.pos[jsonpointer.Format(at...)] = diag.Pos{File: r.display(file), Line: n.Line, Column: n.Column}
+}
+
+// walk returns n, with any references contained in it resolved. at holds the
+// reference tokens of n's location in the bundled document.
+func (r *resolver) walk(n *yaml.Node, file string, ctx context, at []string) (*yaml.Node, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		switch ctx {
+		case schemaMap:
+			return r.walkItems(n, file, at, func(string) context { return schema })
+		case otherMap:
+			return r.walkItems(n, file, at, func(string) context { return other })
+		case components:
+			return r.walkItems(n, file, at, func(key string) context {
+				if key == "schemas" {
+					return schemaMap
+				}
+				return otherMap
+			})
+		}
+
+		if ref, ok := refOf(n); ok {
+			return r.resolve(n, ref, file, ctx, at)
+		}
+
+		if ctx == schema {
+			return r.walkItems(n, file, at, func(key string) context {
+				if c, ok := schemaContexts[key]; ok {
+					return c
+				}
+				return data
+			})
+		}
+
+		return r.walkItems(n, file, at, func(key string) context {
+			if c, ok := otherContexts[key]; ok {
+				return c
+			}
+			return other
+		})
+	case yaml.SequenceNode:
+		// Lists hold the same kind of values as maps in the same context, ie
+		// operation and path item parameters, or draft 4 tuple items.
+		switch ctx {
+		case schemaList:
+			ctx = schema
+		case otherMap:
+			ctx = other
+		}
+
+		out := copyNode(n)
+		for i, item := range n.Content {
+			iat := append(at[:len(at):len(at)], strconv.Itoa(i))
+			r.mark(iat, file, item)
+
+			var err error
+			if out.Content[i], err = r.walk(item, file, ctx, iat); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return n, nil
+	}
+}
+
+func (r *resolver) walkItems(n *yaml.Node, file string, at []string, ctxFor func(string) context) (*yaml.Node, error) {
+	out := copyNode(n)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+
+		kat := append(at[:len(at):len(at)], key.Value)
+		r.mark(kat, file, key)
+
+		ctx := ctxFor(key.Value)
+		if ctx == data {
+			continue
+		}
+
+		var err error
+		if out.Content[i+1], err = r.walk(value, file, ctx, kat); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// copyNode returns a shallow copy of n, with its own list of content.
+func copyNode(n *yaml.Node) *yaml.Node {
+	out := *n
+	out.Anchor = "" // aliases are expanded, so anchors would be duplicated
+	out.Content = append([]*yaml.Node(nil), n.Content...)
+	return &out
+}
+
+func refOf(n *yaml.Node) (string, bool) {
+	if v := valueOf(n, "$ref"); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value, true
+	}
+
+	return "", false
+}
+
+// valueOf returns the value of key in the map n, or nil if there is none.
+func valueOf(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// targetOf returns the target of a reference found in file.
+func targetOf(ref, file string) (target, error) {
+	parts := strings.SplitN(ref, "#", 2)
+
+	t := target{file: file}
+	if len(parts) == 2 {
+		t.pointer = parts[1]
+	}
+
+	switch {
+	case parts[0] == "":
+	case strings.Contains(parts[0], "://"):
+		return t, fmt.Errorf("remote reference %s is not supported", ref)
+	case filepath.IsAbs(parts[0]):
+		t.file = filepath.Clean(parts[0])
+	default:
+		t.file = filepath.Join(filepath.Dir(file), parts[0])
+	}
+
+	return t, nil
+}
+
+// resolve resolves the reference ref held in the map n, found in file.
+func (r *resolver) resolve(n *yaml.Node, ref, file string, ctx context, at []string) (*yaml.Node, error) {
+	t, err := targetOf(ref, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.file == r.root {
+		if file == r.root {
+			return n, nil
+		}
+
+		out, _ := replaceRef(n, "#"+t.pointer)
+		return out, nil
+	}
+
+	if ctx == schema {
+		return r.resolveSchema(n, t)
+	}
+
+	for _, s := range r.stack {
+		if s == t {
+			return nil, fmt.Errorf("circular reference to %s", t)
+		}
+	}
+
+	r.stack = append(r.stack, t)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+
+	v, err := r.lookup(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.walk(v, t.file, ctx, at)
+}
+
+// resolveSchema registers the externally defined schema at t, if it hasn't
+// been already, and returns a reference to it that will be rewritten once all
+// external schemas are named.
+func (r *resolver) resolveSchema(n *yaml.Node, t target) (*yaml.Node, error) {
+	out, site := replaceRef(n, "")
+
+	if e, ok := r.schemas[t]; ok {
+		e.sites = append(e.sites, site)
+		return out, nil
+	}
+
+	if err := r.checkRefCycle(t); err != nil {
+		return nil, err
+	}
+
+	e := &external{target: t, sites: []*yaml.Node{site}, pos: make(map[string]diag.Pos)}
+	r.schemas[t] = e // registered before walking, to allow recursive schemas
+
+	v, err := r.lookup(t)
+	if err != nil {
+		return nil, err
+	}
+
+	// Positions are relative to the schema until it is named.
+	pos := r.pos
+	r.pos = e.pos
+	defer func() { r.pos = pos }()
+
+	r.mark(nil, t.file, v)
+	e.value, err = r.walk(v, t.file, schema, nil)
+	return out, err
+}
+
+// checkRefCycle reports an error if t is a reference that only leads to other
+// references, eventually referencing itself.
+func (r *resolver) checkRefCycle(t target) error {
+	seen := map[target]struct{}{}
+	for {
+		if _, ok := seen[t]; ok {
+			return fmt.Errorf("circular reference to %s", t)
+		}
+		seen[t] = struct{}{}
+
+		v, err := r.lookup(t)
+		if err != nil {
+			return err
+		}
+
+		if v.Kind != yaml.MappingNode || len(v.Content) != 2 {
+			return nil
+		}
+
+		ref, ok := refOf(v)
+		if !ok {
+			return nil
+		}
+
+		if t, err = targetOf(ref, t.file); err != nil {
+			return err
+		}
+	}
+}
+
+// lookup returns the value at t.
+func (r *resolver) lookup(t target) (*yaml.Node, error) {
+	doc, err := r.load(t.file)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := jsonpointer.Parse("#" + t.pointer)
+	if err != nil {
+		return nil, fmt.Errorf("reference to %s: %s", t, err)
+	}
+
+	v := find(doc, tokens)
+	if v == nil {
+		return nil, fmt.Errorf("unresolved reference to %s", t)
+	}
+
+	return v, nil
+}
+
+// replaceRef returns a copy of the map n with its $ref value set to ref, along
+// with the new $ref value.
+func replaceRef(n *yaml.Node, ref string) (*yaml.Node, *yaml.Node) {
+	out := copyNode(n)
+
+	var site *yaml.Node
+	for i := 0; i+1 < len(out.Content); i += 2 {
+		if out.Content[i].Value == "$ref" {
+			v := *out.Content[i+1]
+			v.Value = ref
+			site = &v
+			out.Content[i+1] = site
+		}
+	}
+
+	return out, site
+}
+
+// addSchemas names all external schemas, adds them to doc's schema
+// definitions, and rewrites references to them.
+func (r *resolver) addSchemas(doc *yaml.Node) {
+	prefix := []string{"components", "schemas"}
+	if valueOf(doc, "swagger") != nil {
+		prefix = []string{"definitions"}
+	}
+
+	defs := doc
+	for _, key := range prefix {
+		defs = childMap(defs, key)
+	}
+
+	for _, e := range r.nameSchemas(defs) {
+		ptr := jsonpointer.Format(append(prefix, e.name)...)
+		for _, site := range e.sites {
+			site.Value = "#" + ptr
+		}
+
+		defs.Content = append(defs.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: e.name}, e.value)
+
+		for rel, pos := range e.pos {
+			r.pos[ptr+rel] = pos
+		}
+	}
+}
+
+// childMap returns the map held at key in the map n, adding it if missing.
+func childMap(n *yaml.Node, key string) *yaml.Node {
+	if v := valueOf(n, key); v != nil && v.Kind == yaml.MappingNode {
+		return v
+	}
+
+	v := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content[i+1] = v // replaces an empty value
+			return v
+		}
+	}
+
+	n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+	return v
+}
+
+// nameSchemas assigns names to all external schemas, returning them in name
+// order.
+//
+// A schema is named after the last segment of the pointer referencing it, or
+// the file name if the whole file is referenced. If that name is used more
+// than once, it is qualified with the file's path relative to the root
+// document. Names are assigned only after all schemas are found, so they do
+// not depend on the order references appear in.
+func (r *resolver) nameSchemas(defs *yaml.Node) []*external {
+	all := make([]*external, 0, len(r.schemas))
+	for _, e := range r.schemas {
+		all = append(all, e)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].file != all[j].file {
+			return all[i].file < all[j].file
+		}
+		return all[i].pointer < all[j].pointer
+	})
+
+	used := make(map[string]int)
+	taken := make(map[string]struct{})
+	for i := 0; i+1 < len(defs.Content); i += 2 {
+		used[defs.Content[i].Value]++
+		taken[defs.Content[i].Value] = struct{}{}
+	}
+
+	bases := make([]string, len(all))
+	for i, e := range all {
+		bases[i] = r.baseName(e.target)
+		used[bases[i]]++
+	}
+
+	for i, e := range all {
+		e.name = bases[i]
+		if used[e.name] > 1 {
+			e.name = r.qualifier(e.file) + "_" + e.name
+		}
+
+		name := e.name
+		for n := 2; ; n++ {
+			if _, ok := taken[name]; !ok {
+				break
+			}
+			name = e.name + strconv.Itoa(n)
+		}
+
+		e.name = name
+		taken[name] = struct{}{}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	return all
+}
+
+func (r *resolver) baseName(t target) string {
+	tokens, _ := jsonpointer.Parse("#" + t.pointer) // already validated by lookup
+	if len(tokens) == 0 || tokens[len(tokens)-1] == "" {
+		return strings.TrimSuffix(filepath.Base(t.file), filepath.Ext(t.file))
+	}
+
+	return tokens[len(tokens)-1]
+}
+
+// qualifier returns a prefix identifying file, based on its path relative to
+// the root document.
+func (r *resolver) qualifier(file string) string {
+	rel, err := filepath.Rel(filepath.Dir(r.root), file)
+	if err != nil {
+		rel = file
+	}
+	rel = strings.TrimSuffix(rel, filepath.Ext(rel))
+
+	var parts []string
+	for _, p := range strings.Split(filepath.ToSlash(rel), "/") {
+		if p != "" && p != "." && p != ".." {
+			parts = append(parts, p)
+		}
+	}
+
+	return strings.Join(parts, "_")
+}
@@ -0,0 +1,218 @@
+package openapi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/lithammer/dedent"
+
+	"github.com/jbowes/oag/openapi/v3"
+)
+
+func writeFiles(t testing.TB, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(dedent.Dedent(content)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func schemaNames(doc *v3.Document) []string {
+	var names []string
+	for _, s := range *doc.Components.Schemas {
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestLoadFileExternalRefs(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"openapi.yaml": `
+            openapi: 3.0.3
+            info:
+              title: test
+              version: "1"
+            paths:
+              /pets:
+                $ref: ./paths/pets.yaml
+            components:
+              schemas:
+                Error:
+                  type: object
+            `,
+		"paths/pets.yaml": `
+            get:
+              parameters:
+                - $ref: ../parameters.yaml#/limit
+              responses:
+                200:
+                  description: ok
+                  content:
+                    application/json:
+                      schema:
+                        type: array
+                        items:
+                          $ref: ../schemas/pet.yaml#/Pet
+                default:
+                  description: error
+                  content:
+                    application/json:
+                      schema:
+                        $ref: ../openapi.yaml#/components/schemas/Error
+            `,
+		"parameters.yaml": `
+            limit:
+              name: limit
+              in: query
+              schema:
+                type: integer
+            `,
+		"schemas/pet.yaml": `
+            Pet:
+              type: object
+              properties:
+                owner:
+                  $ref: ./owner.yaml
+                children:
+                  type: array
+                  items:
+                    $ref: '#/Pet'
+                legacy:
+                  $ref: ./legacy/pet.yaml#/Pet
+            `,
+		"schemas/owner.yaml": `
+            type: object
+            properties:
+              name:
+                type: string
+            `,
+		"schemas/legacy/pet.yaml": `
+            Pet:
+              type: object
+            `,
+	})
+
+	doc, _, err := LoadFile(filepath.Join(dir, "openapi.yaml"))
+	if err != nil {
+		t.Fatal("could not load. got error:", err)
+	}
+
+	expected := []string{"Error", "owner", "schemas_legacy_pet_Pet", "schemas_pet_Pet"}
+	if names := schemaNames(doc); !reflect.DeepEqual(names, expected) {
+		t.Error("wrong schemas. got:", names, "expected:", expected)
+	}
+
+	get := doc.Paths["/pets"].Get
+	if get == nil {
+		t.Fatal("path item not inlined. got:", doc.Paths["/pets"])
+	}
+
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "limit" {
+		t.Error("parameter not inlined. got:", get.Parameters)
+	}
+
+	items := get.Responses.Codes[200].Content["application/json"].Schema.(*v3.ArraySchema).Items
+	if !reflect.DeepEqual(items, &v3.ReferenceSchema{Reference: "#/components/schemas/schemas_pet_Pet"}) {
+		t.Error("wrong items reference. got:", items)
+	}
+
+	def := get.Responses.Default.Content["application/json"].Schema
+	if !reflect.DeepEqual(def, &v3.ReferenceSchema{Reference: "#/components/schemas/Error"}) {
+		t.Error("wrong root reference. got:", def)
+	}
+
+	for _, s := range *doc.Components.Schemas {
+		if s.Name != "schemas_pet_Pet" {
+			continue
+		}
+
+		props := *s.Schema.(*v3.ObjectSchema).Properties
+		children := props[1].Schema.(*v3.ArraySchema).Items
+		if !reflect.DeepEqual(children, &v3.ReferenceSchema{Reference: "#/components/schemas/schemas_pet_Pet"}) {
+			t.Error("wrong recursive reference. got:", children)
+		}
+	}
+}
+
+func TestLoadFileExternalRefsV2(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"swagger.yaml": `
+            swagger: "2.0"
+            info:
+              title: test
+              version: "1"
+            paths:
+              /pets:
+                get:
+                  responses:
+                    200:
+                      description: ok
+                      schema:
+                        $ref: pet.yaml#/definitions/Pet
+            `,
+		"pet.yaml": `
+            definitions:
+              Pet:
+                type: object
+            `,
+	})
+
+	doc, _, err := LoadFile(filepath.Join(dir, "swagger.yaml"))
+	if err != nil {
+		t.Fatal("could not load. got error:", err)
+	}
+
+	if names := schemaNames(doc); !reflect.DeepEqual(names, []string{"Pet"}) {
+		t.Error("wrong schemas. got:", names)
+	}
+}
+
+func TestLoadFileCircularRefs(t *testing.T) {
+	tcs := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"path items", map[string]string{
+			"openapi.yaml": `
+                openapi: 3.0.3
+                paths:
+                  /a:
+                    $ref: a.yaml
+                `,
+			"a.yaml": `$ref: b.yaml`,
+			"b.yaml": `$ref: a.yaml`,
+		}},
+		{"schemas", map[string]string{
+			"openapi.yaml": `
+                openapi: 3.0.3
+                components:
+                  schemas:
+                    A:
+                      $ref: a.yaml
+                `,
+			"a.yaml": `$ref: b.yaml`,
+			"b.yaml": `$ref: a.yaml`,
+		}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeFiles(t, tc.files)
+			if _, _, err := LoadFile(filepath.Join(dir, "openapi.yaml")); err == nil {
+				t.Error("expected error but got none")
+			}
+		})
+	}
+}
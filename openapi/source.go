@@ -0,0 +1,50 @@
+package openapi
+
+import (
+	"strings"
+
+	"github.com/jbowes/oag/diag"
+)
+
+// Source locates the values of a loaded document in the files they were
+// defined in, so problems found in them can be reported by file, line and
+// column.
+type Source struct {
+	file string // the root document
+
+	pos   map[string]diag.Pos // by JSON pointer into the bundled document
+	moved map[string]string   // JSON pointers of converted values, to their origin
+}
+
+// Locate returns the position of the value at the given JSON pointer, in its
+// URI fragment form, or of the closest enclosing value with a known position.
+// It implements diag.Locator.
+func (s *Source) Locate(pointer string) diag.Pos {
+	p := strings.TrimPrefix(pointer, "#")
+	for {
+		if pos, ok := s.pos[s.origin(p)]; ok {
+			return pos
+		}
+
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			return diag.Pos{File: s.file}
+		}
+		p = p[:i]
+	}
+}
+
+// origin returns the pointer p had before the document was converted.
+func (s *Source) origin(p string) string {
+	for prefix := p; ; {
+		if from, ok := s.moved[prefix]; ok {
+			return from + p[len(prefix):]
+		}
+
+		i := strings.LastIndex(prefix, "/")
+		if i < 0 {
+			return p
+		}
+		prefix = prefix[:i]
+	}
+}
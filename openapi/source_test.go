@@ -0,0 +1,176 @@
+package openapi
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jbowes/oag/diag"
+)
+
+func TestSourceLocate(t *testing.T) {
+	tcs := []struct {
+		name  string
+		files map[string]string
+		in    string
+		file  string
+		line  int
+		col   int
+	}{
+		{
+			"schema property",
+			map[string]string{"openapi.yaml": `
+                openapi: 3.0.3
+                components:
+                  schemas:
+                    Pet:
+                      type: object
+                      properties:
+                        name:
+                          not:
+                            type: string
+                `},
+			"#/components/schemas/Pet/properties/name",
+			"openapi.yaml", 8, 9,
+		},
+		{
+			"closest known value",
+			map[string]string{"openapi.yaml": `
+                openapi: 3.0.3
+                paths:
+                  /pets:
+                    get:
+                      responses: {}
+                `},
+			"#/paths/~1pets/get/parameters/0",
+			"openapi.yaml", 5, 5,
+		},
+		{
+			"inlined reference",
+			map[string]string{
+				"openapi.yaml": `
+                openapi: 3.0.3
+                paths:
+                  /pets:
+                    $ref: paths.yaml#/pets
+                `,
+				"paths.yaml": `
+                pets:
+                  get:
+                    parameters:
+                      - name: limit
+                        in: query
+                `,
+			},
+			"#/paths/~1pets/get/parameters/0",
+			"paths.yaml", 5, 9,
+		},
+		{
+			"external schema",
+			map[string]string{
+				"openapi.yaml": `
+                openapi: 3.0.3
+                paths:
+                  /pets:
+                    get:
+                      responses:
+                        200:
+                          description: ok
+                          content:
+                            application/json:
+                              schema:
+                                $ref: schemas/pet.yaml
+                `,
+				"schemas/pet.yaml": `
+                type: object
+                properties:
+                  name:
+                    type: string
+                `,
+			},
+			"#/components/schemas/pet/properties/name",
+			filepath.Join("schemas", "pet.yaml"), 4, 3,
+		},
+		{
+			"converted body parameter",
+			map[string]string{"swagger.yaml": `
+                swagger: "2.0"
+                paths:
+                  /pets:
+                    post:
+                      parameters:
+                        - name: limit
+                          in: query
+                          type: integer
+                        - name: pet
+                          in: body
+                          schema:
+                            type: object
+                            properties:
+                              name:
+                                type: string
+                      responses:
+                        200:
+                          description: ok
+                `},
+			"#/paths/~1pets/post/requestBody/content/application~1json/schema/properties/name",
+			"swagger.yaml", 15, 15,
+		},
+		{
+			"converted parameter schema",
+			map[string]string{"swagger.yaml": `
+                swagger: "2.0"
+                paths:
+                  /pets:
+                    get:
+                      parameters:
+                        - name: pet
+                          in: body
+                          schema:
+                            type: object
+                        - name: tags
+                          in: query
+                          type: array
+                          items:
+                            type: string
+                      responses:
+                        200:
+                          description: ok
+                `},
+			"#/paths/~1pets/get/parameters/0/schema/items",
+			"swagger.yaml", 14, 11,
+		},
+		{
+			"converted definition",
+			map[string]string{"swagger.yaml": `
+                swagger: "2.0"
+                paths: {}
+                definitions:
+                  Pet:
+                    type: object
+                `},
+			"#/components/schemas/Pet",
+			"swagger.yaml", 5, 3,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeFiles(t, tc.files)
+
+			root := "openapi.yaml"
+			if _, ok := tc.files[root]; !ok {
+				root = "swagger.yaml"
+			}
+
+			_, src, err := LoadFile(filepath.Join(dir, root))
+			if err != nil {
+				t.Fatal("could not load. got error:", err)
+			}
+
+			expected := diag.Pos{File: filepath.Join(dir, tc.file), Line: tc.line, Column: tc.col}
+			if pos := src.Locate(tc.in); pos != expected {
+				t.Error("got:", pos, "expected:", expected)
+			}
+		})
+	}
+}
@@ -5,16 +5,17 @@
 package v2
 
 import (
-	"errors"
+	"fmt"
 	"net/url"
+	"strconv"
 
-	"github.com/go-yaml/yaml"
+	"gopkg.in/yaml.v3"
 )
 
 // Document is a top level OpenAPI 2.0 / Swagger 2.0 API definition, according to
 // https://github.com/OAI/OpenAPI-Specification/blob/master/versions/2.0.md#swagger-object
 type Document struct {
-	Version string // required
+	Version string `yaml:"swagger"` // required
 	Info    *Info  // required
 
 	Host     *string
@@ -33,7 +34,7 @@ type Document struct {
 	Security            []map[string][]string
 
 	Tags          []Tag
-	Documentation *ExternalDocumentation
+	Documentation *ExternalDocumentation `yaml:"externalDocs"`
 }
 
 // Info is the required OpenAPI Info object, according to
@@ -42,7 +43,7 @@ type Info struct {
 	Title       string // required
 	Description *string
 
-	TermsOfService *string
+	TermsOfService *string `yaml:"termsOfService"`
 	Contact        *Contact
 	License        *License
 
@@ -87,9 +88,9 @@ type Operation struct {
 
 	Summary       *string
 	Description   *string
-	Documentation *ExternalDocumentation
+	Documentation *ExternalDocumentation `yaml:"externalDocs"`
 
-	OperationID *string
+	OperationID *string `yaml:"operationId"`
 
 	Consumes []string
 	Produces []string
@@ -116,95 +117,88 @@ type ExternalDocumentation struct {
 type Parameters []Parameter
 
 // UnmarshalYAML unmarshals Parameters from YAML or JSON.
-func (p *Parameters) UnmarshalYAML(um func(interface{}) error) error {
-	var ys []map[string]interface{}
-	if err := um(&ys); err != nil {
-		return err
+func (p *Parameters) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.SequenceNode {
+		return decodeError(n, "expected a list of parameters")
 	}
+	*p = make(Parameters, len(n.Content))
 
-	*p = make(Parameters, len(ys))
-
-	var err error
-	for i, y := range ys {
-		if (*p)[i], err = unmarshalParameter(y); err != nil {
+	var errs typeErrors
+	for i, v := range n.Content {
+		var err error
+		(*p)[i], err = decodeParameter(v)
+		if err = errs.add(err); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return errs.err()
 }
 
 // ParameterMap is a map of identifiers to Parameters
 type ParameterMap map[string]Parameter
 
 // UnmarshalYAML unmarshals a ParameterMap from YAML or JSON.
-func (p *ParameterMap) UnmarshalYAML(um func(interface{}) error) error {
-	var ys map[string]map[string]interface{}
-	if err := um(&ys); err != nil {
-		return err
+func (p *ParameterMap) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return decodeError(n, "expected a map of parameters")
 	}
-	*p = make(map[string]Parameter, len(ys))
+	*p = make(map[string]Parameter, len(n.Content)/2)
 
-	var err error
-	for k, y := range ys {
-		if (*p)[k], err = unmarshalParameter(y); err != nil {
+	var errs typeErrors
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		v, err := decodeParameter(n.Content[i+1])
+		if err = errs.add(err); err != nil {
 			return err
 		}
+		(*p)[n.Content[i].Value] = v
 	}
 
-	return nil
+	return errs.err()
 }
 
-func unmarshalParameter(y map[string]interface{}) (Parameter, error) {
-	b, err := yaml.Marshal(&y)
-	if err != nil {
-		return nil, err
+// decodeParameter decodes the parameter at n, by its location and type.
+func decodeParameter(n *yaml.Node) (Parameter, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, decodeError(n, "parameter must be a map")
 	}
 
-	if _, ok := y["$ref"]; ok {
+	if value(n, "$ref") != nil {
 		var v ReferenceParamter
-		err = yaml.Unmarshal(b, &v)
-		return &v, err
-	} else if y["in"] == "body" {
+		return &v, n.Decode(&v)
+	} else if in := value(n, "in"); in != nil && in.Value == "body" {
 		var v BodyParameter
-		err = yaml.Unmarshal(b, &v)
-		return &v, err
+		return &v, n.Decode(&v)
 	}
 
-	switch y["type"] {
+	var v Parameter
+	switch typ, at := typeOf(n); typ {
 	case "string":
-		var v StringParameter
-		err = yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &StringParameter{}
 	case "number":
-		var v NumberParameter
-		err = yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &NumberParameter{}
 	case "integer":
-		var v IntegerParameter
-		err = yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &IntegerParameter{}
 	case "boolean":
-		var v BooleanParameter
-		err = yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &BooleanParameter{}
 	case "array":
 		// XXX for whatever reason, ParameterFields won't unmarshal in
 		// ArrayParameter directly.
 		var p ParameterFields
-		if err = yaml.Unmarshal(b, &p); err != nil {
+		if err := n.Decode(&p); err != nil {
 			return nil, err
 		}
-		v := ArrayParameter{ParameterFields: p}
-		err = yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &ArrayParameter{ParameterFields: p}
 	case "file":
-		var v FileParameter
-		err = yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &FileParameter{}
 	default:
-		return nil, errors.New("bad parameter type")
+		return nil, badType(at, "parameter", typ)
 	}
+
+	return v, n.Decode(v)
 }
 
 // Parameter is the common interface for types that define operation parameters,
@@ -265,7 +259,7 @@ type BodyParameter struct {
 func (b *BodyParameter) UnmarshalYAML(um func(interface{}) error) error {
 	var y struct {
 		ParameterFields `yaml:",inline"`
-		Schema          yaml.MapSlice
+		Schema          schemaValue
 	}
 
 	if err := um(&y); err != nil {
@@ -273,10 +267,9 @@ func (b *BodyParameter) UnmarshalYAML(um func(interface{}) error) error {
 	}
 
 	b.ParameterFields = y.ParameterFields
+	b.Schema = y.Schema.Schema
 
-	var err error
-	b.Schema, err = unmarshalSchema(y.Schema)
-	return err
+	return nil
 }
 
 // StringParameter is an operation parameter in any non-body location that is a
@@ -284,7 +277,7 @@ func (b *BodyParameter) UnmarshalYAML(um func(interface{}) error) error {
 type StringParameter struct {
 	ParameterFields `yaml:",inline"`
 	StringItem      `yaml:",inline"`
-	AllowEmptyValue bool
+	AllowEmptyValue bool `yaml:"allowEmptyValue"`
 }
 
 // NumberParameter is an operation parameter in any non-body location that is a
@@ -292,7 +285,7 @@ type StringParameter struct {
 type NumberParameter struct {
 	ParameterFields `yaml:",inline"`
 	NumberItem      `yaml:",inline"`
-	AllowEmptyValue bool
+	AllowEmptyValue bool `yaml:"allowEmptyValue"`
 }
 
 // IntegerParameter is an operation parameter in any non-body location that is
@@ -300,7 +293,7 @@ type NumberParameter struct {
 type IntegerParameter struct {
 	ParameterFields `yaml:",inline"`
 	IntegerItem     `yaml:",inline"`
-	AllowEmptyValue bool
+	AllowEmptyValue bool `yaml:"allowEmptyValue"`
 }
 
 // BooleanParameter is an operation parameter in any non-body location that is a
@@ -308,7 +301,7 @@ type IntegerParameter struct {
 type BooleanParameter struct {
 	ParameterFields `yaml:",inline"`
 	BooleanItem     `yaml:",inline"`
-	AllowEmptyValue bool
+	AllowEmptyValue bool `yaml:"allowEmptyValue"`
 }
 
 // ArrayParameter is an operation parameter in any non-body location that is an
@@ -316,13 +309,13 @@ type BooleanParameter struct {
 type ArrayParameter struct {
 	ParameterFields `yaml:",inline"`
 	ArrayItem       `yaml:",inline"`
-	AllowEmptyValue bool
+	AllowEmptyValue bool `yaml:"allowEmptyValue"`
 }
 
 // FileParameter is an operation parameter that represents a file upload.
 type FileParameter struct {
 	ParameterFields `yaml:",inline"`
-	AllowEmptyValue bool
+	AllowEmptyValue bool `yaml:"allowEmptyValue"`
 }
 
 // Items are used for array item definitions, and parameters.
@@ -341,36 +334,32 @@ const (
 	arrayItem   = "array"
 )
 
-func unmarshalItems(y map[string]interface{}) (Items, error) {
-	b, err := yaml.Marshal(&y)
-	if err != nil {
-		return nil, err
+// decodeItems decodes the array items at n, by their type.
+func decodeItems(n *yaml.Node) (Items, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, decodeError(n, "items must be a map")
 	}
 
-	switch y["type"] {
+	var v Items
+	switch typ, at := typeOf(n); typ {
 	case stringItem:
-		var v StringItem
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &StringItem{}
 	case numberItem:
-		var v NumberItem
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &NumberItem{}
 	case integerItem:
-		var v IntegerItem
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &IntegerItem{}
 	case booleanItem:
-		var v BooleanItem
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &BooleanItem{}
 	case arrayItem:
-		var v ArrayItem
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &ArrayItem{}
 	default:
-		return nil, errors.New("bad schema type: " + y["type"].(string))
+		return nil, badType(at, "items", typ)
 	}
+
+	return v, n.Decode(v)
 }
 
 // StringItem represents the definition for a string array item.
@@ -380,8 +369,8 @@ type StringItem struct {
 	Default *string
 	Enum    *[]string
 
-	MaxLength *int64
-	MinLength *int64
+	MaxLength *int64 `yaml:"maxLength"`
+	MinLength *int64 `yaml:"minLength"`
 	Pattern   *string
 }
 
@@ -396,10 +385,10 @@ type NumberItem struct {
 	Enum    *[]float64
 
 	Maximum          *float64
-	ExclusiveMaximum bool
-	Minium           *float64
-	ExclusiveMinimum bool
-	MultipleOf       *float64
+	ExclusiveMaximum bool `yaml:"exclusiveMaximum"`
+	Minimum          *float64
+	ExclusiveMinimum bool     `yaml:"exclusiveMinimum"`
+	MultipleOf       *float64 `yaml:"multipleOf"`
 }
 
 // Type returns the type of this item.
@@ -413,10 +402,10 @@ type IntegerItem struct {
 	Enum    *[]int64
 
 	Maximum          *int64
-	ExclusiveMaximum bool
-	Minium           *int64
-	ExclusiveMinimum bool
-	MultipleOf       *int64
+	ExclusiveMaximum bool `yaml:"exclusiveMaximum"`
+	Minimum          *int64
+	ExclusiveMinimum bool   `yaml:"exclusiveMinimum"`
+	MultipleOf       *int64 `yaml:"multipleOf"`
 }
 
 // Type returns the type of this item.
@@ -438,9 +427,9 @@ type ArrayFields struct {
 	Default interface{}
 	Enum    *[]interface{}
 
-	MaxItems    *uint64
-	MinItems    *uint64
-	UniqueItems bool
+	MaxItems    *uint64 `yaml:"maxItems"`
+	MinItems    *uint64 `yaml:"minItems"`
+	UniqueItems bool    `yaml:"uniqueItems"`
 }
 
 // ArrayItem represents the definition for a nested array array item.
@@ -453,22 +442,32 @@ type ArrayItem struct {
 func (ArrayItem) Type() string { return arrayItem }
 
 // UnmarshalYAML unmarshals an ArrayItem from YAML or JSON.
-func (a *ArrayItem) UnmarshalYAML(um func(interface{}) error) error {
+func (a *ArrayItem) UnmarshalYAML(n *yaml.Node) error {
 	var y struct {
 		ArrayFields `yaml:",inline"`
 
-		Items map[string]interface{}
+		Items yaml.Node
 	}
 
-	if err := um(&y); err != nil {
+	var errs typeErrors
+	if err := errs.add(n.Decode(&y)); err != nil {
 		return err
 	}
 
 	a.ArrayFields = y.ArrayFields
 
+	if y.Items.Kind == 0 {
+		errs.add(decodeError(n, "array has no items"))
+		return errs.err()
+	}
+
 	var err error
-	a.Items, err = unmarshalItems(y.Items)
-	return err
+	a.Items, err = decodeItems(&y.Items)
+	if err = errs.add(err); err != nil {
+		return err
+	}
+
+	return errs.err()
 }
 
 // Responses defines the Response for each status code for an operation,
@@ -494,6 +493,14 @@ func (r *Responses) UnmarshalYAML(um func(interface{}) error) error {
 			}
 			r.Codes[t] = v
 		case string:
+			if code, err := strconv.Atoi(t); err == nil { // JSON keys are always strings
+				if r.Codes == nil {
+					r.Codes = make(map[int]Response)
+				}
+				r.Codes[code] = v
+				continue
+			}
+
 			nv := v
 			r.Default = &nv
 		}
@@ -508,7 +515,7 @@ type Response struct {
 	Reference   string `yaml:"$ref"` // XXX should be distinct type?
 	Description string // required
 	Schema      Schema
-	Headers     map[string]Header
+	Headers     Headers
 	Examples    map[string]interface{} // mime type to anything
 }
 
@@ -517,10 +524,10 @@ func (r *Response) UnmarshalYAML(um func(interface{}) error) error {
 	var y struct {
 		Reference   string `yaml:"$ref"`
 		Description string
-		Headers     map[string]Header
+		Headers     Headers
 		Examples    map[string]interface{}
 
-		Schema yaml.MapSlice
+		Schema schemaValue // schema is optional for responses. they may have no body
 	}
 
 	if err := um(&y); err != nil {
@@ -531,20 +538,71 @@ func (r *Response) UnmarshalYAML(um func(interface{}) error) error {
 	r.Description = y.Description
 	r.Headers = y.Headers
 	r.Examples = y.Examples
+	r.Schema = y.Schema.Schema
 
-	if y.Schema == nil { // schema is optional for responses. they may have no body
-		return nil
+	return nil
+}
+
+// Headers is a map of header names to Header definitions.
+type Headers map[string]Header
+
+// UnmarshalYAML unmarshals Headers from YAML or JSON.
+func (h *Headers) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return decodeError(n, "expected a map of headers")
 	}
+	*h = make(Headers, len(n.Content)/2)
 
-	var err error
-	r.Schema, err = unmarshalSchema(y.Schema)
-	return err
+	var errs typeErrors
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		v, err := decodeHeader(n.Content[i+1])
+		if err = errs.add(err); err != nil {
+			return err
+		}
+		(*h)[n.Content[i].Value] = v
+	}
+
+	return errs.err()
+}
+
+// decodeHeader decodes the response header at n, by its type.
+func decodeHeader(n *yaml.Node) (Header, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, decodeError(n, "header must be a map")
+	}
+
+	var v Header
+	switch typ, at := typeOf(n); typ {
+	case stringItem:
+		v = &StringHeader{}
+	case numberItem:
+		v = &NumberHeader{}
+	case integerItem:
+		v = &IntegerHeader{}
+	case booleanItem:
+		v = &BooleanHeader{}
+	case arrayItem:
+		// As with ArrayParameter, the embedded ArrayItem's UnmarshalYAML hides
+		// the header fields.
+		var h HeaderFields
+		if err := n.Decode(&h); err != nil {
+			return nil, err
+		}
+		v = &ArrayHeader{HeaderFields: h}
+	default:
+		return nil, badType(at, "header", typ)
+	}
+
+	return v, n.Decode(v)
 }
 
 // Header is the common interface for headers set on responses, according to
 // https://github.com/OAI/OpenAPI-Specification/blob/master/versions/2.0.md#headerObject
 type Header interface {
-	Description() *string
+	GetDescription() *string
 	Items
 }
 
@@ -591,7 +649,7 @@ type ArrayHeader struct {
 type Tag struct {
 	Name          string // required
 	Description   *string
-	Documentation *ExternalDocumentation
+	Documentation *ExternalDocumentation `yaml:"externalDocs"`
 }
 
 // Schema defines the common interface for schema definitions, according to
@@ -603,7 +661,7 @@ type Schema interface {
 	GetExample() interface{}
 }
 
-//SchemaMap is an ordered list of named schema definitions or object properties,
+// SchemaMap is an ordered list of named schema definitions or object properties,
 // so that order is maintained.
 type SchemaMap []struct {
 	Name   string
@@ -611,81 +669,159 @@ type SchemaMap []struct {
 }
 
 // UnmarshalYAML unmarshals a SchemaMap from YAML or JSON.
-func (s *SchemaMap) UnmarshalYAML(um func(interface{}) error) error {
-	var ys yaml.MapSlice
-	if err := um(&ys); err != nil {
-		return err
+func (s *SchemaMap) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return decodeError(n, "expected a map of schemas")
 	}
-	*s = make(SchemaMap, len(ys))
+	*s = make(SchemaMap, len(n.Content)/2)
 
-	for i, y := range ys {
-		v, err := unmarshalSchema(y.Value.(yaml.MapSlice))
-		if err != nil {
+	var errs typeErrors
+	for i := range *s {
+		v, err := decodeSchema(n.Content[2*i+1])
+		if err = errs.add(err); err != nil {
 			return err
 		}
 		(*s)[i] = struct {
 			Name   string
 			Schema Schema
-		}{y.Key.(string), v}
+		}{n.Content[2*i].Value, v}
+	}
+
+	return errs.err()
+}
+
+// schemaValue decodes a single Schema of any kind, for use in place of a
+// Schema field.
+type schemaValue struct{ Schema }
+
+// UnmarshalYAML unmarshals a schemaValue from YAML or JSON.
+func (s *schemaValue) UnmarshalYAML(n *yaml.Node) error {
+	var err error
+	s.Schema, err = decodeSchema(n)
+	return err
+}
+
+// decodeSchema decodes the schema at n. Its keys are scanned once to pick the
+// concrete Schema type, and n is then decoded directly into it.
+func decodeSchema(n *yaml.Node) (Schema, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, decodeError(n, "schema must be a map")
+	}
+
+	var ref, allOf bool
+	var typ string
+	at := n
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		switch v := n.Content[i+1]; n.Content[i].Value {
+		case "$ref":
+			ref = true
+		case "allOf":
+			allOf = true
+		case "type":
+			if v.ShortTag() != "!!null" {
+				typ, at = v.Value, v
+			}
+		}
+	}
+
+	var v Schema
+	switch {
+	case ref:
+		v = &ReferenceSchema{}
+	case allOf:
+		v = &AllOfSchema{}
+	default:
+		switch typ {
+		case "object", "":
+			v = &ObjectSchema{}
+		case "null":
+			v = &NullSchema{}
+		case "string":
+			v = &StringSchema{}
+		case "number":
+			v = &NumberSchema{}
+		case "integer":
+			v = &IntegerSchema{}
+		case "boolean":
+			v = &BooleanSchema{}
+		case "array":
+			v = &ArraySchema{}
+		case "file":
+			v = &FileSchema{}
+		default:
+			return nil, decodeError(at, "bad schema type: %s", typ)
+		}
+	}
+
+	return v, n.Decode(v)
+}
+
+// value returns the value of key in the map node n, or nil if it has none.
+func value(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
 	}
 
 	return nil
 }
 
-func unmarshalSchema(yms interface{}) (Schema, error) {
-	b, err := yaml.Marshal(yms)
-	if err != nil {
-		return nil, err
+// typeOf returns the type of the map node n, and the node to report a bad
+// type at.
+func typeOf(n *yaml.Node) (string, *yaml.Node) {
+	if v := value(n, "type"); v != nil {
+		return v.Value, v
 	}
 
-	// XXX wasteful just for indexing
-	var y map[string]interface{}
-	if err = yaml.Unmarshal(b, &y); err != nil {
-		return nil, err
+	return "", n
+}
+
+// badType returns the error for the value at n, of the given kind, with an
+// unknown or missing type.
+func badType(n *yaml.Node, kind, typ string) error {
+	if typ == "" {
+		return decodeError(n, "%s has no type", kind)
 	}
 
-	if _, ok := y["$ref"]; ok {
-		var v ReferenceSchema
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
-	} else if _, ok := y["allOf"]; ok {
-		var v AllOfSchema
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
+	return decodeError(n, "bad %s type: %s", kind, typ)
+}
+
+// decodeError returns an error for the value at n, positioned by its line and
+// column. As a *yaml.TypeError, decoding continues past it, so that any other
+// errors in the document are reported too.
+func decodeError(n *yaml.Node, format string, args ...interface{}) error {
+	return &yaml.TypeError{Errors: []string{
+		fmt.Sprintf("line %d, column %d: %s", n.Line, n.Column, fmt.Sprintf(format, args...)),
+	}}
+}
+
+// typeErrors collects the errors decoding each value of a collection, instead
+// of stopping at the first.
+type typeErrors []string
+
+// add adds the errors of err, if it is a *yaml.TypeError. Any other error
+// stops decoding, and is returned.
+func (e *typeErrors) add(err error) error {
+	if te, ok := err.(*yaml.TypeError); ok {
+		*e = append(*e, te.Errors...)
+		return nil
 	}
 
-	switch y["type"] {
-	case "object", nil:
-		var v ObjectSchema
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
-	case "null":
-		var v NullSchema
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
-	case "string":
-		var v StringSchema
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
-	case "number":
-		var v NumberSchema
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
-	case "integer":
-		var v IntegerSchema
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
-	case "boolean":
-		var v BooleanSchema
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
-	case "array":
-		var v ArraySchema
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
-	default:
-		return nil, errors.New("bad schema type: " + y["type"].(string))
+	return err
+}
+
+// err returns the collected errors as a *yaml.TypeError, or nil if there are
+// none.
+func (e typeErrors) err() error {
+	if len(e) == 0 {
+		return nil
 	}
+
+	return &yaml.TypeError{Errors: e}
 }
 
 // ReferenceSchema is an inline reference to another schema definition.
@@ -709,10 +845,10 @@ func (ReferenceSchema) GetExample() interface{} { return nil }
 type SchemaFields struct {
 	Title         *string
 	Description   *string
-	Documentation *ExternalDocumentation
+	Documentation *ExternalDocumentation `yaml:"externalDocs"`
 	Example       interface{}
 
-	ReadOnly bool        // valid only for items under properties
+	ReadOnly bool        `yaml:"readOnly"` // valid only for items under properties
 	XML      interface{} // valid only for items under properties
 }
 
@@ -739,18 +875,15 @@ type AllOfSchema struct {
 func (a *AllOfSchema) UnmarshalYAML(um func(interface{}) error) error {
 	var ay struct {
 		SchemaFields `yaml:",inline"`
-		AllOf        []yaml.MapSlice `yaml:"allOf"`
+		AllOf        []schemaValue `yaml:"allOf"`
 	}
 	if err := um(&ay); err != nil {
 		return err
 	}
 	a.AllOf = make([]Schema, len(ay.AllOf))
 
-	var err error
 	for i, y := range ay.AllOf {
-		if a.AllOf[i], err = unmarshalSchema(y); err != nil {
-			return err
-		}
+		a.AllOf[i] = y.Schema
 	}
 
 	return nil
@@ -759,7 +892,7 @@ func (a *AllOfSchema) UnmarshalYAML(um func(interface{}) error) error {
 // ObjectSchema is a schema definition for an object.
 type ObjectSchema struct {
 	SchemaFields  `yaml:",inline"`
-	Descriminator *string
+	Descriminator *string `yaml:"discriminator"`
 
 	Properties *SchemaMap
 	Required   *[]string
@@ -767,22 +900,22 @@ type ObjectSchema struct {
 	AdditionalProperties    Schema `yaml:"additionalProperties"` // null if defined as false
 	AnyAdditionalProperties bool   // if additionalProperties is true, this is set
 
-	MinProperties *uint64
-	MaxProperties *uint64
+	MinProperties *uint64 `yaml:"minProperties"`
+	MaxProperties *uint64 `yaml:"maxProperties"`
 }
 
 // UnmarshalYAML unmarshals an ObjectSchema from YAML or JSON.
 func (o *ObjectSchema) UnmarshalYAML(um func(interface{}) error) error {
 	var oy struct {
 		SchemaFields  `yaml:",inline"`
-		Descriminator *string
+		Descriminator *string `yaml:"discriminator"`
 
 		Properties           *SchemaMap
 		Required             *[]string
-		AdditionalProperties interface{} `yaml:"additionalProperties"` // null if defined as false
+		AdditionalProperties yaml.Node `yaml:"additionalProperties"` // null if defined as false
 
-		MinProperties *uint64
-		MaxProperties *uint64
+		MinProperties *uint64 `yaml:"minProperties"`
+		MaxProperties *uint64 `yaml:"maxProperties"`
 	}
 	if err := um(&oy); err != nil {
 		return err
@@ -795,15 +928,14 @@ func (o *ObjectSchema) UnmarshalYAML(um func(interface{}) error) error {
 	o.MinProperties = oy.MinProperties
 	o.MaxProperties = oy.MaxProperties
 
-	switch t := oy.AdditionalProperties.(type) {
-	case bool:
-		o.AnyAdditionalProperties = t
-		return nil
-	case nil:
+	switch ap := &oy.AdditionalProperties; ap.ShortTag() {
+	case "!!bool":
+		return ap.Decode(&o.AnyAdditionalProperties)
+	case "!!null":
 		return nil
 	default: // try and use as a schema
 		var err error
-		o.AdditionalProperties, err = unmarshalSchema(t)
+		o.AdditionalProperties, err = decodeSchema(ap)
 		return err
 	}
 }
@@ -837,6 +969,12 @@ type BooleanSchema struct {
 	BooleanItem  `yaml:",inline"`
 }
 
+// FileSchema is a schema definition for a file. It is valid only for
+// responses.
+type FileSchema struct {
+	SchemaFields `yaml:",inline"`
+}
+
 // ArraySchema is a schema definition for an array type.
 type ArraySchema struct {
 	SchemaFields `yaml:",inline"`
@@ -849,7 +987,7 @@ func (a *ArraySchema) UnmarshalYAML(um func(interface{}) error) error {
 	var y struct {
 		SchemaFields `yaml:",inline"`
 		ArrayFields  `yaml:",inline"`
-		Items        yaml.MapSlice
+		Items        schemaValue
 	}
 
 	if err := um(&y); err != nil {
@@ -858,32 +996,31 @@ func (a *ArraySchema) UnmarshalYAML(um func(interface{}) error) error {
 
 	a.SchemaFields = y.SchemaFields
 	a.ArrayFields = y.ArrayFields
+	a.Items = y.Items.Schema
 
-	var err error
-	a.Items, err = unmarshalSchema(y.Items)
-	return err
+	return nil
 }
 
 // SecuritySchemeMap is a map if identifiers to SecuritySchemes.
 type SecuritySchemeMap map[string]SecurityScheme
 
 // UnmarshalYAML unmarshals a SecuritySchemaMap from YAML or JSON.
-func (s *SecuritySchemeMap) UnmarshalYAML(um func(interface{}) error) error {
-	var ys map[string]map[string]interface{}
-	if err := um(&ys); err != nil {
-		return err
+func (s *SecuritySchemeMap) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return decodeError(n, "expected a map of security schemes")
 	}
+	*s = make(map[string]SecurityScheme, len(n.Content)/2)
 
-	*s = make(map[string]SecurityScheme, len(ys))
-
-	var err error
-	for k, y := range ys {
-		if (*s)[k], err = unmarshalSecurityScheme(y); err != nil {
+	var errs typeErrors
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		v, err := decodeSecurityScheme(n.Content[i+1])
+		if err = errs.add(err); err != nil {
 			return err
 		}
+		(*s)[n.Content[i].Value] = v
 	}
 
-	return nil
+	return errs.err()
 }
 
 // SecurityScheme is the common interface for security schemes, according to
@@ -893,28 +1030,28 @@ type SecurityScheme interface {
 	GetDescription() *string
 }
 
-func unmarshalSecurityScheme(y map[string]interface{}) (SecurityScheme, error) {
-	b, err := yaml.Marshal(&y)
-	if err != nil {
-		return nil, err
+// decodeSecurityScheme decodes the security scheme at n, by its type.
+func decodeSecurityScheme(n *yaml.Node) (SecurityScheme, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, decodeError(n, "security scheme must be a map")
 	}
 
-	switch y["type"] {
+	var v SecurityScheme
+	switch typ, at := typeOf(n); typ {
 	case "basic":
-		var v BasicSecurityScheme
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &BasicSecurityScheme{}
 	case "apiKey":
-		var v APIKeySecurityScheme
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &APIKeySecurityScheme{}
 	case "oauth2":
-		var v OAuth2SecurityScheme
-		err := yaml.Unmarshal(b, &v)
-		return &v, err
+		v = &OAuth2SecurityScheme{}
 	default:
-		return nil, errors.New("bad security scheme: " + y["type"].(string))
+		return nil, badType(at, "security scheme", typ)
 	}
+
+	return v, n.Decode(v)
 }
 
 // SecuritySchemeFields holds the common fields for SecuritySchemes.
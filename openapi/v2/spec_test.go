@@ -5,8 +5,8 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/go-yaml/yaml"
 	"github.com/lithammer/dedent"
+	"gopkg.in/yaml.v3"
 )
 
 func TestParametersUnmarshalYAML(t *testing.T) {
@@ -29,6 +29,39 @@ func TestParametersUnmarshalYAML(t *testing.T) {
 	}
 }
 
+func TestParametersUnmarshalYAMLErrors(t *testing.T) {
+	d := dedent.Dedent(`
+	- name: limit
+	  in: query
+	  type: int
+	- name: tags
+	  in: query
+	  type: array
+	  items:
+	    type: strin
+	- name: pet
+	  in: body
+	  schema:
+	    type: strang
+	- name: untyped
+	  in: query
+	`)
+
+	var out Parameters
+	err := yaml.Unmarshal([]byte(d), &out)
+
+	expected := []string{
+		"line 4, column 9: bad parameter type: int",
+		"line 9, column 11: bad items type: strin",
+		"line 13, column 11: bad schema type: strang",
+		"line 14, column 3: parameter has no type",
+	}
+	te, ok := err.(*yaml.TypeError)
+	if !ok || !reflect.DeepEqual(te.Errors, expected) {
+		t.Error("got:", err, "expected:", expected)
+	}
+}
+
 func TestParameterMapUnmarshalYAML(t *testing.T) {
 	d := dedent.Dedent(`
 	qparam:
@@ -79,6 +112,16 @@ func TestUnmarshalParameter(t *testing.T) {
             `,
 			&StringParameter{ParameterFields: ParameterFields{In: "query", Name: "param"}},
 		},
+		{
+			"allowEmptyValue",
+			`
+            - name: param
+              in: query
+              type: string
+              allowEmptyValue: true
+            `,
+			&StringParameter{ParameterFields: ParameterFields{In: "query", Name: "param"}, AllowEmptyValue: true},
+		},
 		{
 			"number",
 			`
@@ -245,6 +288,19 @@ func TestResponsesUnmarshalYAML(t *testing.T) {
         type: string
     204:
       description: success with no content
+    "201":
+      description: created
+      headers:
+        Location:
+          type: string
+          description: the new resource
+        X-Rate-Limit:
+          type: integer
+        X-Tags:
+          type: array
+          description: tags
+          items:
+            type: string
     default:
       schema:
         $ref: '#/definitions/Error'
@@ -255,6 +311,8 @@ func TestResponsesUnmarshalYAML(t *testing.T) {
 		t.Fatal("could not unmarshal. got error:", err)
 	}
 
+	location := "the new resource"
+	tags := "tags"
 	expected := Responses{
 		Default: &Response{Schema: &ReferenceSchema{Reference: "#/definitions/Error"}},
 		Codes: map[int]Response{
@@ -265,6 +323,17 @@ func TestResponsesUnmarshalYAML(t *testing.T) {
 			204: {
 				Description: "success with no content",
 			},
+			201: {
+				Description: "created",
+				Headers: Headers{
+					"Location":     &StringHeader{HeaderFields: HeaderFields{Description: &location}},
+					"X-Rate-Limit": &IntegerHeader{},
+					"X-Tags": &ArrayHeader{
+						HeaderFields: HeaderFields{Description: &tags},
+						ArrayItem:    ArrayItem{Items: &StringItem{}},
+					},
+				},
+			},
 		},
 	}
 	if !reflect.DeepEqual(out, expected) {
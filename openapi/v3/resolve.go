@@ -0,0 +1,281 @@
+package v3
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jbowes/oag/openapi/jsonpointer"
+)
+
+// ResolveSchema returns the schema referenced by the local reference ref. The
+// reference may point to a schema defined in the document's components, or
+// to any schema nested within one, such as a property or array items.
+//
+// References encountered along the way are not followed, as JSON pointers
+// apply to the document as written.
+func (d *Document) ResolveSchema(ref string) (Schema, error) {
+	tokens, err := parseRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	unresolved := fmt.Errorf("unresolved reference %s", ref)
+	if len(tokens) < 3 || tokens[0] != "components" || d.Components == nil {
+		return nil, unresolved
+	}
+
+	var s Schema
+	var rest []string
+	name := tokens[2]
+	switch c := d.Components; tokens[1] {
+	case "schemas":
+		s, rest = lookupSchema(c.Schemas, name), tokens[3:]
+	case "parameters":
+		if p, ok := c.Parameters[name]; ok && len(tokens) > 3 && tokens[3] == "schema" {
+			s, rest = p.Schema, tokens[4:]
+		}
+	case "headers":
+		if h, ok := c.Headers[name]; ok && len(tokens) > 3 && tokens[3] == "schema" {
+			s, rest = h.Schema, tokens[4:]
+		}
+	case "responses":
+		if r, ok := c.Responses[name]; ok {
+			s, rest = contentSchema(r.Content, tokens[3:])
+		}
+	case "requestBodies":
+		if rb, ok := c.RequestBodies[name]; ok {
+			s, rest = contentSchema(rb.Content, tokens[3:])
+		}
+	}
+
+	if s == nil {
+		return nil, unresolved
+	}
+
+	if s = walkSchema(s, rest); s == nil {
+		return nil, unresolved
+	}
+
+	return s, nil
+}
+
+// ResolveParameter returns the parameter referenced by the local reference ref.
+func (d *Document) ResolveParameter(ref string) (*Parameter, error) {
+	name, err := componentName(ref, "parameters")
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Components != nil {
+		if p, ok := d.Components.Parameters[name]; ok {
+			return &p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unresolved reference %s", ref)
+}
+
+// ResolveRequestBody returns the request body referenced by the local
+// reference ref.
+func (d *Document) ResolveRequestBody(ref string) (*RequestBody, error) {
+	name, err := componentName(ref, "requestBodies")
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Components != nil {
+		if rb, ok := d.Components.RequestBodies[name]; ok {
+			return &rb, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unresolved reference %s", ref)
+}
+
+// ResolveResponse returns the response referenced by the local reference ref.
+func (d *Document) ResolveResponse(ref string) (*Response, error) {
+	name, err := componentName(ref, "responses")
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Components != nil {
+		if r, ok := d.Components.Responses[name]; ok {
+			return &r, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unresolved reference %s", ref)
+}
+
+// ResolveHeader returns the header referenced by the local reference ref.
+func (d *Document) ResolveHeader(ref string) (*Header, error) {
+	name, err := componentName(ref, "headers")
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Components != nil {
+		if h, ok := d.Components.Headers[name]; ok {
+			return &h, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unresolved reference %s", ref)
+}
+
+// ResolvePathItem returns the path item referenced by the local reference ref.
+// The reference may point to a path item defined in the document's
+// components, or to another path.
+func (d *Document) ResolvePathItem(ref string) (*PathItem, error) {
+	tokens, err := parseRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(tokens) == 3 && tokens[0] == "components" && tokens[1] == "pathItems":
+		if d.Components != nil {
+			if pi, ok := d.Components.PathItems[tokens[2]]; ok {
+				return &pi, nil
+			}
+		}
+	case len(tokens) == 2 && tokens[0] == "paths":
+		if pi, ok := d.Paths[tokens[1]]; ok {
+			return &pi, nil
+		}
+	default:
+		return nil, fmt.Errorf("reference %s does not refer to a path item", ref)
+	}
+
+	return nil, fmt.Errorf("unresolved reference %s", ref)
+}
+
+func parseRef(ref string) ([]string, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("reference %s is not local to the document", ref)
+	}
+
+	return jsonpointer.Parse(ref)
+}
+
+// componentName returns the name of the component of the given kind referenced
+// by ref.
+func componentName(ref, kind string) (string, error) {
+	tokens, err := parseRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	if len(tokens) != 3 || tokens[0] != "components" || tokens[1] != kind {
+		return "", fmt.Errorf("reference %s does not refer to components/%s", ref, kind)
+	}
+
+	return tokens[2], nil
+}
+
+func lookupSchema(sm *SchemaMap, name string) Schema {
+	if sm == nil {
+		return nil
+	}
+
+	for _, s := range *sm {
+		if s.Name == name {
+			return s.Schema
+		}
+	}
+
+	return nil
+}
+
+// contentSchema returns the schema found under content/{media type}/schema,
+// and the remaining tokens.
+func contentSchema(content map[string]MediaType, tokens []string) (Schema, []string) {
+	if len(tokens) < 3 || tokens[0] != "content" || tokens[2] != "schema" {
+		return nil, nil
+	}
+
+	mt, ok := content[tokens[1]]
+	if !ok {
+		return nil, nil
+	}
+
+	return mt.Schema, tokens[3:]
+}
+
+// walkSchema returns the schema nested within s at the path given by tokens,
+// or nil if there is none.
+func walkSchema(s Schema, tokens []string) Schema {
+	index := func(schemas []Schema, tok string) Schema {
+		i, err := strconv.Atoi(tok)
+		if err != nil || i < 0 || i >= len(schemas) {
+			return nil
+		}
+		return schemas[i]
+	}
+
+	for len(tokens) > 0 && s != nil {
+		tok := tokens[0]
+		tokens = tokens[1:]
+
+		var arg string
+		switch tok {
+		case "properties", "$defs", "allOf", "oneOf", "anyOf", "prefixItems":
+			if len(tokens) == 0 {
+				return nil
+			}
+			arg, tokens = tokens[0], tokens[1:]
+		}
+
+		if tok == "$defs" {
+			s = lookupSchema(s.GetDefs(), arg)
+			continue
+		}
+
+		switch t := s.(type) {
+		case *ObjectSchema:
+			switch tok {
+			case "properties":
+				s = lookupSchema(t.Properties, arg)
+			case "additionalProperties":
+				s = t.AdditionalProperties
+			default:
+				return nil
+			}
+		case *ArraySchema:
+			switch tok {
+			case "items":
+				s = t.Items
+			case "prefixItems":
+				s = index(t.PrefixItems, arg)
+			default:
+				return nil
+			}
+		case *AllOfSchema:
+			if tok != "allOf" {
+				return nil
+			}
+			s = index(t.AllOf, arg)
+		case *OneOfSchema:
+			if tok != "oneOf" {
+				return nil
+			}
+			s = index(t.OneOf, arg)
+		case *AnyOfSchema:
+			if tok != "anyOf" {
+				return nil
+			}
+			s = index(t.AnyOf, arg)
+		case *NotSchema:
+			if tok != "not" {
+				return nil
+			}
+			s = t.Not
+		default:
+			return nil
+		}
+	}
+
+	return s
+}
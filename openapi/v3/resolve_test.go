@@ -0,0 +1,89 @@
+package v3
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lithammer/dedent"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDocumentResolveSchema(t *testing.T) {
+	var doc Document
+	err := yaml.Unmarshal([]byte(dedent.Dedent(`
+        openapi: 3.1.0
+        components:
+          schemas:
+            Order:
+              type: object
+              properties:
+                items:
+                  type: array
+                  items:
+                    type: string
+                a/b~c:
+                  type: integer
+              $defs:
+                note:
+                  type: boolean
+            Pet:
+              allOf:
+                - $ref: '#/components/schemas/Order'
+                - type: number
+          parameters:
+            limit:
+              name: limit
+              in: query
+              schema:
+                type: integer
+          responses:
+            Err:
+              description: error
+              content:
+                application/json:
+                  schema:
+                    type: object
+                    properties:
+                      code:
+                        type: string
+        `)), &doc)
+	if err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	tcs := []struct {
+		name string
+		in   string
+		out  Schema
+		err  bool
+	}{
+		{"nested property", "#/components/schemas/Order/properties/items/items", &StringSchema{}, false},
+		{"escaped property", "#/components/schemas/Order/properties/a~1b~0c", &IntegerSchema{}, false},
+		{"defs", "#/components/schemas/Order/$defs/note", &BooleanSchema{}, false},
+		{"all of", "#/components/schemas/Pet/allOf/1", &NumberSchema{}, false},
+		{"parameter", "#/components/parameters/limit/schema", &IntegerSchema{}, false},
+		{"response", "#/components/responses/Err/content/application~1json/schema/properties/code", &StringSchema{}, false},
+		{"missing property", "#/components/schemas/Order/properties/nope", nil, true},
+		{"out of range", "#/components/schemas/Pet/allOf/2", nil, true},
+		{"external", "other.yaml#/Pet", nil, true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := doc.ResolveSchema(tc.in)
+			if tc.err {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("could not resolve. got error:", err)
+			}
+
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
@@ -0,0 +1,1351 @@
+// Package v3 defines the OpenAPI 3.0 specification data structures.
+//
+// The specification can be found at
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md
+//
+// OpenAPI 3.1 documents are also supported. Their schemas follow JSON Schema
+// 2020-12, found at https://json-schema.org/draft/2020-12/json-schema-core.html,
+// and are normalized into the same structures where possible.
+package v3
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a top level OpenAPI 3.0 API definition, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#openapi-object
+type Document struct {
+	OpenAPI string `yaml:"openapi"` // required
+	Info    *Info  // required
+
+	Servers []Server
+
+	Paths map[string]PathItem // required
+
+	Components *Components
+
+	Security []map[string][]string
+
+	Tags          []Tag
+	Documentation *ExternalDocumentation `yaml:"externalDocs"`
+
+	JSONSchemaDialect *string             `yaml:"jsonSchemaDialect"` // 3.1 only
+	Webhooks          map[string]PathItem // 3.1 only
+}
+
+// Info is the required OpenAPI Info object, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#info-object
+type Info struct {
+	Title       string  // required
+	Summary     *string // 3.1 only
+	Description *string
+
+	TermsOfService *string `yaml:"termsOfService"`
+	Contact        *Contact
+	License        *License
+
+	Version string // required
+}
+
+// Contact holds the API contact information, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#contact-object
+type Contact struct {
+	Name  *string
+	URL   *url.URL
+	Email *string
+}
+
+// UnmarshalYAML unmarshals a Contact from YAML or JSON.
+func (c *Contact) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		Name  *string
+		URL   *string
+		Email *string
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	c.Name = y.Name
+	c.Email = y.Email
+
+	var err error
+	c.URL, err = parseURL(y.URL)
+	return err
+}
+
+// License holds the API license information, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#license-object
+type License struct {
+	Name       string  // required
+	Identifier *string // 3.1 only. An SPDX license expression
+	URL        *url.URL
+}
+
+// UnmarshalYAML unmarshals a License from YAML or JSON.
+func (l *License) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		Name       string
+		Identifier *string
+		URL        *string
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	l.Name = y.Name
+	l.Identifier = y.Identifier
+
+	var err error
+	l.URL, err = parseURL(y.URL)
+	return err
+}
+
+// Server describes a server hosting the API, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#server-object
+type Server struct {
+	URL         string // required. May contain {variables}
+	Description *string
+	Variables   map[string]ServerVariable
+}
+
+// ServerVariable is a substitution variable for a Server URL template, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#server-variable-object
+type ServerVariable struct {
+	Enum        []string
+	Default     string // required
+	Description *string
+}
+
+// Components holds reusable objects that may be referenced from elsewhere in
+// the document, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#components-object
+type Components struct {
+	Schemas         *SchemaMap
+	Responses       map[string]Response
+	Parameters      map[string]Parameter
+	Examples        map[string]Example
+	RequestBodies   map[string]RequestBody `yaml:"requestBodies"`
+	Headers         map[string]Header
+	SecuritySchemes SecuritySchemeMap `yaml:"securitySchemes"`
+	Links           map[string]Link
+	Callbacks       map[string]Callback
+	PathItems       map[string]PathItem `yaml:"pathItems"` // 3.1 only
+}
+
+// PathItem describes all operations/methods at a single path, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#path-item-object
+type PathItem struct {
+	Reference   *string `yaml:"$ref"`
+	Summary     *string
+	Description *string
+
+	Get     *Operation
+	Put     *Operation
+	Post    *Operation
+	Delete  *Operation
+	Options *Operation
+	Head    *Operation
+	Patch   *Operation
+	Trace   *Operation
+
+	Servers    []Server
+	Parameters []Parameter
+}
+
+// Operation describes a single method on a given path, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#operation-object
+type Operation struct {
+	Tags []string
+
+	Summary       *string
+	Description   *string
+	Documentation *ExternalDocumentation `yaml:"externalDocs"`
+
+	OperationID *string `yaml:"operationId"`
+
+	Parameters  []Parameter
+	RequestBody *RequestBody `yaml:"requestBody"`
+	Responses   *Responses   // required
+	Callbacks   map[string]Callback
+
+	Deprecated bool
+
+	Security *[]map[string][]string
+	Servers  []Server
+}
+
+// ExternalDocumentation describes a reference to additional documentation hosted
+// elsewhere, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#external-documentation-object
+type ExternalDocumentation struct {
+	Description *string
+	URL         *url.URL // required
+}
+
+// UnmarshalYAML unmarshals ExternalDocumentation from YAML or JSON.
+func (e *ExternalDocumentation) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		Description *string
+		URL         *string
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	e.Description = y.Description
+
+	var err error
+	e.URL, err = parseURL(y.URL)
+	return err
+}
+
+// Parameter describes a single operation parameter, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#parameter-object
+//
+// A Parameter with a non-empty Reference is a $ref to a parameter defined in
+// the components section of the document, and has no other fields set.
+type Parameter struct {
+	Reference string `yaml:"$ref"`
+
+	Name        string // required
+	In          string // required
+	Description *string
+	Required    bool
+	Deprecated  bool
+
+	AllowEmptyValue bool `yaml:"allowEmptyValue"`
+
+	Style         *string
+	Explode       *bool
+	AllowReserved bool `yaml:"allowReserved"`
+
+	Schema   Schema
+	Example  interface{}
+	Examples map[string]Example
+
+	Content map[string]MediaType
+}
+
+// UnmarshalYAML unmarshals a Parameter from YAML or JSON.
+func (p *Parameter) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		Reference string `yaml:"$ref"`
+
+		Name        string
+		In          string
+		Description *string
+		Required    bool
+		Deprecated  bool
+
+		AllowEmptyValue bool `yaml:"allowEmptyValue"`
+
+		Style         *string
+		Explode       *bool
+		AllowReserved bool `yaml:"allowReserved"`
+
+		Schema   schemaValue
+		Example  interface{}
+		Examples map[string]Example
+
+		Content map[string]MediaType
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	p.Reference = y.Reference
+	p.Name = y.Name
+	p.In = y.In
+	p.Description = y.Description
+	p.Required = y.Required
+	p.Deprecated = y.Deprecated
+	p.AllowEmptyValue = y.AllowEmptyValue
+	p.Style = y.Style
+	p.Explode = y.Explode
+	p.AllowReserved = y.AllowReserved
+	p.Example = y.Example
+	p.Examples = y.Examples
+	p.Content = y.Content
+	p.Schema = y.Schema.Schema
+
+	return nil
+}
+
+// RequestBody describes a single request body, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#request-body-object
+type RequestBody struct {
+	Reference   string `yaml:"$ref"`
+	Description *string
+	Content     map[string]MediaType // required
+	Required    bool
+}
+
+// MediaType provides the schema and examples for a single media type, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#media-type-object
+type MediaType struct {
+	Schema   Schema
+	Example  interface{}
+	Examples map[string]Example
+	Encoding map[string]Encoding
+}
+
+// UnmarshalYAML unmarshals a MediaType from YAML or JSON.
+func (m *MediaType) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		Schema   schemaValue
+		Example  interface{}
+		Examples map[string]Example
+		Encoding map[string]Encoding
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	m.Example = y.Example
+	m.Examples = y.Examples
+	m.Encoding = y.Encoding
+	m.Schema = y.Schema.Schema
+
+	return nil
+}
+
+// Encoding describes how a single property of a form request body is
+// serialized, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#encoding-object
+type Encoding struct {
+	ContentType   *string `yaml:"contentType"`
+	Headers       map[string]Header
+	Style         *string
+	Explode       *bool
+	AllowReserved bool `yaml:"allowReserved"`
+}
+
+// Responses defines the Response for each status code for an operation,
+// according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#responses-object
+type Responses struct {
+	Default *Response
+	Codes   map[int]Response
+	Ranges  map[int]Response // keyed by the leading digit, ie 4 for 4XX
+}
+
+// UnmarshalYAML unmarshals Responses from YAML or JSON.
+func (r *Responses) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return decodeError(n, "expected a map of responses")
+	}
+
+	var errs typeErrors
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k := n.Content[i]
+
+		var v Response
+		if err := errs.add(n.Content[i+1].Decode(&v)); err != nil {
+			return err
+		}
+
+		switch key := k.Value; {
+		case k.Kind != yaml.ScalarNode:
+			errs.add(decodeError(k, "bad response code"))
+		case key == "default":
+			r.Default = &v
+		case len(key) == 3 && strings.ToUpper(key[1:]) == "XX":
+			code, err := strconv.Atoi(key[:1])
+			if err != nil {
+				errs.add(decodeError(k, "bad response code: %s", key))
+				continue
+			}
+			if r.Ranges == nil {
+				r.Ranges = make(map[int]Response)
+			}
+			r.Ranges[code] = v
+		default:
+			code, err := strconv.Atoi(key)
+			if err != nil {
+				errs.add(decodeError(k, "bad response code: %s", key))
+				continue
+			}
+			if r.Codes == nil {
+				r.Codes = make(map[int]Response)
+			}
+			r.Codes[code] = v
+		}
+	}
+
+	return errs.err()
+}
+
+// Response is a single response from an operation, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#response-object
+type Response struct {
+	Reference   string `yaml:"$ref"`
+	Description string // required
+	Headers     map[string]Header
+	Content     map[string]MediaType
+	Links       map[string]Link
+}
+
+// Header describes a single header, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#header-object
+//
+// It is a Parameter without the name or location.
+type Header struct {
+	Reference   string `yaml:"$ref"`
+	Description *string
+	Required    bool
+	Deprecated  bool
+
+	Style   *string
+	Explode *bool
+
+	Schema   Schema
+	Example  interface{}
+	Examples map[string]Example
+
+	Content map[string]MediaType
+}
+
+// UnmarshalYAML unmarshals a Header from YAML or JSON.
+func (h *Header) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		Reference   string `yaml:"$ref"`
+		Description *string
+		Required    bool
+		Deprecated  bool
+
+		Style   *string
+		Explode *bool
+
+		Schema   schemaValue
+		Example  interface{}
+		Examples map[string]Example
+
+		Content map[string]MediaType
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	h.Reference = y.Reference
+	h.Description = y.Description
+	h.Required = y.Required
+	h.Deprecated = y.Deprecated
+	h.Style = y.Style
+	h.Explode = y.Explode
+	h.Example = y.Example
+	h.Examples = y.Examples
+	h.Content = y.Content
+	h.Schema = y.Schema.Schema
+
+	return nil
+}
+
+// Example is a single example value, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#example-object
+type Example struct {
+	Reference     string `yaml:"$ref"`
+	Summary       *string
+	Description   *string
+	Value         interface{}
+	ExternalValue *string `yaml:"externalValue"`
+}
+
+// Link describes a design-time relationship between a response and another
+// operation, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#link-object
+type Link struct {
+	Reference    string  `yaml:"$ref"`
+	OperationRef *string `yaml:"operationRef"`
+	OperationID  *string `yaml:"operationId"`
+	Parameters   map[string]interface{}
+	RequestBody  interface{} `yaml:"requestBody"`
+	Description  *string
+	Server       *Server
+}
+
+// Callback is a map of runtime expressions to the PathItems describing the
+// out-of-band requests the API may make, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#callback-object
+type Callback struct {
+	Reference string
+	Paths     map[string]PathItem
+}
+
+// UnmarshalYAML unmarshals a Callback from YAML or JSON.
+func (c *Callback) UnmarshalYAML(um func(interface{}) error) error {
+	var ref struct {
+		Reference string `yaml:"$ref"`
+	}
+	if err := um(&ref); err != nil {
+		return err
+	}
+
+	if ref.Reference != "" {
+		c.Reference = ref.Reference
+		return nil
+	}
+
+	return um(&c.Paths)
+}
+
+// Tag defines additional metadata for tags attached to operations, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#tag-object
+type Tag struct {
+	Name          string // required
+	Description   *string
+	Documentation *ExternalDocumentation `yaml:"externalDocs"`
+}
+
+// Discriminator aids in serialization and deserialization of polymorphic
+// schemas, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#discriminator-object
+type Discriminator struct {
+	PropertyName string            `yaml:"propertyName"` // required
+	Mapping      map[string]string // value to schema name or reference
+}
+
+// Schema defines the common interface for schema definitions, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#schema-object
+type Schema interface {
+	GetTitle() *string
+	GetDescription() *string
+	GetDocumentation() *ExternalDocumentation
+	GetExample() interface{}
+
+	IsNullable() bool
+	GetConst() interface{}
+	GetDefs() *SchemaMap
+}
+
+// SchemaMap is an ordered list of named schema definitions or object properties,
+// so that order is maintained.
+type SchemaMap []struct {
+	Name   string
+	Schema Schema
+}
+
+// UnmarshalYAML unmarshals a SchemaMap from YAML or JSON.
+func (s *SchemaMap) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return decodeError(n, "expected a map of schemas")
+	}
+	*s = make(SchemaMap, len(n.Content)/2)
+
+	var errs typeErrors
+	for i := range *s {
+		v, err := decodeSchema(n.Content[2*i+1])
+		if err = errs.add(err); err != nil {
+			return err
+		}
+		(*s)[i] = struct {
+			Name   string
+			Schema Schema
+		}{n.Content[2*i].Value, v}
+	}
+
+	return errs.err()
+}
+
+// schemaValue decodes a single Schema of any kind, for use in place of a
+// Schema field.
+type schemaValue struct{ Schema }
+
+// UnmarshalYAML unmarshals a schemaValue from YAML or JSON.
+func (s *schemaValue) UnmarshalYAML(n *yaml.Node) error {
+	var err error
+	s.Schema, err = decodeSchema(n)
+	return err
+}
+
+// schemaOrBool decodes a value that may be either a Schema or a boolean, as
+// used by additionalProperties and 3.1 items.
+type schemaOrBool struct {
+	Schema
+	Bool *bool
+}
+
+// UnmarshalYAML unmarshals a schemaOrBool from YAML or JSON.
+func (s *schemaOrBool) UnmarshalYAML(n *yaml.Node) error {
+	if n.ShortTag() == "!!bool" {
+		return n.Decode(&s.Bool)
+	}
+
+	var err error
+	s.Schema, err = decodeSchema(n)
+	return err
+}
+
+func schemas(vs []schemaValue) []Schema {
+	if vs == nil {
+		return nil
+	}
+
+	out := make([]Schema, len(vs))
+	for i, v := range vs {
+		out[i] = v.Schema
+	}
+
+	return out
+}
+
+// schemaKeys holds the keywords that decide how a schema is decoded.
+type schemaKeys struct {
+	ref, allOf, oneOf, anyOf, not bool
+
+	typ, cnst                          *yaml.Node
+	exclusiveMinimum, exclusiveMaximum *yaml.Node
+}
+
+// decodeSchema decodes the schema at n. Its keys are scanned once to pick the
+// concrete Schema type, and n is then decoded directly into it. Nested schemas
+// are decoded the same way as they are reached, so each node is only visited
+// once, no matter how deeply schemas are nested.
+func decodeSchema(n *yaml.Node) (Schema, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, decodeError(n, "schema must be a map")
+	}
+
+	var k schemaKeys
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		v := n.Content[i+1]
+		switch n.Content[i].Value {
+		case "$ref":
+			k.ref = true
+		case "allOf":
+			k.allOf = true
+		case "oneOf":
+			k.oneOf = true
+		case "anyOf":
+			k.anyOf = true
+		case "not":
+			k.not = true
+		case "type":
+			k.typ = v
+		case "const":
+			k.cnst = v
+		case "exclusiveMinimum":
+			k.exclusiveMinimum = v
+		case "exclusiveMaximum":
+			k.exclusiveMaximum = v
+		}
+	}
+
+	n = normalizeSchema(n, &k)
+
+	if k.typ != nil && k.typ.Kind == yaml.SequenceNode {
+		return decodeTypeUnion(n, k.typ.Content)
+	}
+
+	var v Schema
+	switch {
+	case k.ref:
+		v = &ReferenceSchema{}
+	case k.allOf:
+		v = &AllOfSchema{}
+	case k.oneOf:
+		v = &OneOfSchema{}
+	case k.anyOf:
+		v = &AnyOfSchema{}
+	case k.not:
+		v = &NotSchema{}
+	default:
+		return decodeType(n, k.typ)
+	}
+
+	return v, n.Decode(v)
+}
+
+// decodeType decodes n as a schema of the type named by the node typ, which
+// may be nil.
+func decodeType(n, typ *yaml.Node) (Schema, error) {
+	var t string
+	if typ != nil {
+		switch typ.ShortTag() {
+		case "!!null":
+		case "!!str":
+			t = typ.Value
+		default:
+			var tv interface{}
+			_ = typ.Decode(&tv)
+			return nil, decodeError(typ, "bad schema type: %s", typeString(tv))
+		}
+	}
+
+	var v Schema
+	switch t {
+	case "object", "":
+		v = &ObjectSchema{}
+	case "null":
+		v = &NullSchema{}
+	case "string":
+		v = &StringSchema{}
+	case "number":
+		v = &NumberSchema{}
+	case "integer":
+		v = &IntegerSchema{}
+	case "boolean":
+		v = &BooleanSchema{}
+	case "array":
+		v = &ArraySchema{}
+	default:
+		return nil, decodeError(typ, "bad schema type: %s", t)
+	}
+
+	return v, n.Decode(v)
+}
+
+// normalizeSchema rewrites JSON Schema 2020-12 keywords used by OpenAPI 3.1 into
+// their OpenAPI 3.0 equivalents, updating k to match. n itself is not
+// modified; a shallow copy is returned when a change is needed.
+//
+// Numeric exclusiveMinimum and exclusiveMaximum values become a minimum or
+// maximum with the boolean form set, and the type of a const value is inferred
+// when no type is given.
+func normalizeSchema(n *yaml.Node, k *schemaKeys) *yaml.Node {
+	for _, e := range []struct {
+		value            **yaml.Node
+		exclusive, bound string
+	}{
+		{&k.exclusiveMinimum, "exclusiveMinimum", "minimum"},
+		{&k.exclusiveMaximum, "exclusiveMaximum", "maximum"},
+	} {
+		v := *e.value
+		if v == nil || (v.ShortTag() != "!!int" && v.ShortTag() != "!!float") {
+			continue
+		}
+
+		n = withValue(n, e.bound, v)
+		*e.value = scalar("!!bool", "true")
+		n = withValue(n, e.exclusive, *e.value)
+	}
+
+	if k.typ == nil && k.cnst != nil {
+		var t string
+		switch k.cnst.ShortTag() {
+		case "!!str":
+			t = "string"
+		case "!!int":
+			t = "integer"
+		case "!!float":
+			t = "number"
+		case "!!bool":
+			t = "boolean"
+		}
+
+		if t != "" {
+			k.typ = scalar("!!str", t)
+		}
+	}
+
+	return n
+}
+
+// decodeTypeUnion decodes a schema with multiple types, as allowed by
+// OpenAPI 3.1. A "null" type marks the schema as nullable. If more than one
+// other type remains, the schema is treated as an anyOf of each type.
+func decodeTypeUnion(n *yaml.Node, types []*yaml.Node) (Schema, error) {
+	var nonNull []*yaml.Node
+	nullable := false
+	for _, t := range types {
+		if t.Value == "null" {
+			nullable = true
+		} else {
+			nonNull = append(nonNull, t)
+		}
+	}
+
+	switch len(nonNull) {
+	case 0:
+		return decodeType(n, scalar("!!str", "null"))
+	case 1:
+		if nullable {
+			n = withValue(n, "nullable", scalar("!!bool", "true"))
+		}
+		return decodeType(n, nonNull[0])
+	}
+
+	var errs typeErrors
+	var a AnyOfSchema
+	if err := errs.add(n.Decode(&a.SchemaFields)); err != nil {
+		return nil, err
+	}
+	a.Nullable = a.Nullable || nullable
+
+	for _, t := range nonNull {
+		v, err := decodeType(&yaml.Node{Kind: yaml.MappingNode}, t)
+		if err = errs.add(err); err != nil {
+			return nil, err
+		}
+		a.AnyOf = append(a.AnyOf, v)
+	}
+
+	return &a, errs.err()
+}
+
+// withValue returns a shallow copy of the map node n, with key set to v.
+func withValue(n *yaml.Node, key string, v *yaml.Node) *yaml.Node {
+	out := *n
+	out.Content = make([]*yaml.Node, len(n.Content), len(n.Content)+2)
+	copy(out.Content, n.Content)
+
+	for i := 0; i+1 < len(out.Content); i += 2 {
+		if out.Content[i].Value == key {
+			out.Content[i+1] = v
+			return &out
+		}
+	}
+
+	out.Content = append(out.Content, scalar("!!str", key), v)
+	return &out
+}
+
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+func typeString(t interface{}) string {
+	if s, ok := t.(string); ok {
+		return s
+	}
+
+	b, err := yaml.Marshal(t)
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// decodeError returns an error for the value at n, positioned by its line and
+// column. As a *yaml.TypeError, decoding continues past it, so that any other
+// errors in the document are reported too.
+func decodeError(n *yaml.Node, format string, args ...interface{}) error {
+	return &yaml.TypeError{Errors: []string{
+		fmt.Sprintf("line %d, column %d: %s", n.Line, n.Column, fmt.Sprintf(format, args...)),
+	}}
+}
+
+// typeErrors collects the errors decoding each value of a collection, instead
+// of stopping at the first.
+type typeErrors []string
+
+// add adds the errors of err, if it is a *yaml.TypeError. Any other error
+// stops decoding, and is returned.
+func (e *typeErrors) add(err error) error {
+	if te, ok := err.(*yaml.TypeError); ok {
+		*e = append(*e, te.Errors...)
+		return nil
+	}
+
+	return err
+}
+
+// err returns the collected errors as a *yaml.TypeError, or nil if there are
+// none.
+func (e typeErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return &yaml.TypeError{Errors: e}
+}
+
+// ReferenceSchema is an inline reference to another schema definition.
+type ReferenceSchema struct {
+	Reference string `yaml:"$ref"` // required
+}
+
+// GetTitle returns the optional title for this schema.
+func (ReferenceSchema) GetTitle() *string { return nil }
+
+// GetDescription returns the optional documentation description for this schema.
+func (ReferenceSchema) GetDescription() *string { return nil }
+
+// GetDocumentation returns the optional Documentation for this schema.
+func (ReferenceSchema) GetDocumentation() *ExternalDocumentation { return nil }
+
+// GetExample returns the optional example value for this schema.
+func (ReferenceSchema) GetExample() interface{} { return nil }
+
+// IsNullable returns true if null is a valid value for this schema.
+func (ReferenceSchema) IsNullable() bool { return false }
+
+// GetConst returns the optional fixed value for this schema.
+func (ReferenceSchema) GetConst() interface{} { return nil }
+
+// GetDefs returns the optional schema definitions nested in this schema.
+func (ReferenceSchema) GetDefs() *SchemaMap { return nil }
+
+// SchemaFields holds the common fields for schema definitions.
+type SchemaFields struct {
+	Title         *string
+	Description   *string
+	Documentation *ExternalDocumentation `yaml:"externalDocs"`
+	Example       interface{}
+	Examples      []interface{} // 3.1 only
+
+	Nullable   bool
+	Deprecated bool
+
+	Const interface{} // 3.1 only
+	Defs  *SchemaMap  `yaml:"$defs"` // 3.1 only
+
+	ReadOnly  bool        `yaml:"readOnly"`  // valid only for items under properties
+	WriteOnly bool        `yaml:"writeOnly"` // valid only for items under properties
+	XML       interface{} // valid only for items under properties
+}
+
+// GetTitle returns the optional title for this schema.
+func (s *SchemaFields) GetTitle() *string { return s.Title }
+
+// GetDescription returns the optional documentation description for this schema.
+func (s *SchemaFields) GetDescription() *string { return s.Description }
+
+// GetDocumentation returns the optional Documentation for this schema.
+func (s *SchemaFields) GetDocumentation() *ExternalDocumentation { return s.Documentation }
+
+// GetExample returns the optional example value for this schema.
+func (s *SchemaFields) GetExample() interface{} { return s.Example }
+
+// IsNullable returns true if null is a valid value for this schema.
+func (s *SchemaFields) IsNullable() bool { return s.Nullable }
+
+// GetConst returns the optional fixed value for this schema.
+func (s *SchemaFields) GetConst() interface{} { return s.Const }
+
+// GetDefs returns the optional schema definitions nested in this schema.
+func (s *SchemaFields) GetDefs() *SchemaMap { return s.Defs }
+
+// AllOfSchema represents an allOf definition, according to
+// https://tools.ietf.org/html/draft-wright-json-schema-validation-00#section-5.22
+type AllOfSchema struct {
+	SchemaFields  `yaml:",inline"`
+	Discriminator *Discriminator
+	AllOf         []Schema `yaml:"allOf"`
+}
+
+// UnmarshalYAML unmarshals an AllOfSchema from YAML or JSON.
+func (a *AllOfSchema) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		SchemaFields  `yaml:",inline"`
+		Discriminator *Discriminator
+		AllOf         []schemaValue `yaml:"allOf"`
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	a.SchemaFields = y.SchemaFields
+	a.Discriminator = y.Discriminator
+	a.AllOf = schemas(y.AllOf)
+
+	return nil
+}
+
+// OneOfSchema represents a oneOf definition, according to
+// https://tools.ietf.org/html/draft-wright-json-schema-validation-00#section-5.24
+type OneOfSchema struct {
+	SchemaFields  `yaml:",inline"`
+	Discriminator *Discriminator
+	OneOf         []Schema `yaml:"oneOf"`
+}
+
+// UnmarshalYAML unmarshals a OneOfSchema from YAML or JSON.
+func (o *OneOfSchema) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		SchemaFields  `yaml:",inline"`
+		Discriminator *Discriminator
+		OneOf         []schemaValue `yaml:"oneOf"`
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	o.SchemaFields = y.SchemaFields
+	o.Discriminator = y.Discriminator
+	o.OneOf = schemas(y.OneOf)
+
+	return nil
+}
+
+// AnyOfSchema represents an anyOf definition, according to
+// https://tools.ietf.org/html/draft-wright-json-schema-validation-00#section-5.23
+type AnyOfSchema struct {
+	SchemaFields  `yaml:",inline"`
+	Discriminator *Discriminator
+	AnyOf         []Schema `yaml:"anyOf"`
+}
+
+// UnmarshalYAML unmarshals an AnyOfSchema from YAML or JSON.
+func (a *AnyOfSchema) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		SchemaFields  `yaml:",inline"`
+		Discriminator *Discriminator
+		AnyOf         []schemaValue `yaml:"anyOf"`
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	a.SchemaFields = y.SchemaFields
+	a.Discriminator = y.Discriminator
+	a.AnyOf = schemas(y.AnyOf)
+
+	return nil
+}
+
+// NotSchema represents a not definition, according to
+// https://tools.ietf.org/html/draft-wright-json-schema-validation-00#section-5.25
+type NotSchema struct {
+	SchemaFields `yaml:",inline"`
+	Not          Schema
+}
+
+// UnmarshalYAML unmarshals a NotSchema from YAML or JSON.
+func (n *NotSchema) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		SchemaFields `yaml:",inline"`
+		Not          schemaValue
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	n.SchemaFields = y.SchemaFields
+	n.Not = y.Not.Schema
+
+	return nil
+}
+
+// ObjectSchema is a schema definition for an object.
+type ObjectSchema struct {
+	SchemaFields  `yaml:",inline"`
+	Discriminator *Discriminator
+
+	Properties *SchemaMap
+	Required   *[]string
+
+	AdditionalProperties    Schema `yaml:"additionalProperties"` // null if defined as false
+	AnyAdditionalProperties bool   // if additionalProperties is true, this is set
+
+	MinProperties *uint64 `yaml:"minProperties"`
+	MaxProperties *uint64 `yaml:"maxProperties"`
+}
+
+// UnmarshalYAML unmarshals an ObjectSchema from YAML or JSON.
+func (o *ObjectSchema) UnmarshalYAML(um func(interface{}) error) error {
+	var oy struct {
+		SchemaFields  `yaml:",inline"`
+		Discriminator *Discriminator
+
+		Properties           *SchemaMap
+		Required             *[]string
+		AdditionalProperties schemaOrBool `yaml:"additionalProperties"`
+
+		MinProperties *uint64 `yaml:"minProperties"`
+		MaxProperties *uint64 `yaml:"maxProperties"`
+	}
+	if err := um(&oy); err != nil {
+		return err
+	}
+
+	o.SchemaFields = oy.SchemaFields
+	o.Discriminator = oy.Discriminator
+	o.Properties = oy.Properties
+	o.Required = oy.Required
+	o.MinProperties = oy.MinProperties
+	o.MaxProperties = oy.MaxProperties
+	o.AdditionalProperties = oy.AdditionalProperties.Schema
+	o.AnyAdditionalProperties = oy.AdditionalProperties.Bool != nil && *oy.AdditionalProperties.Bool
+
+	return nil
+}
+
+// NullSchema is a literal null schema definition. It is not part of OpenAPI
+// 3.0, but is produced when converting OpenAPI 2.0 documents.
+type NullSchema struct {
+	SchemaFields `yaml:",inline"`
+}
+
+// StringFields holds the validation fields for string schemas.
+type StringFields struct {
+	Format *string
+
+	Default *string
+	Enum    *[]string
+
+	MaxLength *int64 `yaml:"maxLength"`
+	MinLength *int64 `yaml:"minLength"`
+	Pattern   *string
+}
+
+// NumberFields holds the validation fields for number schemas.
+type NumberFields struct {
+	Format *string
+
+	Default *float64
+	Enum    *[]float64
+
+	Maximum          *float64
+	ExclusiveMaximum bool `yaml:"exclusiveMaximum"`
+	Minimum          *float64
+	ExclusiveMinimum bool     `yaml:"exclusiveMinimum"`
+	MultipleOf       *float64 `yaml:"multipleOf"`
+}
+
+// IntegerFields holds the validation fields for integer schemas.
+type IntegerFields struct {
+	Format *string
+
+	Default *int64
+	Enum    *[]int64
+
+	Maximum          *int64
+	ExclusiveMaximum bool `yaml:"exclusiveMaximum"`
+	Minimum          *int64
+	ExclusiveMinimum bool   `yaml:"exclusiveMinimum"`
+	MultipleOf       *int64 `yaml:"multipleOf"`
+}
+
+// BooleanFields holds the validation fields for boolean schemas.
+type BooleanFields struct {
+	Default *bool
+	Enum    *[]bool
+}
+
+// ArrayFields holds the validation fields for array schemas.
+type ArrayFields struct {
+	Default interface{}
+	Enum    *[]interface{}
+
+	MaxItems    *uint64 `yaml:"maxItems"`
+	MinItems    *uint64 `yaml:"minItems"`
+	UniqueItems bool    `yaml:"uniqueItems"`
+}
+
+// StringSchema is a schema definition for a string type.
+type StringSchema struct {
+	SchemaFields `yaml:",inline"`
+	StringFields `yaml:",inline"`
+}
+
+// NumberSchema is a schema definition for a number type.
+type NumberSchema struct {
+	SchemaFields `yaml:",inline"`
+	NumberFields `yaml:",inline"`
+}
+
+// IntegerSchema is a schema definition for an integer type.
+type IntegerSchema struct {
+	SchemaFields  `yaml:",inline"`
+	IntegerFields `yaml:",inline"`
+}
+
+// BooleanSchema is a schema definition for a boolean type.
+type BooleanSchema struct {
+	SchemaFields  `yaml:",inline"`
+	BooleanFields `yaml:",inline"`
+}
+
+// ArraySchema is a schema definition for an array type.
+type ArraySchema struct {
+	SchemaFields `yaml:",inline"`
+	ArrayFields  `yaml:",inline"`
+	Items        Schema // required for 3.0. nil if missing, or 3.1 items is false
+
+	PrefixItems []Schema // 3.1 only. Schemas for leading tuple items
+}
+
+// UnmarshalYAML unmarshals an ArraySchema from YAML or JSON.
+func (a *ArraySchema) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		SchemaFields `yaml:",inline"`
+		ArrayFields  `yaml:",inline"`
+		Items        schemaOrBool
+		PrefixItems  []schemaValue `yaml:"prefixItems"`
+	}
+
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	a.SchemaFields = y.SchemaFields
+	a.ArrayFields = y.ArrayFields
+	a.Items = y.Items.Schema
+	a.PrefixItems = schemas(y.PrefixItems)
+
+	return nil
+}
+
+// SecuritySchemeMap is a map if identifiers to SecuritySchemes.
+type SecuritySchemeMap map[string]SecurityScheme
+
+// UnmarshalYAML unmarshals a SecuritySchemaMap from YAML or JSON.
+func (s *SecuritySchemeMap) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return decodeError(n, "expected a map of security schemes")
+	}
+	*s = make(map[string]SecurityScheme, len(n.Content)/2)
+
+	var errs typeErrors
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		v, err := decodeSecurityScheme(n.Content[i+1])
+		if err = errs.add(err); err != nil {
+			return err
+		}
+		(*s)[n.Content[i].Value] = v
+	}
+
+	return errs.err()
+}
+
+// SecurityScheme is the common interface for security schemes, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#security-scheme-object
+type SecurityScheme interface {
+	Type() string // required
+	GetDescription() *string
+}
+
+// decodeSecurityScheme decodes the security scheme at n, by its type.
+func decodeSecurityScheme(n *yaml.Node) (SecurityScheme, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, decodeError(n, "security scheme must be a map")
+	}
+
+	typ, at := "", n
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "type" {
+			typ, at = n.Content[i+1].Value, n.Content[i+1]
+		}
+	}
+
+	var v SecurityScheme
+	switch typ {
+	case "apiKey":
+		v = &APIKeySecurityScheme{}
+	case "http":
+		v = &HTTPSecurityScheme{}
+	case "oauth2":
+		v = &OAuth2SecurityScheme{}
+	case "openIdConnect":
+		v = &OpenIDConnectSecurityScheme{}
+	case "":
+		return nil, decodeError(at, "security scheme has no type")
+	default:
+		return nil, decodeError(at, "bad security scheme type: %s", typ)
+	}
+
+	return v, n.Decode(v)
+}
+
+// SecuritySchemeFields holds the common fields for SecuritySchemes.
+type SecuritySchemeFields struct {
+	Description *string
+}
+
+// GetDescription returns the optional documentation description for this security scheme.
+func (s *SecuritySchemeFields) GetDescription() *string { return s.Description }
+
+// APIKeySecurityScheme represents an API key security scheme, sent via a header,
+// query parameter, or cookie.
+type APIKeySecurityScheme struct {
+	SecuritySchemeFields `yaml:",inline"`
+	Name                 string // required
+	In                   string // required
+}
+
+// Type returns the type of this SecurityScheme
+func (APIKeySecurityScheme) Type() string { return "apiKey" }
+
+// HTTPSecurityScheme represents an HTTP authentication security scheme, such
+// as basic or bearer auth.
+type HTTPSecurityScheme struct {
+	SecuritySchemeFields `yaml:",inline"`
+	Scheme               string  // required
+	BearerFormat         *string `yaml:"bearerFormat"`
+}
+
+// Type returns the type of this SecurityScheme
+func (HTTPSecurityScheme) Type() string { return "http" }
+
+// OAuth2SecurityScheme represents an OAuth 2.0 security scheme.
+type OAuth2SecurityScheme struct {
+	SecuritySchemeFields `yaml:",inline"`
+	Flows                OAuthFlows // required
+}
+
+// Type returns the type of this SecurityScheme
+func (OAuth2SecurityScheme) Type() string { return "oauth2" }
+
+// OAuthFlows holds the configuration of the supported OAuth flows, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#oauth-flows-object
+type OAuthFlows struct {
+	Implicit          *OAuthFlow
+	Password          *OAuthFlow
+	ClientCredentials *OAuthFlow `yaml:"clientCredentials"`
+	AuthorizationCode *OAuthFlow `yaml:"authorizationCode"`
+}
+
+// OAuthFlow holds the configuration for a single OAuth flow, according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#oauth-flow-object
+type OAuthFlow struct {
+	AuthorizationURL *url.URL // required for implicit and authorizationCode flows
+	TokenURL         *url.URL // required for password, clientCredentials, and authorizationCode flows
+	RefreshURL       *url.URL
+
+	Scopes map[string]string // required
+}
+
+// UnmarshalYAML unmarshals an OAuthFlow from YAML or JSON.
+func (o *OAuthFlow) UnmarshalYAML(um func(interface{}) error) error {
+	var y struct {
+		AuthorizationURL *string `yaml:"authorizationUrl"`
+		TokenURL         *string `yaml:"tokenUrl"`
+		RefreshURL       *string `yaml:"refreshUrl"`
+		Scopes           map[string]string
+	}
+	if err := um(&y); err != nil {
+		return err
+	}
+
+	o.Scopes = y.Scopes
+
+	var err error
+	if o.AuthorizationURL, err = parseURL(y.AuthorizationURL); err != nil {
+		return err
+	}
+	if o.TokenURL, err = parseURL(y.TokenURL); err != nil {
+		return err
+	}
+	o.RefreshURL, err = parseURL(y.RefreshURL)
+	return err
+}
+
+// OpenIDConnectSecurityScheme represents an OpenID Connect discovery security
+// scheme.
+type OpenIDConnectSecurityScheme struct {
+	SecuritySchemeFields `yaml:",inline"`
+	OpenIDConnectURL     string `yaml:"openIdConnectUrl"` // required
+}
+
+// Type returns the type of this SecurityScheme
+func (OpenIDConnectSecurityScheme) Type() string { return "openIdConnect" }
+
+func parseURL(s *string) (*url.URL, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	return url.Parse(*s)
+}
@@ -0,0 +1,486 @@
+package v3
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/lithammer/dedent"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParameterUnmarshalYAML(t *testing.T) {
+	explode := false
+	style := "form"
+
+	tcs := []struct {
+		name string
+		in   string
+		out  Parameter
+	}{
+		{
+			"schema",
+			`
+            name: param
+            in: query
+            schema:
+              type: string
+            `,
+			Parameter{In: "query", Name: "param", Schema: &StringSchema{}},
+		},
+		{
+			"style",
+			`
+            name: param
+            in: query
+            style: form
+            explode: false
+            schema:
+              type: array
+              items:
+                type: integer
+            `,
+			Parameter{
+				In: "query", Name: "param", Style: &style, Explode: &explode,
+				Schema: &ArraySchema{Items: &IntegerSchema{}},
+			},
+		},
+		{
+			"content",
+			`
+            name: param
+            in: query
+            content:
+              application/json:
+                schema:
+                  type: object
+            `,
+			Parameter{
+				In: "query", Name: "param",
+				Content: map[string]MediaType{
+					"application/json": {Schema: &ObjectSchema{}},
+				},
+			},
+		},
+		{
+			"$ref",
+			`
+            $ref: '#/components/parameters/TestParam'
+            `,
+			Parameter{Reference: "#/components/parameters/TestParam"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var out Parameter
+			if err := yaml.Unmarshal([]byte(dedent.Dedent(tc.in)), &out); err != nil {
+				t.Fatal("could not unmarshal. got error:", err)
+			}
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("Wrong value unmarshaled. got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestRequestBodyUnmarshalYAML(t *testing.T) {
+	d := dedent.Dedent(`
+    required: true
+    content:
+      application/json:
+        schema:
+          $ref: '#/components/schemas/Pet'
+      multipart/form-data:
+        schema:
+          type: object
+          properties:
+            file:
+              type: string
+              format: binary
+	`)
+
+	var out RequestBody
+	if err := yaml.Unmarshal([]byte(d), &out); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	binary := "binary"
+	expected := RequestBody{
+		Required: true,
+		Content: map[string]MediaType{
+			"application/json": {Schema: &ReferenceSchema{Reference: "#/components/schemas/Pet"}},
+			"multipart/form-data": {Schema: &ObjectSchema{
+				Properties: &SchemaMap{
+					{Name: "file", Schema: &StringSchema{StringFields: StringFields{Format: &binary}}},
+				},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Error("Wrong value unmarshaled. got:", out, "expected:", expected)
+	}
+}
+
+func TestResponsesUnmarshalYAML(t *testing.T) {
+	d := dedent.Dedent(`
+    200:
+      description: success
+      content:
+        application/json:
+          schema:
+            type: string
+    "204":
+      description: success with no content
+    4XX:
+      description: client error
+    default:
+      description: error
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Error'
+	`)
+
+	var out Responses
+	if err := yaml.Unmarshal([]byte(d), &out); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	expected := Responses{
+		Default: &Response{
+			Description: "error",
+			Content: map[string]MediaType{
+				"application/json": {Schema: &ReferenceSchema{Reference: "#/components/schemas/Error"}},
+			},
+		},
+		Codes: map[int]Response{
+			200: {
+				Description: "success",
+				Content: map[string]MediaType{
+					"application/json": {Schema: &StringSchema{}},
+				},
+			},
+			204: {
+				Description: "success with no content",
+			},
+		},
+		Ranges: map[int]Response{
+			4: {Description: "client error"},
+		},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Error("Wrong value unmarshaled. got:", out, "expected:", expected)
+	}
+}
+
+func TestSchemaMapUnmarshalYAML(t *testing.T) {
+	d := dedent.Dedent(`
+    name:
+      type: string
+      nullable: true
+    version:
+      type: number
+    active:
+      type: boolean
+    count:
+      type: integer
+    props:
+      type: object
+      properties:
+        name:
+          type: string
+    not:
+      not:
+        type: string
+	`)
+
+	var out SchemaMap
+	if err := yaml.Unmarshal([]byte(d), &out); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	expected := SchemaMap{
+		{Name: "name", Schema: &StringSchema{SchemaFields: SchemaFields{Nullable: true}}},
+		{Name: "version", Schema: &NumberSchema{}},
+		{Name: "active", Schema: &BooleanSchema{}},
+		{Name: "count", Schema: &IntegerSchema{}},
+		{Name: "props", Schema: &ObjectSchema{
+			Properties: &SchemaMap{
+				{Name: "name", Schema: &StringSchema{}},
+			},
+		}},
+		{Name: "not", Schema: &NotSchema{Not: &StringSchema{}}},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Error("Wrong value unmarshaled. got:", out, "expected:", expected)
+	}
+}
+
+func TestCompositionSchemaUnmarshalYAML(t *testing.T) {
+	d := dedent.Dedent(`
+    all:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            age:
+              type: integer
+    one:
+      oneOf:
+        - $ref: '#/components/schemas/Cat'
+        - $ref: '#/components/schemas/Dog'
+      discriminator:
+        propertyName: kind
+        mapping:
+          cat: '#/components/schemas/Cat'
+    any:
+      anyOf:
+        - type: string
+        - type: integer
+	`)
+
+	var out SchemaMap
+	if err := yaml.Unmarshal([]byte(d), &out); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	expected := SchemaMap{
+		{Name: "all", Schema: &AllOfSchema{
+			AllOf: []Schema{
+				&ReferenceSchema{Reference: "#/components/schemas/Base"},
+				&ObjectSchema{Properties: &SchemaMap{
+					{Name: "age", Schema: &IntegerSchema{}},
+				}},
+			},
+		}},
+		{Name: "one", Schema: &OneOfSchema{
+			Discriminator: &Discriminator{
+				PropertyName: "kind",
+				Mapping:      map[string]string{"cat": "#/components/schemas/Cat"},
+			},
+			OneOf: []Schema{
+				&ReferenceSchema{Reference: "#/components/schemas/Cat"},
+				&ReferenceSchema{Reference: "#/components/schemas/Dog"},
+			},
+		}},
+		{Name: "any", Schema: &AnyOfSchema{
+			AnyOf: []Schema{&StringSchema{}, &IntegerSchema{}},
+		}},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Error("Wrong value unmarshaled. got:", out, "expected:", expected)
+	}
+}
+
+func TestCallbackUnmarshalYAML(t *testing.T) {
+	d := dedent.Dedent(`
+    onEvent:
+      '{$request.body#/callbackUrl}':
+        post:
+          responses:
+            200:
+              description: ok
+    refd:
+      $ref: '#/components/callbacks/Other'
+	`)
+
+	var out map[string]Callback
+	if err := yaml.Unmarshal([]byte(d), &out); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	expected := map[string]Callback{
+		"onEvent": {Paths: map[string]PathItem{
+			"{$request.body#/callbackUrl}": {Post: &Operation{
+				Responses: &Responses{Codes: map[int]Response{200: {Description: "ok"}}},
+			}},
+		}},
+		"refd": {Reference: "#/components/callbacks/Other"},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Error("Wrong value unmarshaled. got:", out, "expected:", expected)
+	}
+}
+
+func TestSecuritySchemeMapUnmarshalYAML(t *testing.T) {
+	d := dedent.Dedent(`
+    httpType:
+      type: http
+      description: bearer auth
+      scheme: bearer
+    apiKeyType:
+      type: apiKey
+      name: Authorization
+      in: header
+    oauthType:
+      type: oauth2
+      flows:
+        implicit:
+          authorizationUrl: "http://whereever.place"
+          scopes: {}
+    oidcType:
+      type: openIdConnect
+      openIdConnectUrl: "http://whereever.place/.well-known"
+	`)
+
+	var out SecuritySchemeMap
+	if err := yaml.Unmarshal([]byte(d), &out); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	hdesc := "bearer auth"
+	oURL, _ := url.Parse("http://whereever.place")
+	expected := SecuritySchemeMap{
+		"httpType": &HTTPSecurityScheme{
+			SecuritySchemeFields: SecuritySchemeFields{Description: &hdesc},
+			Scheme:               "bearer",
+		},
+		"apiKeyType": &APIKeySecurityScheme{Name: "Authorization", In: "header"},
+		"oauthType": &OAuth2SecurityScheme{Flows: OAuthFlows{
+			Implicit: &OAuthFlow{AuthorizationURL: oURL, Scopes: make(map[string]string)},
+		}},
+		"oidcType": &OpenIDConnectSecurityScheme{
+			OpenIDConnectURL: "http://whereever.place/.well-known",
+		},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Error("Wrong value unmarshaled. got:", out, "expected:", expected)
+	}
+}
+
+func TestSchemaMapUnmarshalYAML31(t *testing.T) {
+	d := dedent.Dedent(`
+    nullable:
+      type: [string, "null"]
+      examples: [a, b]
+    onlyNull:
+      type: ["null"]
+    union:
+      type: [string, integer, "null"]
+      description: either
+    const:
+      const: fixed
+    exclusive:
+      type: number
+      exclusiveMaximum: 10
+    tuple:
+      type: array
+      prefixItems:
+        - type: string
+        - type: integer
+      items: false
+    defs:
+      type: object
+      $defs:
+        inner:
+          type: boolean
+	`)
+
+	var out SchemaMap
+	if err := yaml.Unmarshal([]byte(d), &out); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	fixed := "fixed"
+	either := "either"
+	ten := float64(10)
+	expected := SchemaMap{
+		{Name: "nullable", Schema: &StringSchema{SchemaFields: SchemaFields{
+			Nullable: true, Examples: []interface{}{"a", "b"},
+		}}},
+		{Name: "onlyNull", Schema: &NullSchema{}},
+		{Name: "union", Schema: &AnyOfSchema{
+			SchemaFields: SchemaFields{Description: &either, Nullable: true},
+			AnyOf:        []Schema{&StringSchema{}, &IntegerSchema{}},
+		}},
+		{Name: "const", Schema: &StringSchema{SchemaFields: SchemaFields{Const: fixed}}},
+		{Name: "exclusive", Schema: &NumberSchema{NumberFields: NumberFields{
+			Maximum: &ten, ExclusiveMaximum: true,
+		}}},
+		{Name: "tuple", Schema: &ArraySchema{PrefixItems: []Schema{&StringSchema{}, &IntegerSchema{}}}},
+		{Name: "defs", Schema: &ObjectSchema{SchemaFields: SchemaFields{Defs: &SchemaMap{
+			{Name: "inner", Schema: &BooleanSchema{}},
+		}}}},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Error("Wrong value unmarshaled. got:", out, "expected:", expected)
+	}
+}
+
+func TestSchemaMapUnmarshalYAMLAliases(t *testing.T) {
+	d := dedent.Dedent(`
+    base: &base
+      type: string
+      description: shared
+    alias: *base
+    list:
+      type: array
+      items: *base
+	`)
+
+	var out SchemaMap
+	if err := yaml.Unmarshal([]byte(d), &out); err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	shared := "shared"
+	base := &StringSchema{SchemaFields: SchemaFields{Description: &shared}}
+	expected := SchemaMap{
+		{Name: "base", Schema: base},
+		{Name: "alias", Schema: base},
+		{Name: "list", Schema: &ArraySchema{Items: base}},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Error("Wrong value unmarshaled. got:", out, "expected:", expected)
+	}
+}
+
+func TestSchemaMapUnmarshalYAMLErrors(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   string
+		errs []string
+	}{
+		{"bad type", `
+            a:
+              type: object
+              properties:
+                b:
+                  type: strin
+            `, []string{"line 6, column 13: bad schema type: strin"}},
+		{"bad type union", `
+            a:
+              type: [string, strin]
+            `, []string{"line 3, column 18: bad schema type: strin"}},
+		{"not a map", `
+            a: 5
+            `, []string{"line 2, column 4: schema must be a map"}},
+		{"each error", `
+            a:
+              type: strin
+            b:
+              type: object
+              properties:
+                c:
+                  maxLength: many
+                  type: string
+            d: 5
+            `, []string{
+			"line 3, column 9: bad schema type: strin",
+			"line 8: cannot unmarshal !!str `many` into int64",
+			"line 10, column 4: schema must be a map",
+		}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var out SchemaMap
+			err := yaml.Unmarshal([]byte(dedent.Dedent(tc.in)), &out)
+			te, ok := err.(*yaml.TypeError)
+			if !ok || !reflect.DeepEqual(te.Errors, tc.errs) {
+				t.Error("got:", err, "expected:", tc.errs)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 // Package pkg contains the structures that define a generated go client api,
 package pkg
 
+import "reflect"
+
 // Package is a go package
 type Package struct {
 	Qualifier string
@@ -9,9 +11,12 @@ type Package struct {
 	BaseURL string
 
 	TypeDecls []TypeDecl
+	Consts    []ConstDecl
 
 	Iters   []Iter
 	Clients []Client
+
+	DateType string // optional. Name of the civil date type, if used
 }
 
 // Type is type literal or qualified identifier. It may be used inline, or
@@ -102,6 +107,16 @@ func (t *IterType) Equal(o Type) bool {
 	return false
 }
 
+// StreamType is used for return types, indicating the response body is
+// returned unread, as a *Stream
+type StreamType struct{}
+
+// Equal implements equality for Types
+func (t *StreamType) Equal(o Type) bool {
+	_, ok := o.(*StreamType)
+	return ok
+}
+
 // MapType is a map type
 type MapType struct {
 	Key   Type
@@ -126,17 +141,78 @@ func (t *InterfaceType) Equal(o Type) bool {
 	return ok
 }
 
+// UnionType is a value of one of several types. It is declared as a struct
+// holding the value, with methods to get and set each variant.
+type UnionType struct {
+	Variants      []UnionVariant // in the order they are tried when decoding
+	Discriminator string         // optional. Property selecting the variant by value
+}
+
+// Equal implements equality for Types
+func (t *UnionType) Equal(o Type) bool {
+	ot, ok := o.(*UnionType)
+	if !ok || t.Discriminator != ot.Discriminator || len(t.Variants) != len(ot.Variants) {
+		return false
+	}
+
+	for i, v := range t.Variants {
+		ov := ot.Variants[i]
+		if v.Name != ov.Name || !v.Type.Equal(ov.Type) || !reflect.DeepEqual(v.Values, ov.Values) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// UnionVariant is one of the types of a union.
+type UnionVariant struct {
+	Name string // names the methods for the variant, ie AsCard
+	Type Type
+
+	Values []string // optional. Discriminator values that select the variant
+}
+
 // TypeDecl is a type declaration.
 type TypeDecl struct {
 	Name    string
 	Comment string
 	Type    Type
+
+	Enum          []ConstDecl    // optional. The values the type is limited to
+	Discriminator *Discriminator // optional. Declares the type as an interface, implemented by its variants
+}
+
+// Discriminator selects the type of a polymorphic value by the value of one
+// of its properties.
+type Discriminator struct {
+	Property string // as named in the JSON encoding
+	Base     string // struct type for values that match no variant
+	Variants []Variant
+}
+
+// Variant is a struct type implementing a polymorphic type, and the value of
+// the discriminator property that selects it.
+type Variant struct {
+	Value string
+	Type  string
+}
+
+// ConstDecl is a constant declaration.
+type ConstDecl struct {
+	Name    string
+	Comment string
+	Type    Type
+	Value   interface{}
 }
 
 // Iter is an iterator over multiple results/pages from a response
 type Iter struct {
 	Name   string
 	Return Type
+
+	Envelope *IdentType // optional type of responses wrapping the items
+	Field    string     // field of Envelope holding the items
 }
 
 // Field is a struct field
@@ -145,9 +221,10 @@ type Field struct {
 	Type    Type
 	Comment string
 
-	Orig       string     // optional name of field as it is originally from the spec
-	Kind       Kind       // optional. Used for Opts structs
-	Collection Collection // optional. Used for Opts structs
+	Orig        string       // optional name of field as it is originally from the spec
+	Kind        Kind         // optional. Used for Opts structs
+	Collection  Collection   // optional. Used for Opts structs
+	Constraints *Constraints // optional. Limits on the field's value
 }
 
 func (f Field) equal(of Field) bool {
@@ -156,7 +233,29 @@ func (f Field) equal(of Field) bool {
 		f.Comment == of.Comment &&
 		f.Orig == of.Orig &&
 		f.Kind == of.Kind &&
-		f.Collection == of.Collection
+		f.Collection == of.Collection &&
+		reflect.DeepEqual(f.Constraints, of.Constraints)
+}
+
+// Constraints are the limits a schema places on a value, beyond its type.
+// Limits that are not set are nil.
+type Constraints struct {
+	Required bool // a slice or map must not be nil
+
+	MinLength *int64 // in characters, not bytes
+	MaxLength *int64
+	Pattern   *string // a regular expression in RE2 syntax
+
+	Minimum          *float64
+	Maximum          *float64
+	ExclusiveMinimum bool
+	ExclusiveMaximum bool
+	MultipleOf       *float64
+
+	MinItems *uint64
+	MaxItems *uint64
+
+	Items *Constraints // optional. Limits on each item of a slice, or value of a map
 }
 
 // Client is a struct that holds the methods for communicating with an API
@@ -166,6 +265,9 @@ type Client struct {
 	Comment     string
 	ContextName string
 
+	Parent string // optional. Name of the client holding this one, in a field
+	Field  string // optional. Name of the field of Parent holding this client
+
 	Methods []Method
 }
 
@@ -182,10 +284,20 @@ type Method struct {
 	Return  []Type
 	Comment string
 
-	Errors map[int]Type // Non-success status codes to types. -1 is default
+	Errors  map[int]Type // Non-success status codes to types. -1 is default
+	Results []Result     // optional. Fields of the result type holding each success body
+	Meta    Type         // optional. Type holding the parsed response headers, returned before the error
 
 	HTTPMethod string
 	Path       string // Path to endpoint, in printf format, including base path.
+	Form       string // optional. Media type of the body holding Form params
+}
+
+// Result is the body of one of several successful responses of a Method,
+// held in a field of the type it returns.
+type Result struct {
+	Code  int // Status code of the response. -1 is default
+	Field string
 }
 
 // Kind is the kind of parameter; ie where it maps to in the request
@@ -197,6 +309,7 @@ const (
 	Query
 	Path
 	Header
+	Form // form field, sent in a multipart/form-data or urlencoded body
 
 	Opts // Opts struct holding optional values
 )
@@ -216,10 +329,11 @@ const (
 
 // Param is a function parameter
 type Param struct {
-	ID         string
-	Orig       string // original name, ie for query params or headers
-	Arg        string // argument name, this is to avoid reserved keywords being used
-	Type       Type
-	Kind       Kind
-	Collection Collection
+	ID          string
+	Orig        string // original name, ie for query params or headers
+	Arg         string // argument name, this is to avoid reserved keywords being used
+	Type        Type
+	Kind        Kind
+	Collection  Collection
+	Constraints *Constraints // optional
 }
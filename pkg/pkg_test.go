@@ -15,6 +15,8 @@ func TestTypeEqual(t *testing.T) {
 		&MapType{Key: &IdentType{Name: "string"}, Value: &IdentType{Name: "int"}},
 		&MapType{Key: &IdentType{Name: "int"}, Value: &SliceType{&IdentType{Name: "int"}}},
 		&InterfaceType{},
+		&UnionType{Variants: []UnionVariant{{Name: "String", Type: &IdentType{Name: "string"}}}},
+		&UnionType{Variants: []UnionVariant{{Name: "Card", Type: &IdentType{Name: "Card"}, Values: []string{"card"}}}, Discriminator: "type"},
 	}
 
 	for i := range cases {
@@ -0,0 +1,112 @@
+package translator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/jbowes/oag/pkg"
+)
+
+const schemaPrefix = "#/components/schemas/"
+
+// discriminators finds the schemas of the document that select their type by
+// a discriminator property, and the schemas derived from them with allOf,
+// keyed by their type name. Schemas without derived or mapped schemas are
+// left as plain structs.
+func (tr *typeRegistry) discriminators() map[string]*pkg.Discriminator {
+	if tr.doc.Components == nil || tr.doc.Components.Schemas == nil {
+		return nil
+	}
+
+	schemas := *tr.doc.Components.Schemas
+	names := make(map[string]bool, len(schemas))
+	var bases []string
+	for _, def := range schemas {
+		names[def.Name] = true
+		if o, ok := def.Schema.(*v3.ObjectSchema); ok && o.Discriminator != nil {
+			bases = append(bases, def.Name)
+		}
+	}
+	if len(bases) == 0 {
+		return nil
+	}
+
+	derived := make(map[string][]string)
+	for _, def := range schemas {
+		a, ok := def.Schema.(*v3.AllOfSchema)
+		if !ok {
+			continue
+		}
+
+		for _, s := range a.AllOf {
+			if r, ok := s.(*v3.ReferenceSchema); ok && strings.HasPrefix(r.Reference, schemaPrefix) {
+				base := r.Reference[len(schemaPrefix):]
+				derived[base] = append(derived[base], def.Name)
+			}
+		}
+	}
+
+	out := make(map[string]*pkg.Discriminator)
+	for _, def := range schemas {
+		o, ok := def.Schema.(*v3.ObjectSchema)
+		if !ok || o.Discriminator == nil {
+			continue
+		}
+
+		name := formatID(def.Name)
+		d := &pkg.Discriminator{Property: o.Discriminator.PropertyName, Base: name + "Base"}
+
+		// Mapped values replace the default value of a schema, its name.
+		mapped := make(map[string]bool)
+		for value, target := range o.Discriminator.Mapping {
+			target = strings.TrimPrefix(target, schemaPrefix)
+			if !names[target] {
+				leave := tr.enter("components", "schemas", def.Name, "discriminator", "mapping", value)
+				tr.errorf("mapping to unknown schema %s", target)
+				leave()
+				continue
+			}
+
+			typ := formatID(target)
+			if target == def.Name {
+				typ = d.Base
+			}
+			d.Variants = append(d.Variants, pkg.Variant{Value: value, Type: typ})
+			mapped[target] = true
+		}
+
+		for _, v := range derived[def.Name] {
+			if !mapped[v] {
+				d.Variants = append(d.Variants, pkg.Variant{Value: v, Type: formatID(v)})
+			}
+		}
+
+		if len(d.Variants) == 0 {
+			continue
+		}
+
+		sort.Slice(d.Variants, func(i, j int) bool { return d.Variants[i].Value < d.Variants[j].Value })
+		out[name] = d
+	}
+
+	return out
+}
+
+// isInterface reports if t is a polymorphic type, declared as an interface.
+// Its values are never wrapped in pointers, as nil interfaces already
+// represent missing values.
+func (tr *typeRegistry) isInterface(t pkg.Type) bool {
+	it, ok := t.(*pkg.IdentType)
+	return ok && it.Qualifier == "" && tr.polymorphic[it.Name] != nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,124 @@
+package translator
+
+import (
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/jbowes/oag/pkg"
+)
+
+// listProperty returns the name of the property of the response schema s
+// holding the items of a wrapped list, such as data in
+// {"data": [...], "next_cursor": "..."}, or "" if s is not a wrapped list.
+// The property is the array named by the configuration, or else the only
+// array of objects.
+func listProperty(tr *typeRegistry, s v3.Schema) string {
+	os, ok := resolvedSchema(tr.doc, s).(*v3.ObjectSchema)
+	if !ok || os.Properties == nil {
+		return ""
+	}
+
+	name := ""
+	for _, prop := range *os.Properties {
+		as, ok := resolvedSchema(tr.doc, prop.Schema).(*v3.ArraySchema)
+		if !ok {
+			continue
+		}
+
+		if tr.listField != "" {
+			if prop.Name == tr.listField {
+				return prop.Name
+			}
+			continue
+		}
+
+		items, _ := nonNull(as.Items)
+		switch resolvedSchema(tr.doc, items).(type) {
+		case *v3.ObjectSchema, *v3.AllOfSchema, *v3.OneOfSchema, *v3.AnyOfSchema:
+		default:
+			continue
+		}
+
+		if name != "" {
+			return "" // several lists; none is the list
+		}
+		name = prop.Name
+	}
+
+	return name
+}
+
+// resolvedSchema returns s, or the schema it references. Schemas that fail
+// to resolve are returned as is, to be reported when converted.
+func resolvedSchema(doc *v3.Document, s v3.Schema) v3.Schema {
+	if rs, ok := s.(*v3.ReferenceSchema); ok && doc != nil {
+		if r, err := doc.ResolveSchema(rs.Reference); err == nil {
+			return r
+		}
+	}
+
+	return s
+}
+
+// envelopeTypes returns the return values of a method with the success
+// response type t, which wraps a list in its property prop. The method
+// returns an iterator over the items, which also holds the rest of each
+// response.
+func envelopeTypes(tr *typeRegistry, t pkg.Type, prop string, p *pkg.Package) []pkg.Type {
+	it, ok := t.(*pkg.IdentType)
+	if !ok || tr.isInterface(t) {
+		return returnTypes(tr, t, p)
+	}
+
+	var field *pkg.Field
+	for _, td := range tr.types {
+		st, ok := td.Type.(*pkg.StructType)
+		if td.Name != it.Name || !ok {
+			continue
+		}
+
+		for i, f := range st.Fields {
+			if f.Orig == prop || f.Orig == "" && f.ID == prop {
+				field = &st.Fields[i]
+			}
+		}
+	}
+	if field == nil {
+		return returnTypes(tr, t, p)
+	}
+
+	ft := field.Type
+	if pt, ok := ft.(*pkg.PointerType); ok {
+		ft = pt.Type
+	}
+	var elem *pkg.IdentType
+	if st, ok := ft.(*pkg.SliceType); ok {
+		elem, _ = st.Type.(*pkg.IdentType)
+	}
+	if elem == nil {
+		tr.errorf("unsupported array response items")
+		return []pkg.Type{&pkg.IdentType{Name: "error"}}
+	}
+
+	return iterTypes(p, pkg.Iter{
+		Name:     it.Name + "Iter",
+		Return:   tr.indirect(elem),
+		Envelope: it,
+		Field:    field.ID,
+	})
+}
+
+// iterTypes returns the return values of a method returning the iterator
+// iter, declaring it if it is new.
+func iterTypes(p *pkg.Package, iter pkg.Iter) []pkg.Type {
+	// iterators are shared by all lists of the same type
+	declared := false
+	for _, i := range p.Iters {
+		declared = declared || i.Name == iter.Name
+	}
+	if !declared {
+		p.Iters = append(p.Iters, iter)
+	}
+
+	return []pkg.Type{&pkg.IterType{Type: &pkg.PointerType{
+		Type: &pkg.IdentType{Name: iter.Name},
+	}}}
+}
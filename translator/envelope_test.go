@@ -0,0 +1,116 @@
+package translator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lithammer/dedent"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jbowes/oag/config"
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestTranslateEnvelope(t *testing.T) {
+	var doc v3.Document
+	err := yaml.Unmarshal([]byte(dedent.Dedent(`
+        openapi: 3.0.3
+        components:
+          schemas:
+            Pet:
+              type: object
+              properties:
+                toys:
+                  type: array
+                  items:
+                    type: object
+            PetPage:
+              type: object
+              properties:
+                data:
+                  type: array
+                  items:
+                    $ref: '#/components/schemas/Pet'
+                next_cursor:
+                  type: string
+        paths:
+          /pets:
+            get:
+              responses:
+                200:
+                  description: ok
+                  content:
+                    application/json:
+                      schema:
+                        $ref: '#/components/schemas/PetPage'
+          /pets/{id}:
+            get:
+              responses:
+                200:
+                  description: ok
+                  content:
+                    application/json:
+                      schema:
+                        $ref: '#/components/schemas/Pet'
+          /owners:
+            get:
+              responses:
+                200:
+                  description: ok
+                  content:
+                    application/json:
+                      schema:
+                        type: object
+                        properties:
+                          owners:
+                            type: array
+                            items:
+                              type: object
+                          data:
+                            type: array
+                            items:
+                              $ref: '#/components/schemas/Pet'
+        `)), &doc)
+	if err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	pet := &pkg.PointerType{Type: &pkg.IdentType{Name: "Pet"}}
+	tcs := []struct {
+		name      string
+		listField string
+		methods   []string
+		iters     []pkg.Iter
+	}{
+		{"detected", "", []string{"OwnersClient.Get", "PetsClient.Get", "PetsClient.List"}, []pkg.Iter{
+			{Name: "PetPageIter", Return: pet, Envelope: &pkg.IdentType{Name: "PetPage"}, Field: "Data"},
+		}},
+		{"configured", "data", []string{"OwnersClient.List", "PetsClient.Get", "PetsClient.List"}, []pkg.Iter{
+			{Name: "OwnersListResponseIter", Return: pet, Envelope: &pkg.IdentType{Name: "OwnersListResponse"}, Field: "Data"},
+			{Name: "PetPageIter", Return: pet, Envelope: &pkg.IdentType{Name: "PetPage"}, Field: "Data"},
+		}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			p, diags := Translate(&doc, &config.Config{Naming: config.NamingPath, ListField: tc.listField})
+			if len(diags) > 0 {
+				t.Fatal("unexpected diagnostics:", diags)
+			}
+
+			var methods []string
+			for _, c := range p.Clients {
+				for _, m := range c.Methods {
+					methods = append(methods, c.Name+"."+m.Name)
+				}
+			}
+			if !reflect.DeepEqual(methods, tc.methods) {
+				t.Error("got:", methods, "expected:", tc.methods)
+			}
+			if !reflect.DeepEqual(p.Iters, tc.iters) {
+				t.Errorf("got: %+v expected: %+v", p.Iters, tc.iters)
+			}
+		})
+	}
+}
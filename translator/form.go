@@ -0,0 +1,118 @@
+package translator
+
+import (
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/jbowes/oag/pkg"
+)
+
+// The form media types, in order of preference.
+var formMediaTypes = []string{"multipart/form-data", "application/x-www-form-urlencoded"}
+
+// formMediaType picks the preferred form media type from content, or the
+// empty string if it has none.
+func formMediaType(content map[string]v3.MediaType) string {
+	for _, mt := range formMediaTypes {
+		if _, ok := content[mt]; ok {
+			return mt
+		}
+	}
+
+	return ""
+}
+
+// convertForm converts the properties of the form body schema s, sent as the
+// media type mt, into Form parameters. Required properties are returned as
+// params, and optional properties as fields of the opts struct. Files are
+// always params, read from an io.Reader, as a nil reader already represents a
+// missing file.
+func convertForm(tr *typeRegistry, def *v3.Document, mt string, s v3.Schema, client *pkg.Client, typePrefix string) ([]pkg.Param, []pkg.Field) {
+	if r, ok := s.(*v3.ReferenceSchema); ok {
+		var err error
+		if s, err = def.ResolveSchema(r.Reference); err != nil {
+			tr.errorf("%s", err)
+			return nil, nil
+		}
+	}
+
+	o, ok := s.(*v3.ObjectSchema)
+	if !ok {
+		tr.errorf("unsupported form schema %s", schemaKind(s))
+		return nil, nil
+	}
+	if o.Properties == nil {
+		return nil, nil
+	}
+
+	var required []string
+	if o.Required != nil {
+		required = *o.Required
+	}
+
+	var params []pkg.Param
+	var opts []pkg.Field
+	for _, prop := range *o.Properties {
+		leave := tr.enter("properties", prop.Name)
+
+		if isFile(prop.Schema) {
+			if mt != "multipart/form-data" {
+				tr.errorf("file property %s is only supported in multipart/form-data bodies", prop.Name)
+				leave()
+				continue
+			}
+
+			paramID := formatVar(prop.Name)
+			params = append(params, pkg.Param{
+				ID:   paramID,
+				Orig: prop.Name,
+				Arg:  formatReserved(paramID, client.ContextName),
+				Type: &pkg.IdentType{Qualifier: "io", Name: "Reader"},
+				Kind: pkg.Form,
+			})
+			leave()
+			continue
+		}
+
+		typ := tr.convertItems(prop.Schema, typePrefix+formatID(prop.Name))
+		cf := pkg.None
+		if _, ok := typ.(*pkg.SliceType); ok {
+			cf = pkg.Multi
+		}
+
+		if contains(required, prop.Name) {
+			paramID := formatVar(prop.Name)
+			params = append(params, pkg.Param{
+				ID:          paramID,
+				Orig:        prop.Name,
+				Arg:         formatReserved(paramID, client.ContextName),
+				Type:        typ,
+				Kind:        pkg.Form,
+				Collection:  cf,
+				Constraints: tr.constraintsFor(prop.Schema),
+			})
+			leave()
+			continue
+		}
+
+		opt := pkg.Field{
+			ID:          formatID(prop.Name),
+			Orig:        prop.Name,
+			Type:        &pkg.PointerType{Type: typ},
+			Kind:        pkg.Form,
+			Collection:  cf,
+			Constraints: tr.constraintsFor(prop.Schema),
+		}
+		if d := prop.Schema.GetDescription(); d != nil {
+			opt.Comment = *d
+		}
+		opts = append(opts, opt)
+		leave()
+	}
+
+	return params, opts
+}
+
+// isFile reports if s is the schema of a file, a binary string.
+func isFile(s v3.Schema) bool {
+	ss, ok := s.(*v3.StringSchema)
+	return ok && ss.Format != nil && *ss.Format == "binary"
+}
@@ -0,0 +1,92 @@
+package translator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestFormMediaType(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   []string
+		out  string
+	}{
+		{"none", []string{"text/plain"}, ""},
+		{"urlencoded", []string{"application/x-www-form-urlencoded"}, "application/x-www-form-urlencoded"},
+		{"multipart preferred", []string{"application/x-www-form-urlencoded", "multipart/form-data"}, "multipart/form-data"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			content := make(map[string]v3.MediaType)
+			for _, mt := range tc.in {
+				content[mt] = v3.MediaType{}
+			}
+
+			if out := formMediaType(content); out != tc.out {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestConvertForm(t *testing.T) {
+	binary, years := "binary", "in years"
+	props := v3.SchemaMap{
+		{Name: "name", Schema: &v3.StringSchema{}},
+		{Name: "image", Schema: &v3.StringSchema{StringFields: v3.StringFields{Format: &binary}}},
+		{Name: "tags", Schema: &v3.ArraySchema{Items: &v3.StringSchema{}}},
+		{Name: "age", Schema: &v3.IntegerSchema{SchemaFields: v3.SchemaFields{Description: &years}}},
+	}
+	schema := &v3.ObjectSchema{Properties: &props, Required: &[]string{"name", "tags"}}
+
+	tcs := []struct {
+		name   string
+		mt     string
+		params []pkg.Param
+		opts   []pkg.Field
+		errs   bool
+	}{
+		{"multipart", "multipart/form-data",
+			[]pkg.Param{
+				{ID: "name", Orig: "name", Arg: "name", Type: &pkg.IdentType{Name: "string"}, Kind: pkg.Form},
+				{ID: "image", Orig: "image", Arg: "image", Type: &pkg.IdentType{Qualifier: "io", Name: "Reader"}, Kind: pkg.Form},
+				{ID: "tags", Orig: "tags", Arg: "tags", Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "string"}}, Kind: pkg.Form, Collection: pkg.Multi},
+			},
+			[]pkg.Field{
+				{ID: "Age", Orig: "age", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "int"}}, Kind: pkg.Form, Comment: "in years"},
+			},
+			false,
+		},
+		{"urlencoded", "application/x-www-form-urlencoded",
+			[]pkg.Param{
+				{ID: "name", Orig: "name", Arg: "name", Type: &pkg.IdentType{Name: "string"}, Kind: pkg.Form},
+				{ID: "tags", Orig: "tags", Arg: "tags", Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "string"}}, Kind: pkg.Form, Collection: pkg.Multi},
+			},
+			[]pkg.Field{
+				{ID: "Age", Orig: "age", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "int"}}, Kind: pkg.Form, Comment: "in years"},
+			},
+			true, // files can't be sent
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := &typeRegistry{}
+			params, opts := convertForm(tr, &v3.Document{}, tc.mt, schema, &pkg.Client{ContextName: "pets"}, "Upload")
+
+			if !reflect.DeepEqual(params, tc.params) {
+				t.Error("got params:", params, "expected:", tc.params)
+			}
+			if !reflect.DeepEqual(opts, tc.opts) {
+				t.Error("got opts:", opts, "expected:", tc.opts)
+			}
+			if errs := tr.diags.HasErrors(); errs != tc.errs {
+				t.Error("got errors:", tr.diags, "expected:", tc.errs)
+			}
+		})
+	}
+}
@@ -40,7 +40,7 @@ func partsChan(words ...string) <-chan string {
 			w := ""
 			var last rune
 			for _, ch := range word {
-				if ch == '-' || ch == '_' || unicode.IsSpace(ch) {
+				if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) {
 					c <- w
 					w = ""
 					continue
@@ -72,8 +72,16 @@ var reserved = []string{
 	"complex64", "complex128", "byte", "rune", "uintptr",
 }
 
+// locals are declared by the writer in the body of generated methods, so
+// args may not use them either.
+var locals = []string{
+	"buf", "mw", "form", // form bodies
+	"meta", "httpResp", // response headers
+	"result", "v", // result types
+}
+
 func formatReserved(s, c string) string {
-	for _, r := range reserved {
+	for _, r := range append(reserved, locals...) {
 		if s == r {
 			return fmt.Sprintf("%s%s", strings.ToLower(c), strings.Title(s))
 		}
@@ -32,6 +32,8 @@ func TestFormatID(t *testing.T) {
 		{"thing_thinger", "ThingThinger"},
 		{"thing-thinger", "ThingThinger"},
 		{"thing thinger", "ThingThinger"},
+		{"thing.thinger", "ThingThinger"},
+		{":thinger", "Thinger"},
 		{"thing", "thinger", "ThingThinger"},
 	}
 
@@ -0,0 +1,167 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jbowes/oag/config"
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/jbowes/oag/pkg"
+)
+
+// grouper assigns operations to clients.
+type grouper struct {
+	root   *node
+	byTag  bool
+	nested bool
+	prefix []token // ignored at the start of paths, ie /v1
+
+	tags    map[string]*string // tag descriptions, by name
+	clients map[string]*pkg.Client
+}
+
+func newGrouper(doc *v3.Document, root *node, grouping config.Grouping) *grouper {
+	g := &grouper{
+		root:    root,
+		byTag:   grouping.By == config.GroupByTag,
+		nested:  grouping.Nested,
+		clients: make(map[string]*pkg.Client),
+		tags:    make(map[string]*string),
+	}
+
+	if prefix := strings.Trim(grouping.Prefix, "/"); prefix != "" {
+		g.prefix = tokenize("/" + prefix)
+	}
+
+	for _, t := range doc.Tags {
+		g.tags[t.Name] = t.Description
+	}
+
+	return g
+}
+
+// segment returns the index of the token in path that names its client when
+// grouped by path: the first segment after the prefix, if the path has it.
+func (g *grouper) segment(path []token) int {
+	n := len(g.prefix)
+	if n == 0 || len(path) < n+3 || path[n+1] != literal("/") {
+		return 2
+	}
+
+	for i, t := range g.prefix {
+		if path[i+1] != t {
+			return 2
+		}
+	}
+
+	return n + 2
+}
+
+// client returns the client for the operation o at path, and the index of
+// the token in path that its method names are built from, after the part of
+// the path implied by the client. The client is nil for paths without a
+// segment to group them by.
+//
+// When nested, resources under another resource, such as repos in
+// /orgs/{org}/repos/{repo}, are given their own client, held by the client
+// of the resource above them.
+func (g *grouper) client(path []token, o *v3.Operation) (*pkg.Client, int) {
+	c, start := g.top(path, o)
+	if c == nil || !g.nested {
+		return c, start
+	}
+
+	for i := start; i < len(path); i++ {
+		if !g.isResource(path, i) {
+			continue
+		}
+
+		base := docPath(path[1 : i+1])
+		field := formatID(path[i].value())
+		parent := c
+		c = g.define(strings.TrimSuffix(parent.Name, "Client")+field+"Client", path[i].value())
+		c.Parent, c.Field = parent.Name, field
+		if c.Comment == "" {
+			c.Comment = fmt.Sprintf("%s provides access to the %s APIs", c.Name, base)
+		}
+		start = i + 1
+	}
+
+	return c, start
+}
+
+// isResource reports if the token at index i of path is a literal that
+// follows a param, with more of the path below it, as repos does in
+// /orgs/{org}/repos/{repo}.
+func (g *grouper) isResource(path []token, i int) bool {
+	if i < 3 || path[i-1] != literal("/") {
+		return false
+	}
+	if _, ok := path[i].(literal); !ok || path[i] == literal("/") {
+		return false
+	}
+	if _, ok := path[i-2].(param); !ok {
+		return false
+	}
+
+	n := g.root.find(path[1 : i+1])
+	return n != nil && len(n.literals)+len(n.params) > 0
+}
+
+// top returns the top level client for the operation o at path, as client
+// does.
+func (g *grouper) top(path []token, o *v3.Operation) (*pkg.Client, int) {
+	seg := g.segment(path)
+	if len(path) <= seg {
+		return nil, 0
+	}
+
+	if g.byTag && len(o.Tags) > 0 {
+		tag := o.Tags[0]
+		c := g.define(formatID(tag, "Client"), formatVar(tag))
+		c.Comment = fmt.Sprintf("%s provides access to the %s APIs", c.Name, tag)
+		if desc := g.tags[tag]; desc != nil {
+			c.Comment += "\n\n" + *desc
+		}
+
+		// Paths under the tag's own segment are named as if grouped by path.
+		if formatID(path[seg].value()) == formatID(tag) {
+			return c, seg + 1
+		}
+		return c, seg
+	}
+
+	base := docPath(path[1 : seg+1])
+	name := path[seg].value()
+	c := g.define(formatID(name, "Client"), name)
+	if c.Comment == "" {
+		c.Comment = fmt.Sprintf("%s provides access to the %s APIs", c.Name, base)
+	}
+
+	return c, seg + 1
+}
+
+// define returns the client called name, defining it if it is new.
+func (g *grouper) define(name, context string) *pkg.Client {
+	c, ok := g.clients[name]
+	if !ok {
+		c = &pkg.Client{Name: name, ContextName: context}
+		g.clients[name] = c
+	}
+
+	return c
+}
+
+// docPath formats the path of tokens for documentation, ie /orgs/:org.
+func docPath(tokens []token) string {
+	var path string
+	for _, t := range tokens {
+		if p, ok := t.(param); ok {
+			path += ":" + string(p)
+		} else {
+			path += t.value()
+		}
+	}
+
+	return path
+}
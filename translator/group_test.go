@@ -0,0 +1,117 @@
+package translator
+
+import (
+	"testing"
+
+	"github.com/jbowes/oag/config"
+	"github.com/jbowes/oag/openapi/v3"
+)
+
+func TestGrouperClient(t *testing.T) {
+	desc := "Everything about your pets"
+	doc := &v3.Document{Tags: []v3.Tag{{Name: "pets", Description: &desc}}}
+
+	tcs := []struct {
+		name    string
+		by      string
+		prefix  string
+		path    string
+		tags    []string
+		client  string
+		comment string
+		start   string // the token method names start from
+	}{
+		{"path", config.GroupByPath, "", "/pets/{id}", nil,
+			"PetsClient", "PetsClient provides access to the /pets APIs", "/"},
+		{"path root", config.GroupByPath, "", "/", nil, "", "", ""},
+		{"prefix", config.GroupByPath, "/v1/", "/v1/pets/{id}", nil,
+			"PetsClient", "PetsClient provides access to the /v1/pets APIs", "/"},
+		{"prefix only", config.GroupByPath, "v1", "/v1", nil, "V1Client", "V1Client provides access to the /v1 APIs", ""},
+		{"outside prefix", config.GroupByPath, "/v1", "/v10/pets", nil,
+			"V10Client", "V10Client provides access to the /v10 APIs", "/"},
+		{"tag ignored", config.GroupByPath, "", "/pets", []string{"store"},
+			"PetsClient", "PetsClient provides access to the /pets APIs", ""},
+		{"tag", config.GroupByTag, "", "/pets/{id}", []string{"pets", "store"},
+			"PetsClient", "PetsClient provides access to the pets APIs\n\nEverything about your pets", "/"},
+		{"other tag", config.GroupByTag, "/api", "/api/animals/{id}", []string{"pet store"},
+			"PetStoreClient", "PetStoreClient provides access to the pet store APIs", "animals"},
+		{"untagged", config.GroupByTag, "", "/pets", nil,
+			"PetsClient", "PetsClient provides access to the /pets APIs", ""},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newGrouper(doc, &node{}, config.Grouping{By: tc.by, Prefix: tc.prefix})
+			path := append([]token{nil}, tokenize(tc.path)...)
+
+			c, start := g.client(path, &v3.Operation{Tags: tc.tags})
+			if c == nil {
+				if tc.client != "" {
+					t.Fatal("got no client, expected:", tc.client)
+				}
+				return
+			}
+
+			if c.Name != tc.client || c.Comment != tc.comment {
+				t.Errorf("got: %q %q expected: %q %q", c.Name, c.Comment, tc.client, tc.comment)
+			}
+
+			got := ""
+			if start < len(path) {
+				got = path[start].value()
+			}
+			if got != tc.start {
+				t.Errorf("got start: %q expected: %q", got, tc.start)
+			}
+		})
+	}
+}
+
+func TestGrouperNested(t *testing.T) {
+	root := &node{}
+	for _, p := range []string{
+		"/orgs/{org}",
+		"/orgs/{org}/repos",
+		"/orgs/{org}/repos/{repo}",
+		"/orgs/{org}/repos/{repo}/topics",
+		"/orgs/{org}/repos/{repo}/issues/{number}",
+		"/store/orders/{id}",
+	} {
+		root.add(p, &v3.PathItem{})
+	}
+
+	tcs := []struct {
+		path   string
+		client string
+		parent string
+		field  string
+		start  string
+	}{
+		{"/orgs/{org}", "OrgsClient", "", "", "/"},
+		{"/orgs/{org}/repos", "OrgsReposClient", "OrgsClient", "Repos", ""},
+		{"/orgs/{org}/repos/{repo}", "OrgsReposClient", "OrgsClient", "Repos", "/"},
+		{"/orgs/{org}/repos/{repo}/topics", "OrgsReposClient", "OrgsClient", "Repos", "/"},
+		{"/orgs/{org}/repos/{repo}/issues/{number}", "OrgsReposIssuesClient", "OrgsReposClient", "Issues", "/"},
+		{"/store/orders/{id}", "StoreClient", "", "", "/"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.path, func(t *testing.T) {
+			g := newGrouper(&v3.Document{}, root, config.Grouping{Nested: true})
+			path := append([]token{nil}, tokenize(tc.path)...)
+
+			c, start := g.client(path, &v3.Operation{})
+			if c.Name != tc.client || c.Parent != tc.parent || c.Field != tc.field {
+				t.Errorf("got: %s %s.%s expected: %s %s.%s", c.Name, c.Parent, c.Field, tc.client, tc.parent, tc.field)
+			}
+
+			got := ""
+			if start < len(path) {
+				got = path[start].value()
+			}
+			if got != tc.start {
+				t.Errorf("got start: %q expected: %q", got, tc.start)
+			}
+		})
+	}
+}
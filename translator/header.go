@@ -0,0 +1,140 @@
+package translator
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/jbowes/oag/pkg"
+)
+
+// convertResponseMeta converts the headers of the success responses in resp
+// into a struct holding their parsed values, declared as
+// typePrefix+"ResponseMeta", and returns its type. Without any success
+// responses, the headers of the default response are used. nil is returned
+// if there are no headers.
+func convertResponseMeta(tr *typeRegistry, doc *v3.Document, typePrefix string, resp *v3.Responses) pkg.Type {
+	name := typePrefix + "ResponseMeta"
+
+	var codes []int
+	for code := range resp.Codes {
+		if code >= 200 && code < 300 {
+			codes = append(codes, code)
+		}
+	}
+	sort.Ints(codes)
+
+	responses := make(map[string]v3.Response) // by location
+	var at []string
+	for _, code := range codes {
+		at = append(at, strconv.Itoa(code))
+		responses[at[len(at)-1]] = resp.Codes[code]
+	}
+	if len(codes) == 0 && resp.Default != nil {
+		at = append(at, "default")
+		responses["default"] = *resp.Default
+	}
+
+	var fields []pkg.Field
+	seen := make(map[string]bool)
+	for _, loc := range at {
+		r, err := resolveResponse(doc, responses[loc])
+		if err != nil {
+			continue // reported with the responses
+		}
+
+		var names []string
+		for hn := range r.Headers {
+			names = append(names, hn)
+		}
+		sort.Strings(names)
+
+		for _, hn := range names {
+			key := http.CanonicalHeaderKey(hn)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			leave := tr.enter(loc, "headers", hn)
+			if f := convertHeader(tr, doc, name, hn, r.Headers[hn]); f != nil {
+				fields = append(fields, *f)
+			}
+			leave()
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	tr.add(pkg.TypeDecl{
+		Name:    name,
+		Comment: name + " holds the headers of a successful response.",
+		Type:    &pkg.StructType{Fields: fields},
+	})
+
+	return &pkg.PointerType{Type: &pkg.IdentType{Name: name}}
+}
+
+// convertHeader converts the response header h, named hn, into an optional
+// field of the struct called name. Only headers with primitive values, or
+// comma separated lists of them, are supported. Others are skipped.
+func convertHeader(tr *typeRegistry, doc *v3.Document, name, hn string, h v3.Header) *pkg.Field {
+	if h.Reference != "" {
+		rh, err := doc.ResolveHeader(h.Reference)
+		if err != nil {
+			tr.errorf("%s", err)
+			return nil
+		}
+		h = *rh
+	}
+
+	if !primitiveHeader(doc, h.Schema, true) {
+		tr.warnf("header %s is not a primitive value or list, and is skipped", hn)
+		return nil
+	}
+
+	leave := tr.enter("schema")
+	typ := tr.convertSchema(h.Schema, &pkg.TypeDecl{Name: name + formatID(hn)}, false)
+	leave()
+	if typ == nil {
+		return nil
+	}
+
+	f := &pkg.Field{
+		ID:   formatID(hn),
+		Orig: hn,
+		Kind: pkg.Header,
+		Type: typ,
+	}
+	if _, ok := typ.(*pkg.SliceType); !ok {
+		f.Type = &pkg.PointerType{Type: typ}
+	}
+	if h.Description != nil {
+		f.Comment = *h.Description
+	}
+
+	return f
+}
+
+// primitiveHeader reports if the schema s of a header holds a primitive
+// value, or a list of them when list is set.
+func primitiveHeader(doc *v3.Document, s v3.Schema, list bool) bool {
+	if rs, ok := s.(*v3.ReferenceSchema); ok {
+		var err error
+		if s, err = doc.ResolveSchema(rs.Reference); err != nil {
+			return true // reported when the schema is converted
+		}
+	}
+
+	switch t := s.(type) {
+	case *v3.StringSchema, *v3.IntegerSchema, *v3.NumberSchema, *v3.BooleanSchema:
+		return true
+	case *v3.ArraySchema:
+		return list && primitiveHeader(doc, t.Items, false)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,91 @@
+package translator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestConvertResponseMeta(t *testing.T) {
+	desc := "version of the resource"
+	etag := v3.Header{Description: &desc, Schema: &v3.StringSchema{}}
+	limit := v3.Header{Schema: &v3.IntegerSchema{}}
+	tags := v3.Header{Schema: &v3.ArraySchema{Items: &v3.StringSchema{}}}
+	nested := v3.Header{Schema: &v3.ObjectSchema{}}
+
+	etagField := pkg.Field{
+		ID:      "ETag",
+		Orig:    "ETag",
+		Kind:    pkg.Header,
+		Type:    &pkg.PointerType{Type: &pkg.IdentType{Name: "string"}},
+		Comment: "version of the resource",
+	}
+
+	tcs := []struct {
+		name   string
+		resp   v3.Responses
+		fields []pkg.Field
+		warns  bool
+	}{
+		{"no headers", v3.Responses{Codes: map[int]v3.Response{200: {}}}, nil, false},
+		{"success headers",
+			v3.Responses{Codes: map[int]v3.Response{
+				200: {Headers: map[string]v3.Header{"ETag": etag, "X-Tags": tags}},
+				201: {Headers: map[string]v3.Header{"etag": etag, "X-RateLimit-Remaining": limit}},
+				404: {Headers: map[string]v3.Header{"X-Error": etag}},
+			}},
+			[]pkg.Field{
+				etagField,
+				{ID: "XTags", Orig: "X-Tags", Kind: pkg.Header, Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "string"}}},
+				{ID: "XRateLimitRemaining", Orig: "X-RateLimit-Remaining", Kind: pkg.Header, Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "int"}}},
+			},
+			false,
+		},
+		{"default success",
+			v3.Responses{Default: &v3.Response{Headers: map[string]v3.Header{"ETag": etag}}},
+			[]pkg.Field{etagField},
+			false,
+		},
+		{"default error",
+			v3.Responses{
+				Codes:   map[int]v3.Response{204: {}},
+				Default: &v3.Response{Headers: map[string]v3.Header{"ETag": etag}},
+			},
+			nil,
+			false,
+		},
+		{"unsupported",
+			v3.Responses{Codes: map[int]v3.Response{200: {Headers: map[string]v3.Header{"X-Nested": nested}}}},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := &typeRegistry{}
+			meta := convertResponseMeta(tr, &v3.Document{}, "Get", &tc.resp)
+
+			if len(tc.fields) == 0 {
+				if meta != nil {
+					t.Error("got:", meta, "expected no meta")
+				}
+			} else {
+				expected := []pkg.TypeDecl{{
+					Name:    "GetResponseMeta",
+					Comment: "GetResponseMeta holds the headers of a successful response.",
+					Type:    &pkg.StructType{Fields: tc.fields},
+				}}
+				if !reflect.DeepEqual(tr.types, expected) {
+					t.Error("got:", tr.types, "expected:", expected)
+				}
+			}
+
+			if warns := len(tr.diags) > 0; warns != tc.warns {
+				t.Error("got diagnostics:", tr.diags, "expected:", tc.warns)
+			}
+		})
+	}
+}
@@ -2,25 +2,115 @@ package translator
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
 
-	"github.com/jbowes/oag/openapi/v2"
+	"github.com/jbowes/oag/diag"
+	"github.com/jbowes/oag/openapi/jsonpointer"
+	"github.com/jbowes/oag/openapi/v3"
 	"github.com/jbowes/oag/pkg"
 )
 
 type typeRegistry struct {
-	strFmt stringFormat
-	types  []pkg.TypeDecl
+	doc       *v3.Document
+	strFmt    stringFormat
+	numFmt    numberFormat
+	date      string // name of the civil date type
+	naming    string // how methods are named, config.NamingPath or config.NamingOperationID
+	headers   bool   // return response headers from methods
+	listField string // property holding the items of wrapped lists, or "" to detect it
+	types     []pkg.TypeDecl
+	consts    []pkg.ConstDecl
+
+	resolving map[string]struct{} // names of referenced schemas being declared
+	patterns  map[string]bool     // pointers to patterns reported as unsupported
+
+	polymorphic map[string]*pkg.Discriminator // interface types, by name
+	dateUsed    bool
+
+	diags diag.List
+	path  []string // reference tokens of the part of doc being translated
 }
 
+// enter descends into the document by the given reference tokens, for
+// reporting diagnostics. The returned func returns to the previous location.
+func (tr *typeRegistry) enter(tokens ...string) func() {
+	n := len(tr.path)
+	tr.path = append(tr.path, tokens...)
+	return func() { tr.path = tr.path[:n] }
+}
+
+// at moves to the location referenced by tokens, until the returned func is
+// called.
+func (tr *typeRegistry) at(tokens []string) func() {
+	prev := tr.path
+	tr.path = append([]string(nil), tokens...)
+	return func() { tr.path = prev }
+}
+
+func (tr *typeRegistry) pointer() string {
+	return "#" + jsonpointer.Format(tr.path...)
+}
+
+// errorf reports an error at the current location in the document.
+func (tr *typeRegistry) errorf(format string, args ...interface{}) {
+	tr.diags.Errorf(tr.pointer(), format, args...)
+}
+
+// warnf reports a warning at the current location in the document.
+func (tr *typeRegistry) warnf(format string, args ...interface{}) {
+	tr.diags.Warnf(tr.pointer(), format, args...)
+}
+
+// add adds a type declaration, if one with the same name has not already been
+// added.
 func (tr *typeRegistry) add(td pkg.TypeDecl) {
+	if tr.declared(td.Name) {
+		return
+	}
+
 	tr.types = append(tr.types, td)
 }
 
-func (tr *typeRegistry) convertSchema(schema v2.Schema, td *pkg.TypeDecl, declAll bool) pkg.Type {
+func (tr *typeRegistry) declared(name string) bool {
+	for _, td := range tr.types {
+		if td.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// declaredSchema reports if a type declared as name holds a schema, rather
+// than the optional arguments of an operation, whose fields are all query or
+// form values.
+func (tr *typeRegistry) declaredSchema(name string) bool {
+	for _, td := range tr.types {
+		if td.Name != name {
+			continue
+		}
+
+		st, ok := td.Type.(*pkg.StructType)
+		if !ok {
+			return true
+		}
+		for _, f := range st.Fields {
+			if f.Kind != pkg.Query && f.Kind != pkg.Form {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (tr *typeRegistry) convertSchema(schema v3.Schema, td *pkg.TypeDecl, declAll bool) pkg.Type {
 	var ret pkg.Type
 	switch s := schema.(type) {
-	case *v2.ObjectSchema:
+	case *v3.ObjectSchema:
 		t := &pkg.StructType{}
 		if s.Properties == nil {
 			if !s.AnyAdditionalProperties && s.AdditionalProperties == nil {
@@ -33,13 +123,15 @@ func (tr *typeRegistry) convertSchema(schema v2.Schema, td *pkg.TypeDecl, declAl
 			if s.AnyAdditionalProperties {
 				mt.Value = &pkg.InterfaceType{}
 			} else {
+				leave := tr.enter("additionalProperties")
 				mt.Value = tr.convertSchema(s.AdditionalProperties, &pkg.TypeDecl{
 					Name: td.Name + "Value"}, false)
+				leave()
 			}
 
 			if td != nil {
 				td.Type = mt
-				tr.types = append(tr.types, *td)
+				tr.add(*td)
 			}
 
 			return &pkg.IdentType{Name: td.Name}
@@ -71,52 +163,97 @@ func (tr *typeRegistry) convertSchema(schema v2.Schema, td *pkg.TypeDecl, declAl
 			field.Comment = fieldComment
 
 			sn := td.Name + formatID(prop.Name)
-			field.Type = tr.convertSchema(prop.Schema, &pkg.TypeDecl{
+			schema, nullable := nonNull(prop.Schema)
+			leave := tr.enter("properties", prop.Name)
+			field.Type = tr.convertSchema(schema, &pkg.TypeDecl{
 				Name:    sn,
 				Comment: fmt.Sprintf("%s is a data type for API communication.", sn),
 			}, false)
+			field.Constraints = tr.constraintsFor(schema)
+			leave()
 
-			if _, ok := required[prop.Name]; !ok {
-				field.Type = &pkg.PointerType{Type: field.Type}
-				if field.Comment == "" {
-					field.Comment = "Optional"
+			_, ok := required[prop.Name]
+			if ok && !nullable && tr.nilable(schema) {
+				if field.Constraints == nil {
+					field.Constraints = &pkg.Constraints{}
 				}
+				field.Constraints.Required = true
+			}
+			if (!ok || nullable) && !tr.isInterface(field.Type) {
+				field.Type = &pkg.PointerType{Type: field.Type}
+			}
+			if !ok && field.Comment == "" {
+				field.Comment = "Optional"
 			}
 
 			t.Fields = append(t.Fields, field)
 		}
 		if td != nil {
 			td.Type = t
-			tr.types = append(tr.types, *td)
+			tr.add(*td)
 		}
 
 		return &pkg.IdentType{Name: td.Name}
-	case *v2.StringSchema:
-		ret = tr.strFmt.typeFor(s.Format)
-	case *v2.IntegerSchema:
-		ret = &pkg.IdentType{Name: "int"}
-	case *v2.NumberSchema:
-		ret = &pkg.IdentType{Name: "float64"}
-	case *v2.BooleanSchema:
+	case *v3.StringSchema:
+		ret = tr.stringType(s.Format)
+	case *v3.IntegerSchema:
+		ret = tr.numFmt.typeFor(s.Format, "int")
+	case *v3.NumberSchema:
+		ret = tr.numFmt.typeFor(s.Format, "float64")
+	case *v3.BooleanSchema:
 		ret = &pkg.IdentType{Name: "bool"}
-	case *v2.ReferenceSchema:
-		parts := strings.Split(s.Reference, "/")
-		ret = &pkg.IdentType{Name: formatID(parts[len(parts)-1])}
-	case *v2.ArraySchema:
-		ret = &pkg.SliceType{Type: tr.convertSchema(s.Items, &pkg.TypeDecl{
+	case *v3.ReferenceSchema:
+		ret = tr.refType(s.Reference)
+	case *v3.ArraySchema:
+		if s.PrefixItems != nil || s.Items == nil {
+			// XXX tuples have no direct go equivalent
+			ret = &pkg.SliceType{Type: &pkg.InterfaceType{}}
+			break
+		}
+
+		items, nullable := nonNull(s.Items)
+		leave := tr.enter("items")
+		var it pkg.Type = tr.convertSchema(items, &pkg.TypeDecl{
 			Name: td.Name + "Items",
-		}, false)}
-	case *v2.AllOfSchema:
+		}, false)
+		leave()
+		if nullable && !tr.isInterface(it) {
+			it = &pkg.PointerType{Type: it}
+		}
+		ret = &pkg.SliceType{Type: it}
+	case *v3.OneOfSchema:
+		if inner, _ := nonNull(s); inner != s {
+			return tr.convertSchema(inner, td, declAll)
+		}
+
+		return tr.convertUnion("oneOf", s.OneOf, s.Discriminator, td)
+	case *v3.AnyOfSchema:
+		if inner, _ := nonNull(s); inner != s {
+			return tr.convertSchema(inner, td, declAll)
+		}
+
+		return tr.convertUnion("anyOf", s.AnyOf, s.Discriminator, td)
+	case *v3.NullSchema:
+		ret = &pkg.InterfaceType{}
+	case *v3.AllOfSchema:
 		fields := make([]pkg.Field, len(s.AllOf))
 
 		for i := range s.AllOf {
 			sn := fmt.Sprintf("%sAllOf%d", td.Name, i)
+			leave := tr.enter("allOf", strconv.Itoa(i))
 			field := pkg.Field{
 				Type: tr.convertSchema(s.AllOf[i], &pkg.TypeDecl{
 					Name:    sn,
 					Comment: fmt.Sprintf("%s is a data type for API communication.", sn),
 				}, false),
 			}
+			leave()
+
+			// Derived schemas embed the fields of a polymorphic base, rather
+			// than the interface.
+			if tr.isInterface(field.Type) {
+				field.Type = &pkg.IdentType{Name: tr.polymorphic[field.Type.(*pkg.IdentType).Name].Base}
+			}
 
 			fields[i] = field
 		}
@@ -127,99 +264,677 @@ func (tr *typeRegistry) convertSchema(schema v2.Schema, td *pkg.TypeDecl, declAl
 
 		if td != nil {
 			td.Type = t
-			tr.types = append(tr.types, *td)
+			tr.add(*td)
 		}
 
 		return &pkg.IdentType{Name: td.Name}
+	case nil:
+		tr.errorf("missing schema")
+		return &pkg.InterfaceType{}
 	default:
-		// XXX handle this
-		panic("unknown type")
+		tr.errorf("unsupported schema %s", schemaKind(s))
+		return &pkg.InterfaceType{}
+	}
+
+	declare := td != nil && declAll
+	if values := enumValues(schema); td != nil && values != nil {
+		if et, ok := tr.enumType(*td, ret, values); ok {
+			ret, declare = et, false
+		}
 	}
 
-	if td != nil && declAll {
+	if td != nil && schema.GetConst() != nil {
+		tr.addConst(td.Name, ret, schema.GetConst())
+	}
+
+	if declare {
 		td.Type = ret
-		tr.types = append(tr.types, *td)
+		tr.add(*td)
 	}
 
 	return ret
 }
 
-func (tr *typeRegistry) typeForParameter(p v2.Parameter) (pkg.Type, pkg.Collection) {
-	switch t := p.(type) {
-	case *v2.StringParameter:
-		return tr.strFmt.typeFor(t.Format), pkg.None
-	case *v2.NumberParameter:
-		return &pkg.IdentType{Name: "float64"}, pkg.None
-	case *v2.IntegerParameter:
-		return &pkg.IdentType{Name: "int"}, pkg.None
-	case *v2.BooleanParameter:
-		return &pkg.IdentType{Name: "bool"}, pkg.None
-	case *v2.ArrayParameter:
-		cf := "csv" // the default
-		if t.CollectionFormat != nil {
-			cf = *t.CollectionFormat
-		}
-
-		var collection pkg.Collection
-		switch cf {
-		case "csv":
-			collection = pkg.CSV
-		case "ssv":
-			collection = pkg.SSV
-		case "tsv":
-			collection = pkg.TSV
-		case "pipes":
-			collection = pkg.Pipes
-		case "multi":
-			collection = pkg.Multi
+// enumValues returns the values the string or number schema s is limited to,
+// or nil if it has none.
+func enumValues(s v3.Schema) []interface{} {
+	var values []interface{}
+	switch t := s.(type) {
+	case *v3.StringSchema:
+		if t.Enum != nil {
+			for _, v := range *t.Enum {
+				values = append(values, v)
+			}
+		}
+	case *v3.IntegerSchema:
+		if t.Enum != nil {
+			for _, v := range *t.Enum {
+				values = append(values, int(v))
+			}
+		}
+	case *v3.NumberSchema:
+		if t.Enum != nil {
+			for _, v := range *t.Enum {
+				values = append(values, v)
+			}
+		}
+	}
+
+	return values
+}
+
+// enumType declares td as a named type of base, limited to values, with a
+// constant for each. ok is false if base can't have constants, such as a
+// string format mapped to another type.
+func (tr *typeRegistry) enumType(td pkg.TypeDecl, base pkg.Type, values []interface{}) (t pkg.Type, ok bool) {
+	if it, ok := base.(*pkg.IdentType); !ok || it.Qualifier != "" {
+		return base, false
+	}
+
+	t = &pkg.IdentType{Name: td.Name}
+	if td.Comment == "" {
+		td.Comment = fmt.Sprintf("%s is a data type for API communication.", td.Name)
+	}
+	td.Type = base
+	td.Enum = enumConsts(td.Name, t, values)
+	tr.add(td)
+
+	return t, true
+}
+
+// enumConsts returns a constant for each distinct value of the enum type
+// name, named after the type and the value, ie PetStatusAvailable. Values
+// that don't make a unique name are numbered instead, ie PetStatusValue3.
+func enumConsts(name string, typ pkg.Type, values []interface{}) []pkg.ConstDecl {
+	var consts []pkg.ConstDecl
+	seen := make(map[interface{}]bool, len(values))
+	names := make(map[string]bool, len(values))
+	for i, v := range values {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+
+		id := enumValueID(v)
+		cn := name + id
+		if id == "" || names[cn] {
+			cn = fmt.Sprintf("%sValue%d", name, i)
+		}
+		names[cn] = true
+
+		consts = append(consts, pkg.ConstDecl{Name: cn, Type: typ, Value: v})
+	}
+
+	return consts
+}
+
+// enumValueID formats an enum value for use in an identifier.
+func enumValueID(v interface{}) string {
+	var s string
+	switch t := v.(type) {
+	case string:
+		if t == "" {
+			return "Empty"
+		}
+		s = t
+	case int:
+		s = strconv.Itoa(t)
+	case float64:
+		s = strconv.FormatFloat(t, 'f', -1, 64)
+	}
+
+	if strings.HasPrefix(s, "-") {
+		s = "minus_" + s[1:]
+	}
+
+	return formatID(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, s))
+}
+
+// constraintsFor returns the limits schema s places on its values, or nil if
+// it has none. The limits of referenced schemas are included, unless they are
+// declared as structs, as other types are inlined.
+func (tr *typeRegistry) constraintsFor(s v3.Schema) *pkg.Constraints {
+	return tr.constraints(s, make(map[string]bool))
+}
+
+func (tr *typeRegistry) constraints(s v3.Schema, seen map[string]bool) *pkg.Constraints {
+	var c pkg.Constraints
+	switch t := s.(type) {
+	case *v3.StringSchema:
+		c.MinLength, c.MaxLength = t.MinLength, t.MaxLength
+		if t.Pattern != nil {
+			if _, err := regexp.Compile(*t.Pattern); err == nil {
+				c.Pattern = t.Pattern
+			} else {
+				leave := tr.enter("pattern")
+				if p := tr.pointer(); !tr.patterns[p] {
+					if tr.patterns == nil {
+						tr.patterns = make(map[string]bool)
+					}
+					tr.patterns[p] = true
+					tr.warnf("pattern %q is not supported, and is not validated: %s", *t.Pattern, err)
+				}
+				leave()
+			}
+		}
+	case *v3.IntegerSchema:
+		c.Minimum, c.Maximum = intBound(t.Minimum), intBound(t.Maximum)
+		c.ExclusiveMinimum = t.ExclusiveMinimum && t.Minimum != nil
+		c.ExclusiveMaximum = t.ExclusiveMaximum && t.Maximum != nil
+		c.MultipleOf = intBound(t.MultipleOf)
+	case *v3.NumberSchema:
+		c.Minimum, c.Maximum = t.Minimum, t.Maximum
+		c.ExclusiveMinimum = t.ExclusiveMinimum && t.Minimum != nil
+		c.ExclusiveMaximum = t.ExclusiveMaximum && t.Maximum != nil
+		c.MultipleOf = t.MultipleOf
+	case *v3.ArraySchema:
+		c.MinItems, c.MaxItems = t.MinItems, t.MaxItems
+		if t.PrefixItems == nil && t.Items != nil {
+			items, _ := nonNull(t.Items)
+			leave := tr.enter("items")
+			c.Items = tr.constraints(items, seen)
+			leave()
+		}
+	case *v3.ObjectSchema:
+		if t.Properties == nil && t.AdditionalProperties != nil {
+			value, _ := nonNull(t.AdditionalProperties)
+			leave := tr.enter("additionalProperties")
+			c.Items = tr.constraints(value, seen)
+			leave()
+		}
+	case *v3.ReferenceSchema:
+		if tr.doc == nil || seen[t.Reference] {
+			return nil
+		}
+		seen[t.Reference] = true
+
+		rs, err := tr.doc.ResolveSchema(t.Reference)
+		if err != nil || isStruct(rs) {
+			return nil // unresolved references are reported with their type
+		}
+
+		if tokens, err := jsonpointer.Parse(t.Reference); err == nil {
+			defer tr.at(tokens)()
+		}
+		return tr.constraints(rs, seen)
+	}
+
+	if c == (pkg.Constraints{}) {
+		return nil
+	}
+
+	return &c
+}
+
+func intBound(i *int64) *float64 {
+	if i == nil {
+		return nil
+	}
+
+	f := float64(*i)
+	return &f
+}
+
+// nilable reports if values of schema s are represented by a slice or map,
+// which must not be nil when a value is required.
+func (tr *typeRegistry) nilable(s v3.Schema) bool {
+	for i := 0; i < 10; i++ { // follow references to references, within reason
+		switch t := s.(type) {
+		case *v3.ArraySchema:
+			return true
+		case *v3.ObjectSchema:
+			return t.Properties == nil && (t.AnyAdditionalProperties || t.AdditionalProperties != nil)
+		case *v3.ReferenceSchema:
+			if tr.doc == nil {
+				return false
+			}
+			rs, err := tr.doc.ResolveSchema(t.Reference)
+			if err != nil {
+				return false
+			}
+			s, _ = nonNull(rs)
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// isStruct reports if s is declared as a struct type.
+func isStruct(s v3.Schema) bool {
+	switch t := s.(type) {
+	case *v3.ObjectSchema:
+		return t.Properties != nil
+	case *v3.AllOfSchema:
+		return true
+	default:
+		return false
+	}
+}
+
+// refType returns the type for the schema referenced by ref. Schemas nested
+// within other schemas are declared as needed, named after their location.
+func (tr *typeRegistry) refType(ref string) pkg.Type {
+	tokens, err := jsonpointer.Parse(ref)
+	if err != nil {
+		tr.errorf("%s", err)
+		return &pkg.InterfaceType{}
+	}
+
+	name := pointerName(tokens)
+
+	// Schemas in components, and in $defs wherever they are nested, are
+	// declared by name. They only need to exist.
+	n := len(tokens)
+	if n == 3 && tokens[0] == "components" && tokens[1] == "schemas" ||
+		n >= 2 && (tokens[n-2] == "$defs" || tokens[n-2] == "definitions") {
+		if tr.doc != nil && !tr.exists(ref, tokens) {
+			tr.errorf("unresolved reference %s", ref)
+			return &pkg.InterfaceType{}
+		}
+		return &pkg.IdentType{Name: name}
+	}
+
+	if _, ok := tr.resolving[name]; ok || tr.declared(name) || tr.doc == nil {
+		return &pkg.IdentType{Name: name}
+	}
+
+	s, err := tr.doc.ResolveSchema(ref)
+	if err != nil {
+		tr.errorf("%s", err)
+		return &pkg.InterfaceType{}
+	}
+
+	if tr.resolving == nil {
+		tr.resolving = make(map[string]struct{})
+	}
+	tr.resolving[name] = struct{}{}
+	defer delete(tr.resolving, name)
+	defer tr.at(tokens)()
+
+	tr.convertSchema(s, &pkg.TypeDecl{
+		Name:    name,
+		Comment: fmt.Sprintf("%s is a data type for API communication.", name),
+	}, true)
+
+	return &pkg.IdentType{Name: name}
+}
+
+// exists reports if the schema referenced by ref, with the given tokens, is
+// in the document. Schemas in $defs are declared by name wherever they are
+// nested, so may be referenced relative to the schema holding them too.
+func (tr *typeRegistry) exists(ref string, tokens []string) bool {
+	if _, err := tr.doc.ResolveSchema(ref); err == nil {
+		return true
+	}
+
+	c := tr.doc.Components
+	if tokens[len(tokens)-2] != "$defs" || c == nil || c.Schemas == nil {
+		return false
+	}
+
+	name := tokens[len(tokens)-1]
+	var found func(defs *v3.SchemaMap) bool
+	found = func(defs *v3.SchemaMap) bool {
+		if defs == nil {
+			return false
+		}
+		for _, d := range *defs {
+			if d.Name == name || found(d.Schema.GetDefs()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, def := range *c.Schemas {
+		if found(def.Schema.GetDefs()) {
+			return true
+		}
+	}
+	return false
+}
+
+// pointerName derives a type name from the tokens of a JSON pointer to a
+// schema. Names of nested schemas match those given by convertSchema, ie
+// properties/b of schema A is AB, and its items are ABItems.
+func pointerName(tokens []string) string {
+	id := func(tok string) string {
+		return formatID(strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return '_'
+		}, tok))
+	}
+
+	var parts []string
+
+	i := 0
+	if len(tokens) >= 3 && tokens[0] == "components" {
+		parts = append(parts, id(tokens[2]))
+		i = 3
+	}
+
+	for ; i < len(tokens); i++ {
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+
+		switch tok := tokens[i]; tok {
+		case "properties":
+			parts = append(parts, id(next))
+			i++
+		case "items":
+			parts = append(parts, "Items")
+		case "prefixItems":
+			parts = append(parts, "Items"+next)
+			i++
+		case "additionalProperties":
+			parts = append(parts, "Value")
+		case "allOf", "oneOf", "anyOf":
+			parts = append(parts, id(tok)+next)
+			i++
+		case "not":
+			parts = append(parts, "Not")
+		case "$defs", "definitions":
+			parts = []string{id(next)}
+			i++
+		case "schema":
+		case "content":
+			i++ // skip the media type
 		default:
-			panic("unsupported collection format")
+			parts = append(parts, id(tok))
+		}
+	}
+
+	return strings.Join(parts, "")
+}
+
+// addConst declares the fixed value of the named schema as a constant, if it
+// can be represented as one.
+func (tr *typeRegistry) addConst(name string, typ pkg.Type, value interface{}) {
+	if it, ok := typ.(*pkg.IdentType); !ok || it.Qualifier != "" {
+		return
+	}
+
+	switch value.(type) {
+	case string, int, float64, bool:
+	default:
+		return // XXX objects and arrays can't be constants
+	}
+
+	cn := name + "Value"
+	tr.consts = append(tr.consts, pkg.ConstDecl{
+		Name:    cn,
+		Comment: fmt.Sprintf("%s is the fixed value of %s.", cn, name),
+		Type:    typ,
+		Value:   value,
+	})
+}
+
+// nonNull unwraps nullable unions, such as anyOf a schema and null, returning
+// the underlying schema and whether null is also allowed.
+func nonNull(s v3.Schema) (v3.Schema, bool) {
+	var variants []v3.Schema
+	switch t := s.(type) {
+	case nil:
+		return nil, false
+	case *v3.OneOfSchema:
+		variants = t.OneOf
+	case *v3.AnyOfSchema:
+		variants = t.AnyOf
+	default:
+		return s, s.IsNullable()
+	}
+
+	nullable := s.IsNullable()
+	var rest []v3.Schema
+	for _, v := range variants {
+		if _, ok := v.(*v3.NullSchema); ok {
+			nullable = true
+			continue
+		}
+		rest = append(rest, v)
+	}
+
+	if len(rest) != 1 {
+		return s, nullable
+	}
+
+	return rest[0], nullable || rest[0].IsNullable()
+}
+
+// typeForParameter returns the type of the parameter p, and how it is
+// serialized if it is an array. Parameters limited to a set of values are
+// declared as the type name.
+func (tr *typeRegistry) typeForParameter(p *v3.Parameter, name string) (pkg.Type, pkg.Collection) {
+	switch t := p.Schema.(type) {
+	case *v3.ArraySchema:
+		cf, ok := collectionFor(p)
+		if !ok {
+			tr.errorf("unsupported style %s for %s parameter %s", *p.Style, p.In, p.Name)
+		}
+		return &pkg.SliceType{Type: tr.scalarItems(t.Items, name)}, cf
+	default:
+		return tr.convertItems(t, name), pkg.None
+	}
+}
+
+// scalarItems converts the items of an array parameter, which must be single
+// values to be serialized.
+func (tr *typeRegistry) scalarItems(i v3.Schema, name string) pkg.Type {
+	typ := tr.convertItems(i, name)
+	if st, ok := typ.(*pkg.SliceType); ok {
+		if it, ok := st.Type.(*pkg.IdentType); !ok || it.Name != "byte" {
+			tr.errorf("unsupported nested array parameter")
+		}
+	}
+
+	return typ
+}
+
+// collectionFor determines how an array parameter is serialized, based on its
+// style and explode values, with defaults according to
+// https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.3.md#style-values
+// ok is false if the style is not supported.
+func collectionFor(p *v3.Parameter) (cf pkg.Collection, ok bool) {
+	style := "simple"
+	if p.In == "query" || p.In == "cookie" {
+		style = "form"
+	}
+	if p.Style != nil {
+		style = *p.Style
+	}
+
+	explode := style == "form"
+	if p.Explode != nil {
+		explode = *p.Explode
+	}
+
+	switch style {
+	case "form":
+		if explode {
+			return pkg.Multi, true
 		}
-		return &pkg.SliceType{Type: tr.convertItems(t.Items)}, collection
+		return pkg.CSV, true
+	case "simple":
+		return pkg.CSV, true
+	case "spaceDelimited":
+		return pkg.SSV, true
+	case "tabDelimited": // not part of OpenAPI 3, but converted from 2.0's tsv
+		return pkg.TSV, true
+	case "pipeDelimited":
+		return pkg.Pipes, true
 	default:
-		panic("unhandled parameter type")
+		return pkg.None, false
 	}
 }
 
-func (tr *typeRegistry) convertItems(i v2.Items) pkg.Type {
+func (tr *typeRegistry) convertItems(i v3.Schema, name string) pkg.Type {
+	var ret pkg.Type
 	switch t := i.(type) {
-	case *v2.StringItem:
-		return tr.strFmt.typeFor(t.Format)
-	case *v2.NumberItem:
-		return &pkg.IdentType{Name: "float64"}
-	case *v2.IntegerItem:
-		return &pkg.IdentType{Name: "int"}
-	case *v2.BooleanItem:
+	case *v3.StringSchema:
+		ret = tr.stringType(t.Format)
+	case *v3.NumberSchema:
+		ret = tr.numFmt.typeFor(t.Format, "float64")
+	case *v3.IntegerSchema:
+		ret = tr.numFmt.typeFor(t.Format, "int")
+	case *v3.BooleanSchema:
 		return &pkg.IdentType{Name: "bool"}
-	case *v2.ArrayItem:
-		return &pkg.SliceType{Type: tr.convertItems(t.Items)}
+	case *v3.ArraySchema:
+		return &pkg.SliceType{Type: tr.scalarItems(t.Items, name)}
+	case *v3.ReferenceSchema:
+		// Only references to simple values, such as enums, can be
+		// serialized.
+		if tr.doc != nil {
+			if s, err := tr.doc.ResolveSchema(t.Reference); err == nil && isScalar(s) {
+				return tr.refType(t.Reference)
+			}
+		}
+		tr.errorf("unsupported parameter schema %s", schemaKind(t))
+		return &pkg.InterfaceType{}
+	case nil:
+		tr.errorf("missing schema")
+		return &pkg.InterfaceType{}
+	default:
+		tr.errorf("unsupported parameter schema %s", schemaKind(t))
+		return &pkg.InterfaceType{}
+	}
+
+	if values := enumValues(i); values != nil {
+		ret, _ = tr.enumType(pkg.TypeDecl{Name: name}, ret, values)
+	}
+
+	return ret
+}
+
+// isScalar reports if s is a string, number, or boolean schema.
+func isScalar(s v3.Schema) bool {
+	switch s.(type) {
+	case *v3.StringSchema, *v3.NumberSchema, *v3.IntegerSchema, *v3.BooleanSchema:
+		return true
 	default:
-		panic("unhandled item type")
+		return false
+	}
+}
+
+// schemaKind describes the kind of schema s is, for diagnostics.
+func schemaKind(s v3.Schema) string {
+	switch s.(type) {
+	case *v3.ObjectSchema:
+		return "object"
+	case *v3.ArraySchema:
+		return "array"
+	case *v3.AllOfSchema:
+		return "allOf"
+	case *v3.OneOfSchema:
+		return "oneOf"
+	case *v3.AnyOfSchema:
+		return "anyOf"
+	case *v3.NotSchema:
+		return "not"
+	case *v3.ReferenceSchema:
+		return "reference"
+	default:
+		return fmt.Sprintf("%T", s)
 	}
 }
 
 // indirect wraps a type in a pointer for use in parameters / return values,
 // if required.
 func (tr *typeRegistry) indirect(t pkg.Type) pkg.Type {
+	if tr.isInterface(t) {
+		return t
+	}
+
 	return &pkg.PointerType{Type: t}
 }
 
+// dateType is the default name of the civil date type, used for the date
+// format.
+const dateType = "Date"
+
+// stringType returns the type for strings of format f, noting if the civil
+// date type is used.
+func (tr *typeRegistry) stringType(f *string) pkg.Type {
+	t := tr.strFmt.typeFor(f)
+	if it, ok := t.(*pkg.IdentType); ok && it.Qualifier == "" && it.Name == dateType {
+		it.Name = tr.date
+		tr.dateUsed = true
+	}
+
+	return t
+}
+
 type stringFormat map[string]string
 
+// typeFor returns the configured type for strings of format fmt, or the
+// default type for the format.
 func (sf stringFormat) typeFor(fmt *string) pkg.Type {
 	if fmt == nil {
 		return &pkg.IdentType{Name: "string"}
 	}
 
 	if f, ok := sf[*fmt]; ok {
+		return configuredType(f)
+	}
 
-		idx := strings.LastIndex(f, ".")
-		return &pkg.IdentType{
-			Name:      f[idx+1:],
-			Qualifier: f[:idx],
-			Marshal:   true,
-		}
+	switch *fmt {
+	case "date-time":
+		return &pkg.IdentType{Qualifier: "time", Name: "Time", Marshal: true}
+	case "date":
+		return &pkg.IdentType{Name: dateType, Marshal: true}
+	case "byte":
+		return &pkg.SliceType{Type: &pkg.IdentType{Name: "byte"}}
 	}
 
 	return &pkg.IdentType{Name: "string"}
 }
+
+type numberFormat map[string]string
+
+// typeFor returns the configured type for integers or numbers of format fmt,
+// or the default type for the format, falling back to def.
+func (nf numberFormat) typeFor(fmt *string, def string) pkg.Type {
+	if fmt == nil {
+		return &pkg.IdentType{Name: def}
+	}
+
+	if f, ok := nf[*fmt]; ok {
+		return configuredType(f)
+	}
+
+	switch *fmt {
+	case "int32", "int64":
+		return &pkg.IdentType{Name: *fmt}
+	case "float":
+		return &pkg.IdentType{Name: "float32"}
+	case "double":
+		return &pkg.IdentType{Name: "float64"}
+	}
+
+	return &pkg.IdentType{Name: def}
+}
+
+// configuredType returns the type named by f, either a builtin type or a
+// qualified type, which must implement encoding.TextMarshaler.
+func configuredType(f string) pkg.Type {
+	idx := strings.LastIndex(f, ".")
+	if idx == -1 {
+		return &pkg.IdentType{Name: f}
+	}
+
+	return &pkg.IdentType{
+		Name:      f[idx+1:],
+		Qualifier: f[:idx],
+		Marshal:   true,
+	}
+}
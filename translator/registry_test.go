@@ -4,36 +4,38 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/jbowes/oag/openapi/v2"
+	"github.com/jbowes/oag/openapi/v3"
 	"github.com/jbowes/oag/pkg"
 )
 
 func TestConvertSchema(t *testing.T) {
+	maxCount, maxCountBound := int64(10), 10.0
+
 	tcs := []struct {
 		name string
-		in   v2.Schema
+		in   v3.Schema
 		td   *pkg.TypeDecl
 		out  pkg.Type
 		reg  []pkg.TypeDecl
 	}{
-		{"string", &v2.StringSchema{}, nil, &pkg.IdentType{Name: "string"}, nil},
-		{"int", &v2.IntegerSchema{}, nil, &pkg.IdentType{Name: "int"}, nil},
-		{"float64", &v2.NumberSchema{}, nil, &pkg.IdentType{Name: "float64"}, nil},
-		{"bool", &v2.BooleanSchema{}, nil, &pkg.IdentType{Name: "bool"}, nil},
-		{"reference", &v2.ReferenceSchema{Reference: "#/definitions/foo"}, nil,
+		{"string", &v3.StringSchema{}, nil, &pkg.IdentType{Name: "string"}, nil},
+		{"int", &v3.IntegerSchema{}, nil, &pkg.IdentType{Name: "int"}, nil},
+		{"float64", &v3.NumberSchema{}, nil, &pkg.IdentType{Name: "float64"}, nil},
+		{"bool", &v3.BooleanSchema{}, nil, &pkg.IdentType{Name: "bool"}, nil},
+		{"reference", &v3.ReferenceSchema{Reference: "#/components/schemas/foo"}, nil,
 			&pkg.IdentType{Name: "Foo"}, nil},
 		{
 			"array",
-			&v2.ArraySchema{Items: &v2.BooleanSchema{}},
+			&v3.ArraySchema{Items: &v3.BooleanSchema{}},
 			&pkg.TypeDecl{Name: "Foo"},
 			&pkg.SliceType{Type: &pkg.IdentType{Name: "bool"}},
 			nil,
 		},
 		{
 			"object",
-			&v2.ObjectSchema{
-				Properties: &v2.SchemaMap{
-					{Name: "field", Schema: &v2.StringSchema{}},
+			&v3.ObjectSchema{
+				Properties: &v3.SchemaMap{
+					{Name: "field", Schema: &v3.StringSchema{}},
 				},
 				Required: &[]string{"field"},
 			},
@@ -49,9 +51,9 @@ func TestConvertSchema(t *testing.T) {
 		},
 		{
 			"object optional field",
-			&v2.ObjectSchema{
-				Properties: &v2.SchemaMap{
-					{Name: "field", Schema: &v2.StringSchema{}},
+			&v3.ObjectSchema{
+				Properties: &v3.SchemaMap{
+					{Name: "field", Schema: &v3.StringSchema{}},
 				},
 			},
 			&pkg.TypeDecl{Name: "Foo"},
@@ -65,10 +67,36 @@ func TestConvertSchema(t *testing.T) {
 				}},
 			}}},
 		},
+		{
+			"object constrained fields",
+			&v3.ObjectSchema{
+				Properties: &v3.SchemaMap{
+					{Name: "tags", Schema: &v3.ArraySchema{Items: &v3.StringSchema{}}},
+					{Name: "count", Schema: &v3.IntegerSchema{IntegerFields: v3.IntegerFields{Maximum: &maxCount}}},
+				},
+				Required: &[]string{"tags"},
+			},
+			&pkg.TypeDecl{Name: "Foo"},
+			&pkg.IdentType{Name: "Foo"},
+			[]pkg.TypeDecl{{Name: "Foo", Type: &pkg.StructType{
+				Fields: []pkg.Field{{
+					ID:          "Tags",
+					Type:        &pkg.SliceType{Type: &pkg.IdentType{Name: "string"}},
+					Orig:        "tags",
+					Constraints: &pkg.Constraints{Required: true},
+				}, {
+					ID:          "Count",
+					Type:        &pkg.PointerType{Type: &pkg.IdentType{Name: "int"}},
+					Orig:        "count",
+					Comment:     "Optional",
+					Constraints: &pkg.Constraints{Maximum: &maxCountBound},
+				}},
+			}}},
+		},
 		{
 			"object additionalProperties",
-			&v2.ObjectSchema{
-				AdditionalProperties: &v2.StringSchema{},
+			&v3.ObjectSchema{
+				AdditionalProperties: &v3.StringSchema{},
 			},
 			&pkg.TypeDecl{Name: "Foo"},
 			&pkg.IdentType{Name: "Foo"},
@@ -79,7 +107,7 @@ func TestConvertSchema(t *testing.T) {
 		},
 		{
 			"object any additionalProperties",
-			&v2.ObjectSchema{
+			&v3.ObjectSchema{
 				AnyAdditionalProperties: true,
 			},
 			&pkg.TypeDecl{Name: "Foo"},
@@ -91,8 +119,8 @@ func TestConvertSchema(t *testing.T) {
 		},
 		{
 			"allOf empty",
-			&v2.AllOfSchema{
-				AllOf: []v2.Schema{},
+			&v3.AllOfSchema{
+				AllOf: []v3.Schema{},
 			},
 			&pkg.TypeDecl{Name: "Foo"},
 			&pkg.IdentType{Name: "Foo"},
@@ -100,12 +128,12 @@ func TestConvertSchema(t *testing.T) {
 		},
 		{
 			"allOf reference and struct",
-			&v2.AllOfSchema{
-				AllOf: []v2.Schema{
-					&v2.ReferenceSchema{Reference: "#/definitions/RefField"},
-					&v2.ObjectSchema{
-						Properties: &v2.SchemaMap{
-							{Name: "field", Schema: &v2.StringSchema{}},
+			&v3.AllOfSchema{
+				AllOf: []v3.Schema{
+					&v3.ReferenceSchema{Reference: "#/components/schemas/RefField"},
+					&v3.ObjectSchema{
+						Properties: &v3.SchemaMap{
+							{Name: "field", Schema: &v3.StringSchema{}},
 						},
 						Required: &[]string{"field"},
 					},
@@ -129,6 +157,53 @@ func TestConvertSchema(t *testing.T) {
 				},
 			},
 		},
+		{
+			"object nullable required field",
+			&v3.ObjectSchema{
+				Properties: &v3.SchemaMap{
+					{Name: "field", Schema: &v3.StringSchema{SchemaFields: v3.SchemaFields{Nullable: true}}},
+					{Name: "union", Schema: &v3.AnyOfSchema{AnyOf: []v3.Schema{
+						&v3.ReferenceSchema{Reference: "#/components/schemas/Bar"},
+						&v3.NullSchema{},
+					}}},
+				},
+				Required: &[]string{"field", "union"},
+			},
+			&pkg.TypeDecl{Name: "Foo"},
+			&pkg.IdentType{Name: "Foo"},
+			[]pkg.TypeDecl{{Name: "Foo", Type: &pkg.StructType{
+				Fields: []pkg.Field{
+					{ID: "Field", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "string"}}, Orig: "field"},
+					{ID: "Union", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "Bar"}}, Orig: "union"},
+				},
+			}}},
+		},
+		{
+			"array nullable items",
+			&v3.ArraySchema{Items: &v3.IntegerSchema{SchemaFields: v3.SchemaFields{Nullable: true}}},
+			&pkg.TypeDecl{Name: "Foo"},
+			&pkg.SliceType{Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "int"}}},
+			nil,
+		},
+		{
+			"array tuple",
+			&v3.ArraySchema{PrefixItems: []v3.Schema{&v3.StringSchema{}, &v3.IntegerSchema{}}},
+			&pkg.TypeDecl{Name: "Foo"},
+			&pkg.SliceType{Type: &pkg.InterfaceType{}},
+			nil,
+		},
+		{
+			"anyOf multiple types",
+			&v3.AnyOfSchema{AnyOf: []v3.Schema{&v3.StringSchema{}, &v3.IntegerSchema{}}},
+			&pkg.TypeDecl{Name: "Foo"},
+			&pkg.IdentType{Name: "Foo"},
+			[]pkg.TypeDecl{{Name: "Foo", Comment: "Foo is a data type for API communication.\n\nIt holds one of: String, Int.", Type: &pkg.UnionType{
+				Variants: []pkg.UnionVariant{
+					{Name: "String", Type: &pkg.IdentType{Name: "string"}},
+					{Name: "Int", Type: &pkg.IdentType{Name: "int"}},
+				},
+			}}},
+		},
 	}
 
 	for _, tc := range tcs {
@@ -145,40 +220,169 @@ func TestConvertSchema(t *testing.T) {
 	}
 }
 
+func TestConvertSchemaConst(t *testing.T) {
+	dateFormat := "date"
+
+	tcs := []struct {
+		name   string
+		in     v3.Schema
+		consts []pkg.ConstDecl
+	}{
+		{"string", &v3.StringSchema{SchemaFields: v3.SchemaFields{Const: "fixed"}}, []pkg.ConstDecl{{
+			Name:    "FooValue",
+			Comment: "FooValue is the fixed value of Foo.",
+			Type:    &pkg.IdentType{Name: "string"},
+			Value:   "fixed",
+		}}},
+		{"integer", &v3.IntegerSchema{SchemaFields: v3.SchemaFields{Const: 3}}, []pkg.ConstDecl{{
+			Name:    "FooValue",
+			Comment: "FooValue is the fixed value of Foo.",
+			Type:    &pkg.IdentType{Name: "int"},
+			Value:   3,
+		}}},
+		{"formatted string", &v3.StringSchema{
+			SchemaFields: v3.SchemaFields{Const: "2020-01-01"},
+			StringFields: v3.StringFields{Format: &dateFormat},
+		}, nil},
+		{"no const", &v3.StringSchema{}, nil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := &typeRegistry{strFmt: stringFormat{"date": "time.Time"}}
+			tr.convertSchema(tc.in, &pkg.TypeDecl{Name: "Foo"}, false)
+			if !reflect.DeepEqual(tr.consts, tc.consts) {
+				t.Error("got:", tr.consts, "expected:", tc.consts)
+			}
+		})
+	}
+}
+
+func TestConvertSchemaEnum(t *testing.T) {
+	dateFormat := "date"
+
+	tcs := []struct {
+		name string
+		in   v3.Schema
+		out  pkg.Type
+		reg  []pkg.TypeDecl
+	}{
+		{
+			"string",
+			&v3.StringSchema{StringFields: v3.StringFields{Enum: &[]string{"available", "in-stock", "available", ""}}},
+			&pkg.IdentType{Name: "Foo"},
+			[]pkg.TypeDecl{{
+				Name:    "Foo",
+				Comment: "Foo is a data type for API communication.",
+				Type:    &pkg.IdentType{Name: "string"},
+				Enum: []pkg.ConstDecl{
+					{Name: "FooAvailable", Type: &pkg.IdentType{Name: "Foo"}, Value: "available"},
+					{Name: "FooInStock", Type: &pkg.IdentType{Name: "Foo"}, Value: "in-stock"},
+					{Name: "FooEmpty", Type: &pkg.IdentType{Name: "Foo"}, Value: ""},
+				},
+			}},
+		},
+		{
+			"integer",
+			&v3.IntegerSchema{IntegerFields: v3.IntegerFields{Enum: &[]int64{1, -1}}},
+			&pkg.IdentType{Name: "Foo"},
+			[]pkg.TypeDecl{{
+				Name:    "Foo",
+				Comment: "Foo is a data type for API communication.",
+				Type:    &pkg.IdentType{Name: "int"},
+				Enum: []pkg.ConstDecl{
+					{Name: "Foo1", Type: &pkg.IdentType{Name: "Foo"}, Value: 1},
+					{Name: "FooMinus1", Type: &pkg.IdentType{Name: "Foo"}, Value: -1},
+				},
+			}},
+		},
+		{
+			"number",
+			&v3.NumberSchema{NumberFields: v3.NumberFields{Enum: &[]float64{0.5}}},
+			&pkg.IdentType{Name: "Foo"},
+			[]pkg.TypeDecl{{
+				Name:    "Foo",
+				Comment: "Foo is a data type for API communication.",
+				Type:    &pkg.IdentType{Name: "float64"},
+				Enum: []pkg.ConstDecl{
+					{Name: "Foo05", Type: &pkg.IdentType{Name: "Foo"}, Value: 0.5},
+				},
+			}},
+		},
+		{
+			"names that collide",
+			&v3.StringSchema{StringFields: v3.StringFields{Enum: &[]string{"a-b", "a_b", "!"}}},
+			&pkg.IdentType{Name: "Foo"},
+			[]pkg.TypeDecl{{
+				Name:    "Foo",
+				Comment: "Foo is a data type for API communication.",
+				Type:    &pkg.IdentType{Name: "string"},
+				Enum: []pkg.ConstDecl{
+					{Name: "FooAB", Type: &pkg.IdentType{Name: "Foo"}, Value: "a-b"},
+					{Name: "FooValue1", Type: &pkg.IdentType{Name: "Foo"}, Value: "a_b"},
+					{Name: "FooValue2", Type: &pkg.IdentType{Name: "Foo"}, Value: "!"},
+				},
+			}},
+		},
+		{
+			"formatted string",
+			&v3.StringSchema{StringFields: v3.StringFields{Format: &dateFormat, Enum: &[]string{"2020-01-01"}}},
+			&pkg.IdentType{Name: "Time", Qualifier: "time", Marshal: true},
+			nil,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := &typeRegistry{strFmt: stringFormat{"date": "time.Time"}}
+			out := tr.convertSchema(tc.in, &pkg.TypeDecl{Name: "Foo"}, false)
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+
+			if !reflect.DeepEqual(tr.types, tc.reg) {
+				t.Error("got:", tr.types, "expected:", tc.reg)
+			}
+		})
+	}
+}
+
 func TestTypeForParameter(t *testing.T) {
 	tcs := []struct {
 		name string
-		in   v2.Parameter
+		in   v3.Schema
 		out  pkg.Type
 	}{
-		{"string", &v2.StringParameter{}, &pkg.IdentType{Name: "string"}},
-		{"number", &v2.NumberParameter{}, &pkg.IdentType{Name: "float64"}},
-		{"integer", &v2.IntegerParameter{}, &pkg.IdentType{Name: "int"}},
-		{"bool", &v2.BooleanParameter{}, &pkg.IdentType{Name: "bool"}},
+		{"string", &v3.StringSchema{}, &pkg.IdentType{Name: "string"}},
+		{"number", &v3.NumberSchema{}, &pkg.IdentType{Name: "float64"}},
+		{"integer", &v3.IntegerSchema{}, &pkg.IdentType{Name: "int"}},
+		{"bool", &v3.BooleanSchema{}, &pkg.IdentType{Name: "bool"}},
 
-		{"string array",
-			&v2.ArrayParameter{ArrayItem: v2.ArrayItem{Items: &v2.StringItem{}}},
+		{"string array", &v3.ArraySchema{Items: &v3.StringSchema{}},
 			&pkg.SliceType{Type: &pkg.IdentType{Name: "string"}}},
-		{"number array", &v2.ArrayParameter{ArrayItem: v2.ArrayItem{Items: &v2.NumberItem{}}},
+		{"number array", &v3.ArraySchema{Items: &v3.NumberSchema{}},
 			&pkg.SliceType{Type: &pkg.IdentType{Name: "float64"}}},
-		{"integer array", &v2.ArrayParameter{ArrayItem: v2.ArrayItem{Items: &v2.IntegerItem{}}},
+		{"integer array", &v3.ArraySchema{Items: &v3.IntegerSchema{}},
 			&pkg.SliceType{Type: &pkg.IdentType{Name: "int"}}},
-		{"bool array", &v2.ArrayParameter{ArrayItem: v2.ArrayItem{Items: &v2.BooleanItem{}}},
+		{"bool array", &v3.ArraySchema{Items: &v3.BooleanSchema{}},
 			&pkg.SliceType{Type: &pkg.IdentType{Name: "bool"}}},
 
 		{
 			"nested array",
-			&v2.ArrayParameter{
-				ArrayItem: v2.ArrayItem{Items: &v2.ArrayItem{Items: &v2.StringItem{}}},
-			},
+			&v3.ArraySchema{Items: &v3.ArraySchema{Items: &v3.StringSchema{}}},
 			&pkg.SliceType{Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "string"}}},
 		},
+
+		{"string enum", &v3.StringSchema{StringFields: v3.StringFields{Enum: &[]string{"a"}}},
+			&pkg.IdentType{Name: "Foo"}},
+		{"integer enum array", &v3.ArraySchema{Items: &v3.IntegerSchema{IntegerFields: v3.IntegerFields{Enum: &[]int64{1}}}},
+			&pkg.SliceType{Type: &pkg.IdentType{Name: "Foo"}}},
 	}
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			tr := &typeRegistry{}
-			out, _ := tr.typeForParameter(tc.in)
+			out, _ := tr.typeForParameter(&v3.Parameter{In: "query", Schema: tc.in}, "Foo")
 			if !reflect.DeepEqual(out, tc.out) {
 				t.Error("got:", out, "expected:", tc.out)
 			}
@@ -186,8 +390,46 @@ func TestTypeForParameter(t *testing.T) {
 	}
 }
 
+func TestCollectionFor(t *testing.T) {
+	str := func(s string) *string { return &s }
+	yes, no := true, false
+
+	tcs := []struct {
+		name string
+		in   v3.Parameter
+		out  pkg.Collection
+		ok   bool
+	}{
+		{"query default", v3.Parameter{In: "query"}, pkg.Multi, true},
+		{"path default", v3.Parameter{In: "path"}, pkg.CSV, true},
+		{"header default", v3.Parameter{In: "header"}, pkg.CSV, true},
+		{"form explode", v3.Parameter{In: "query", Style: str("form"), Explode: &yes}, pkg.Multi, true},
+		{"form no explode", v3.Parameter{In: "query", Style: str("form"), Explode: &no}, pkg.CSV, true},
+		{"space delimited", v3.Parameter{In: "query", Style: str("spaceDelimited")}, pkg.SSV, true},
+		{"pipe delimited", v3.Parameter{In: "query", Style: str("pipeDelimited")}, pkg.Pipes, true},
+		{"tab delimited", v3.Parameter{In: "query", Style: str("tabDelimited")}, pkg.TSV, true},
+		{"unsupported", v3.Parameter{In: "query", Style: str("deepObject")}, pkg.None, false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out, ok := collectionFor(&tc.in)
+			if out != tc.out {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+			if ok != tc.ok {
+				t.Error("got ok:", ok, "expected:", tc.ok)
+			}
+		})
+	}
+}
+
 func TestStringFormatTypeFor(t *testing.T) {
-	tr := &typeRegistry{strFmt: stringFormat{"reg": "github.com/jbowes/oag.Reg"}}
+	tr := &typeRegistry{strFmt: stringFormat{
+		"reg":  "github.com/jbowes/oag.Reg",
+		"date": "github.com/jbowes/oag.Day",
+		"word": "string",
+	}}
 
 	tcs := []struct {
 		name string
@@ -201,6 +443,14 @@ func TestStringFormatTypeFor(t *testing.T) {
 			Qualifier: "github.com/jbowes/oag",
 			Marshal:   true,
 		}},
+		{"builtin registered", "word", &pkg.IdentType{Name: "string"}},
+		{"default date-time", "date-time", &pkg.IdentType{Name: "Time", Qualifier: "time", Marshal: true}},
+		{"default byte", "byte", &pkg.SliceType{Type: &pkg.IdentType{Name: "byte"}}},
+		{"default overridden", "date", &pkg.IdentType{
+			Name:      "Day",
+			Qualifier: "github.com/jbowes/oag",
+			Marshal:   true,
+		}},
 	}
 
 	for _, tc := range tcs {
@@ -217,3 +467,227 @@ func TestStringFormatTypeFor(t *testing.T) {
 		})
 	}
 }
+
+func TestNumberFormatTypeFor(t *testing.T) {
+	nf := numberFormat{
+		"int64":   "int",
+		"decimal": "github.com/shopspring/decimal.Decimal",
+	}
+
+	tcs := []struct {
+		name string
+		in   string
+		def  string
+		out  pkg.Type
+	}{
+		{"no format", "", "int", &pkg.IdentType{Name: "int"}},
+		{"format not registered", "unreg", "float64", &pkg.IdentType{Name: "float64"}},
+		{"default int32", "int32", "int", &pkg.IdentType{Name: "int32"}},
+		{"default float", "float", "float64", &pkg.IdentType{Name: "float32"}},
+		{"default double", "double", "float64", &pkg.IdentType{Name: "float64"}},
+		{"default overridden", "int64", "int", &pkg.IdentType{Name: "int"}},
+		{"format registered", "decimal", "float64", &pkg.IdentType{
+			Name:      "Decimal",
+			Qualifier: "github.com/shopspring/decimal",
+			Marshal:   true,
+		}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var f *string
+			if tc.in != "" {
+				f = &tc.in
+			}
+
+			out := nf.typeFor(f, tc.def)
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestStringTypeDate(t *testing.T) {
+	date := "date"
+	tr := &typeRegistry{date: "CivilDate"}
+
+	out := tr.stringType(&date)
+	if expected := (&pkg.IdentType{Name: "CivilDate", Marshal: true}); !reflect.DeepEqual(out, expected) {
+		t.Error("got:", out, "expected:", expected)
+	}
+	if !tr.dateUsed {
+		t.Error("expected date type to be used")
+	}
+}
+
+func TestPointerName(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   []string
+		out  string
+	}{
+		{"component", []string{"components", "schemas", "order"}, "Order"},
+		{"property", []string{"components", "schemas", "Order", "properties", "line_items"}, "OrderLineItems"},
+		{"items", []string{"components", "schemas", "Order", "properties", "lines", "items"}, "OrderLinesItems"},
+		{"additional properties", []string{"components", "schemas", "Tags", "additionalProperties"}, "TagsValue"},
+		{"all of", []string{"components", "schemas", "Pet", "allOf", "1"}, "PetAllOf1"},
+		{"prefix items", []string{"components", "schemas", "Pair", "prefixItems", "0"}, "PairItems0"},
+		{"defs", []string{"components", "schemas", "Pet", "$defs", "name"}, "Name"},
+		{"root defs", []string{"$defs", "owner"}, "Owner"},
+		{"parameter", []string{"components", "parameters", "limit", "schema"}, "Limit"},
+		{"response", []string{"components", "responses", "Err", "content", "application/json", "schema", "properties", "code"}, "ErrCode"},
+		{"escaped", []string{"components", "schemas", "a/b"}, "AB"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := pointerName(tc.in); out != tc.out {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestConvertSchemaNestedRef(t *testing.T) {
+	doc := &v3.Document{Components: &v3.Components{Schemas: &v3.SchemaMap{
+		{Name: "Order", Schema: &v3.ObjectSchema{Properties: &v3.SchemaMap{
+			{Name: "items", Schema: &v3.ArraySchema{Items: &v3.ObjectSchema{
+				Properties: &v3.SchemaMap{
+					{Name: "sku", Schema: &v3.StringSchema{}},
+				},
+			}}},
+			{Name: "a/b", Schema: &v3.StringSchema{}},
+		}}},
+	}}}
+
+	tcs := []struct {
+		name string
+		in   string
+		out  pkg.Type
+		reg  []pkg.TypeDecl
+	}{
+		{
+			"nested property",
+			"#/components/schemas/Order/properties/items/items",
+			&pkg.IdentType{Name: "OrderItemsItems"},
+			[]pkg.TypeDecl{{
+				Name:    "OrderItemsItems",
+				Comment: "OrderItemsItems is a data type for API communication.",
+				Type: &pkg.StructType{Fields: []pkg.Field{{
+					ID:      "Sku",
+					Type:    &pkg.PointerType{Type: &pkg.IdentType{Name: "string"}},
+					Orig:    "sku",
+					Comment: "Optional",
+				}}},
+			}},
+		},
+		{
+			"escaped property",
+			"#/components/schemas/Order/properties/a~1b",
+			&pkg.IdentType{Name: "OrderAB"},
+			[]pkg.TypeDecl{{
+				Name:    "OrderAB",
+				Comment: "OrderAB is a data type for API communication.",
+				Type:    &pkg.IdentType{Name: "string"},
+			}},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := &typeRegistry{doc: doc}
+			out := tr.convertSchema(&v3.ReferenceSchema{Reference: tc.in}, nil, false)
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+
+			if !reflect.DeepEqual(tr.types, tc.reg) {
+				t.Error("registry mismatch. got:", tr.types, "expected:", tc.reg)
+			}
+		})
+	}
+}
+
+func TestConstraintsFor(t *testing.T) {
+	one, ten := int64(1), int64(10)
+	onef, two, half := 1.0, 2.0, 0.5
+	three := uint64(3)
+	lower, lookahead := "^[a-z]+$", "(?!x)"
+
+	doc := &v3.Document{Components: &v3.Components{Schemas: &v3.SchemaMap{
+		{Name: "Name", Schema: &v3.StringSchema{StringFields: v3.StringFields{MaxLength: &ten}}},
+		{Name: "Pet", Schema: &v3.ObjectSchema{Properties: &v3.SchemaMap{
+			{Name: "name", Schema: &v3.StringSchema{StringFields: v3.StringFields{MaxLength: &ten}}},
+		}}},
+		{Name: "Loop", Schema: &v3.ArraySchema{Items: &v3.ReferenceSchema{Reference: "#/components/schemas/Loop"}}},
+	}}}
+
+	tcs := []struct {
+		name  string
+		in    v3.Schema
+		out   *pkg.Constraints
+		diags string
+	}{
+		{"none", &v3.StringSchema{}, nil, ""},
+		{
+			"string",
+			&v3.StringSchema{StringFields: v3.StringFields{MinLength: &one, MaxLength: &ten, Pattern: &lower}},
+			&pkg.Constraints{MinLength: &one, MaxLength: &ten, Pattern: &lower},
+			"",
+		},
+		{
+			"unsupported pattern",
+			&v3.StringSchema{StringFields: v3.StringFields{Pattern: &lookahead}},
+			nil,
+			"warning: #/pattern: pattern \"(?!x)\" is not supported, and is not validated: error parsing regexp: invalid or unsupported Perl syntax: `(?!`",
+		},
+		{
+			"integer",
+			&v3.IntegerSchema{IntegerFields: v3.IntegerFields{Minimum: &one, ExclusiveMinimum: true, ExclusiveMaximum: true}},
+			&pkg.Constraints{Minimum: &onef, ExclusiveMinimum: true},
+			"",
+		},
+		{
+			"number",
+			&v3.NumberSchema{NumberFields: v3.NumberFields{Maximum: &two, MultipleOf: &half}},
+			&pkg.Constraints{Maximum: &two, MultipleOf: &half},
+			"",
+		},
+		{
+			"array",
+			&v3.ArraySchema{
+				ArrayFields: v3.ArrayFields{MaxItems: &three},
+				Items:       &v3.ReferenceSchema{Reference: "#/components/schemas/Name"},
+			},
+			&pkg.Constraints{MaxItems: &three, Items: &pkg.Constraints{MaxLength: &ten}},
+			"",
+		},
+		{
+			"map",
+			&v3.ObjectSchema{AdditionalProperties: &v3.StringSchema{StringFields: v3.StringFields{MinLength: &one}}},
+			&pkg.Constraints{Items: &pkg.Constraints{MinLength: &one}},
+			"",
+		},
+		{"struct reference", &v3.ReferenceSchema{Reference: "#/components/schemas/Pet"}, nil, ""},
+		{"recursive reference", &v3.ReferenceSchema{Reference: "#/components/schemas/Loop"}, nil, ""},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := &typeRegistry{doc: doc}
+			out := tr.constraintsFor(tc.in)
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Errorf("got: %+v expected: %+v", out, tc.out)
+			}
+
+			var diags string
+			if len(tr.diags) > 0 {
+				diags = tr.diags.Error()
+			}
+			if diags != tc.diags {
+				t.Errorf("got diagnostics:\n%s\nexpected:\n%s", diags, tc.diags)
+			}
+		})
+	}
+}
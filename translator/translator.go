@@ -3,65 +3,98 @@ package translator
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
-	"github.com/jbowes/oag/openapi/v2"
+	"github.com/jbowes/oag/config"
+	"github.com/jbowes/oag/diag"
+	"github.com/jbowes/oag/openapi/jsonpointer"
+	"github.com/jbowes/oag/openapi/v3"
 	"github.com/jbowes/oag/pkg"
 )
 
-// Translate translates an openapi.Document into a series of Packages
-func Translate(doc *v2.Document, qual, name string, types map[string]string, stringFormats map[string]string) (*pkg.Package, error) {
+// Translate translates an openapi.Document into a series of Packages, as
+// configured by cfg. Operations are grouped into clients as configured by its
+// grouping, and their methods are named as configured by its naming. With
+// response headers, methods also return the headers of their responses. Lists
+// wrapped in an object are held by its list field property, or else detected.
+// Problems found in the document are returned as diagnostics. The Package
+// should not be used if any are errors.
+func Translate(doc *v3.Document, cfg *config.Config) (*pkg.Package, diag.List) {
 	p := &pkg.Package{
-		Qualifier: qual,
-		Name:      name,
-		BaseURL:   "https://" + *doc.Host + *doc.BasePath,
+		Qualifier: cfg.Package.Path,
+		Name:      cfg.Package.Name,
+		BaseURL:   baseURL(doc),
 	}
 
-	tr := &typeRegistry{strFmt: stringFormats}
-	if doc.Definitions != nil {
-		for _, def := range *doc.Definitions {
+	types := cfg.Types
+	tr := &typeRegistry{
+		doc:       doc,
+		strFmt:    cfg.StringFormats,
+		numFmt:    cfg.NumberFormats,
+		date:      civilDateName(doc),
+		naming:    cfg.Naming,
+		headers:   cfg.ResponseHeaders,
+		listField: cfg.ListField,
+	}
+	tr.polymorphic = tr.discriminators()
+	if doc.Components != nil && doc.Components.Schemas != nil {
+		for _, def := range *doc.Components.Schemas {
+			leave := tr.enter("components", "schemas", def.Name)
 			convertDefinition(tr, def.Name, def.Schema, types)
+			convertDefs(tr, def.Schema, types)
+			leave()
 		}
 	}
 
 	trie := &node{}
 	for path, pi := range doc.Paths {
-		p := pi
-		trie.add(path, &p)
-	}
-
-	clients := make(map[string]*pkg.Client)
-	getClient := func(path []token) *pkg.Client {
-		clientName := formatID(path[2].value(), "Client")
-		if len(path) == 3 { // Define the client
-			clients[clientName] = &pkg.Client{
-				Name:        clientName,
-				ContextName: path[2].value(),
-				Comment:     fmt.Sprintf("%s provides access to the /%s APIs", clientName, path[2].value()),
-			}
+		leave := tr.enter("paths", path)
+		p, err := resolvePathItem(doc, pi)
+		if err != nil {
+			tr.errorf("%s", err)
+		} else {
+			trie.add(path, &p)
 		}
-
-		return clients[clientName]
+		leave()
 	}
 
+	g := newGrouper(doc, trie, cfg.Grouping)
+	methods := make(map[string]string) // endpoints, by client and method name
 	for n := range trie.visit() {
-		if len(n.path) < 3 {
-			continue
+		var path string
+		for _, tok := range n.path[1:] {
+			path += tok.value()
 		}
 
-		client := getClient(n.path)
-
 		mm := methodMap(n.n.handlers)
-		for m, o := range n.n.handlers {
-			method := convertOperation(tr, doc, n, m, mm[m], o, client, p)
-			client.Methods = append(client.Methods, *method)
+		for _, m := range sortedMethods(n.n.handlers) {
+			o := n.n.handlers[m]
+			client, start := g.client(n.path, o)
+			if client == nil {
+				continue
+			}
+
+			leave := tr.enter("paths", path, strings.ToLower(m))
+			method := convertOperation(tr, doc, n, start, m, mm[m], o, client, p)
 
+			key := client.Name + "." + method.Name
+			endpoint := strings.ToUpper(m) + " " + docPath(n.path[1:])
+			if other, ok := methods[key]; ok {
+				tr.errorf("method %s of %s is also generated for %s", method.Name, client.Name, other)
+			} else {
+				methods[key] = endpoint
+				client.Methods = append(client.Methods, *method)
+			}
+			leave()
 		}
-		sort.Slice(client.Methods, func(i, j int) bool { return client.Methods[i].Name < client.Methods[j].Name })
 	}
 
-	for _, c := range clients {
+	for _, c := range g.clients {
+		sort.Slice(c.Methods, func(i, j int) bool { return c.Methods[i].Name < c.Methods[j].Name })
 		p.Clients = append(p.Clients, *c)
 	}
 	sort.Slice(p.Clients, func(i, j int) bool { return p.Clients[i].Name < p.Clients[j].Name })
@@ -71,10 +104,47 @@ func Translate(doc *v2.Document, qual, name string, types map[string]string, str
 	p.TypeDecls = tr.types
 	sort.Slice(p.TypeDecls, func(i, j int) bool { return p.TypeDecls[i].Name < p.TypeDecls[j].Name })
 
-	return p, nil
+	p.Consts = tr.consts
+	sort.Slice(p.Consts, func(i, j int) bool { return p.Consts[i].Name < p.Consts[j].Name })
+
+	if tr.dateUsed {
+		p.DateType = tr.date
+	}
+
+	return p, tr.diags
 }
 
-func convertDefinition(tr *typeRegistry, name string, def v2.Schema, types map[string]string) {
+// civilDateName names the civil date type, avoiding the name of any schema in
+// doc.
+func civilDateName(doc *v3.Document) string {
+	if doc.Components != nil && doc.Components.Schemas != nil {
+		for _, def := range *doc.Components.Schemas {
+			if formatID(def.Name) == dateType {
+				return "Civil" + dateType
+			}
+		}
+	}
+
+	return dateType
+}
+
+// baseURL returns the URL of the first server defined in doc, with any
+// variables replaced by their default values.
+func baseURL(doc *v3.Document) string {
+	if len(doc.Servers) == 0 {
+		return "/" // the default according to the OpenAPI 3 spec
+	}
+
+	s := doc.Servers[0]
+	u := s.URL
+	for name, v := range s.Variables {
+		u = strings.Replace(u, "{"+name+"}", v.Default, -1)
+	}
+
+	return u
+}
+
+func convertDefinition(tr *typeRegistry, name string, def v3.Schema, types map[string]string) {
 	dataName := formatID(name)
 	comment := fmt.Sprintf("%s is a data type for API communication.", dataName)
 	if title := def.GetTitle(); title != nil {
@@ -98,52 +168,110 @@ func convertDefinition(tr *typeRegistry, name string, def v2.Schema, types map[s
 		return
 	}
 
+	if d := tr.polymorphic[dataName]; d != nil {
+		tr.convertSchema(def, &pkg.TypeDecl{
+			Name:    d.Base,
+			Comment: fmt.Sprintf("%s holds the fields common to all kinds of %s.", d.Base, dataName),
+		}, true)
+
+		var kinds []string
+		for _, v := range d.Variants {
+			if k := "*" + v.Type; v.Type != d.Base && !contains(kinds, k) {
+				kinds = append(kinds, k)
+			}
+		}
+		tr.add(pkg.TypeDecl{
+			Name: dataName,
+			Comment: fmt.Sprintf("%s\n\nIts %s property selects the kind of %s: one of %s, or *%s for any other value.",
+				comment, d.Property, dataName, strings.Join(kinds, ", "), d.Base),
+			Type:          &pkg.InterfaceType{},
+			Discriminator: d,
+		})
+		return
+	}
+
 	tr.convertSchema(def, &pkg.TypeDecl{
 		Name:    dataName,
 		Comment: comment,
 	}, true)
 }
 
-func convertOperation(tr *typeRegistry, def *v2.Document, n *visited, httpMethod, prefix string, o *v2.Operation, client *pkg.Client, p *pkg.Package) *pkg.Method {
+// convertDefs converts any schemas declared in $defs of def, recursively, as
+// top level definitions.
+func convertDefs(tr *typeRegistry, def v3.Schema, types map[string]string) {
+	defs := def.GetDefs()
+	if defs == nil {
+		return
+	}
+
+	for _, d := range *defs {
+		leave := tr.enter("$defs", d.Name)
+		convertDefinition(tr, d.Name, d.Schema, types)
+		convertDefs(tr, d.Schema, types)
+		leave()
+	}
+}
+
+// convertOperation converts the operation o, for the httpMethod of the path
+// of n, into a method of client. Its name is built from prefix and the path
+// from the token at index start.
+func convertOperation(tr *typeRegistry, def *v3.Document, n *visited, start int, httpMethod, prefix string, o *v3.Operation, client *pkg.Client, p *pkg.Package) *pkg.Method {
+	// Responses of GET operations may wrap a list in an object. Unless the
+	// property holding it is configured, only collections, with a path not
+	// ending in a param, are checked for one.
+	_, item := n.path[len(n.path)-1].(param)
+	lists := httpMethod == "Get" && (tr.listField != "" || !item)
+
 	// if array response, change Get to List
-	if httpMethod == "Get" {
-		for code, r := range o.Responses.Codes {
-			if code >= 200 && code < 300 {
-				if _, ok := r.Schema.(*v2.ArraySchema); ok {
-					prefix = "List"
-					break
-				}
+	if httpMethod == "Get" && o.Responses != nil {
+		for _, r := range successResponses(def, o.Responses) {
+			schema := jsonSchema(r.Content)
+			if _, ok := schema.(*v3.ArraySchema); ok || lists && listProperty(tr, schema) != "" {
+				prefix = "List"
+				break
 			}
 		}
 	}
 
 	var params []param
 	parts := []string{prefix}
-	for _, p := range n.path[3:] {
+	for i, p := range n.path[1:] {
 		if t, ok := p.(param); ok {
 			params = append(params, t)
 			continue
 		}
-		if p.value() == "/" {
+		if i+1 < start || p.value() == "/" {
 			continue
 		}
 		parts = append(parts, p.value())
 	}
+
+	if tr.naming == config.NamingOperationID && o.OperationID != nil {
+		if id := operationIDParts(*o.OperationID, client); id != nil {
+			parts = id
+		} else {
+			tr.warnf("operationId %q is not a valid method name, and the path is used instead", *o.OperationID)
+		}
+	}
 	methodName := formatID(parts...)
 
+	// types declared for the operation are named after the method, qualified
+	// by the client when the method name alone is too general, ie PetsList.
+	typePrefix := methodName
+	if len(parts) == 1 {
+		typePrefix = strings.TrimSuffix(client.Name, "Client") + methodName
+	}
+
 	var path string
-	var docPath string
 	for _, p := range n.path[1:] {
-		if pp, ok := p.(param); ok {
-			path += "%s" // XXX check type
-			docPath += ":" + string(pp)
+		if _, ok := p.(param); ok {
+			path += "%s"
 		} else {
 			path += p.value()
-			docPath += p.value()
 		}
 	}
 
-	comment := fmt.Sprintf("%s corresponds to the %s %s endpoint.", methodName, strings.ToUpper(httpMethod), docPath)
+	comment := fmt.Sprintf("%s corresponds to the %s %s endpoint.", methodName, strings.ToUpper(httpMethod), docPath(n.path[1:]))
 	if o.Summary != nil {
 		comment += "\n\n" + *o.Summary
 	}
@@ -167,7 +295,7 @@ func convertOperation(tr *typeRegistry, def *v2.Document, n *visited, httpMethod
 	// then body
 	// then optional args in a struct
 	var pathParams []pkg.Param // always first
-	var body *pkg.Param        // always last required arg, before opts
+	var body []pkg.Param       // always last required args, before opts
 	var opts []pkg.Field       // always last
 
 	for _, p := range params {
@@ -178,24 +306,29 @@ func convertOperation(tr *typeRegistry, def *v2.Document, n *visited, httpMethod
 		})
 	}
 
-	for _, p := range o.Parameters {
-		newBody, newParam, newOpts := convertParameter(tr, def, methodName, pathParams, p, client)
-		if newBody != nil {
-			body = newBody
-		}
+	for _, p := range operationParameters(def, tr.path, n.n.parameters, o) {
+		leave := tr.at(p.at)
+		newParam, newOpts := convertParameter(tr, def, pathParams, p.Parameter, client, typePrefix)
 		method.Params = append(method.Params, newParam...)
 		opts = append(opts, newOpts...)
+		leave()
 	}
 
-	method.Params = append(pathParams, method.Params...)
-	if body != nil {
-		method.Params = append(method.Params, *body)
+	if o.RequestBody != nil {
+		leave := tr.enter("requestBody")
+		var bodyOpts []pkg.Field
+		body, bodyOpts, method.Form = convertRequestBody(tr, def, methodName, typePrefix, o.RequestBody, client)
+		opts = append(opts, bodyOpts...)
+		leave()
 	}
 
+	method.Params = append(pathParams, method.Params...)
+	method.Params = append(method.Params, body...)
+
 	if len(opts) > 0 {
-		optsName := methodName + "Opts"
-		if len(parts) == 1 {
-			optsName = formatID(n.path[2].value(), methodName, "Opts")
+		optsName := typePrefix + "Opts"
+		if tr.declaredSchema(optsName) {
+			tr.errorf("type %s for optional arguments is already declared by a schema", optsName)
 		}
 		// XXX dedupe similar Opts structs
 		td := pkg.TypeDecl{
@@ -215,146 +348,592 @@ func convertOperation(tr *typeRegistry, def *v2.Document, n *visited, httpMethod
 		})
 	}
 
-	method.Return, method.Errors = convertOperationResponses(def, tr, methodName, o.Responses, p)
+	resp := o.Responses
+	if resp == nil {
+		resp = &v3.Responses{}
+	}
+	leave := tr.enter("responses")
+	method.Return, method.Errors, method.Results = convertOperationResponses(def, tr, typePrefix, resp, lists, p)
+
+	// XXX iterators could hold the headers of each page
+	if _, iter := method.Return[0].(*pkg.IterType); tr.headers && !iter {
+		if meta := convertResponseMeta(tr, def, typePrefix, resp); meta != nil {
+			n := len(method.Return)
+			method.Return = append(method.Return[:n-1:n-1], meta, method.Return[n-1])
+			method.Meta = meta
+		}
+	}
+	leave()
 
 	return method
 }
 
-func convertOperationResponses(doc *v2.Document, tr *typeRegistry, methodName string, resp *v2.Responses, p *pkg.Package) ([]pkg.Type, map[int]pkg.Type) {
-	var rets []pkg.Type
+// resolvePathItem returns pi with its reference resolved, if it has one.
+// Fields set alongside the reference take precedence over those of the
+// referenced path item.
+func resolvePathItem(doc *v3.Document, pi v3.PathItem) (v3.PathItem, error) {
+	seen := make(map[string]bool)
+	for pi.Reference != nil {
+		ref := *pi.Reference
+		if seen[ref] {
+			return pi, fmt.Errorf("reference cycle at %s", ref)
+		}
+		seen[ref] = true
+
+		rpi, err := doc.ResolvePathItem(ref)
+		if err != nil {
+			return pi, err
+		}
+
+		out := *rpi
+		for _, f := range []struct{ local, ref **v3.Operation }{
+			{&pi.Get, &out.Get},
+			{&pi.Put, &out.Put},
+			{&pi.Post, &out.Post},
+			{&pi.Delete, &out.Delete},
+			{&pi.Options, &out.Options},
+			{&pi.Head, &out.Head},
+			{&pi.Patch, &out.Patch},
+			{&pi.Trace, &out.Trace},
+		} {
+			if *f.local != nil {
+				*f.ref = *f.local
+			}
+		}
+		if pi.Summary != nil {
+			out.Summary = pi.Summary
+		}
+		if pi.Description != nil {
+			out.Description = pi.Description
+		}
+		if pi.Servers != nil {
+			out.Servers = pi.Servers
+		}
+		if pi.Parameters != nil {
+			out.Parameters = pi.Parameters
+		}
+
+		pi = out
+	}
+
+	return pi, nil
+}
+
+// opParam is a parameter of an operation, and the reference tokens to where
+// it is defined.
+type opParam struct {
+	v3.Parameter
+	at []string
+}
+
+// operationParameters returns the parameters of the operation o, found at the
+// reference tokens at, merged with pathParams, the parameters of its path
+// item. Path item parameters apply unless o overrides them, by defining a
+// parameter with the same name and location.
+func operationParameters(doc *v3.Document, at []string, pathParams []v3.Parameter, o *v3.Operation) []opParam {
+	key := func(p v3.Parameter) string {
+		if p.Reference != "" {
+			rp, err := doc.ResolveParameter(p.Reference)
+			if err != nil {
+				return p.Reference // reported when the parameter is converted
+			}
+			p = *rp
+		}
+		return p.In + " " + p.Name
+	}
+
+	params := make([]opParam, 0, len(o.Parameters)+len(pathParams))
+	overrides := make(map[string]bool, len(o.Parameters))
+	for i, p := range o.Parameters {
+		params = append(params, opParam{p, append(at[:len(at):len(at)], "parameters", strconv.Itoa(i))})
+		overrides[key(p)] = true
+	}
+
+	item := at[: len(at)-1 : len(at)-1]
+	for i, p := range pathParams {
+		if !overrides[key(p)] {
+			params = append(params, opParam{p, append(item, "parameters", strconv.Itoa(i))})
+		}
+	}
+
+	return params
+}
+
+// convertOperationResponses converts the responses of an operation into the
+// return values of its method, and the error types for its other status
+// codes. Operations with differing bodies for several success codes return a
+// result type, holding the body of each in its own field, found in the
+// returned results. Without any success codes, the default response is the
+// success response. When lists is set, object responses wrapping a list are
+// returned as an iterator over its items.
+func convertOperationResponses(doc *v3.Document, tr *typeRegistry, typePrefix string, resp *v3.Responses, lists bool, p *pkg.Package) ([]pkg.Type, map[int]pkg.Type, []pkg.Result) {
 	errs := make(map[int]pkg.Type)
 
-	iter := false
-	for code, r := range resp.Codes {
+	var codes []int
+	for code := range resp.Codes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	// success responses with a body, by code. -1 is default
+	var bodies []int
+	responses := make(map[int]v3.Response)
+	success := false
+	for _, code := range codes {
+		leave := tr.enter(strconv.Itoa(code))
+
+		r, err := resolveResponse(doc, resp.Codes[code])
 		switch {
+		case err != nil:
+			tr.errorf("%s", err)
 		case code < 200: // XXX should these be handled?
-		case code == 204, r.Schema == nil: // no response
-		case code < 300: // XXX handle multiple 2XX returns
-			ret := tr.convertSchema(r.Schema, &pkg.TypeDecl{
-				Name: methodName + "Response",
-			}, false)
-
-			if it, ok := ret.(*pkg.SliceType); ok {
-				iter = true
-				name := it.Type.(*pkg.IdentType).Name + "Iter"
-				ret = &pkg.IterType{Type: &pkg.PointerType{
-					Type: &pkg.IdentType{Name: name},
-				}}
-
-				p.Iters = append(p.Iters, pkg.Iter{
-					Name:   name,
-					Return: tr.indirect(it.Type),
-				})
+		case code < 300:
+			success = true
+			if code != 204 && (streamed(r.Content) || jsonSchema(r.Content) != nil) {
+				bodies = append(bodies, code)
+				responses[code] = r
+			}
+		default:
+			if t := resolveErrRefs(r, tr); t != nil {
+				errs[code] = t
+			}
+		}
 
-			} else {
-				ret = tr.indirect(ret)
+		leave()
+	}
+
+	if resp.Default != nil {
+		leave := tr.enter("default")
+		r, err := resolveResponse(doc, *resp.Default)
+		switch {
+		case err != nil:
+			tr.errorf("%s", err)
+		case success:
+			if t := resolveErrRefs(r, tr); t != nil {
+				errs[-1] = t
 			}
+		case streamed(r.Content) || jsonSchema(r.Content) != nil:
+			bodies = append(bodies, -1)
+			responses[-1] = r
+		}
+		leave()
+	}
+
+	if len(bodies) == 0 {
+		return []pkg.Type{&pkg.IdentType{Name: "error"}}, errs, nil
+	}
 
-			rets = append(rets, ret)
+	types := make([]pkg.Type, len(bodies))
+	same := true
+	for i, code := range bodies {
+		name := typePrefix + "Response"
+		if len(bodies) > 1 {
+			name = typePrefix + statusField(code) + "Response"
+		}
+
+		types[i] = convertResponse(tr, code, responses[code], name)
+		same = same && types[0] != nil && types[i] != nil && types[i].Equal(types[0])
+	}
+
+	if same || len(bodies) == 1 {
+		if lists {
+			if prop := listProperty(tr, jsonSchema(responses[bodies[0]].Content)); prop != "" {
+				return envelopeTypes(tr, types[0], prop, p), errs, nil
+			}
+		}
+		return returnTypes(tr, types[0], p), errs, nil
+	}
+
+	// XXX support streams alongside other success responses
+	name := typePrefix + "Result"
+	fields := []pkg.Field{{ID: "Status", Type: &pkg.IdentType{Name: "int"}, Comment: "The status code of the response"}}
+	var results []pkg.Result
+	for i, code := range bodies {
+		switch t := types[i].(type) {
+		case nil:
+			continue
+		case *pkg.StreamType:
+			leave := tr.enter(strconv.Itoa(code))
+			tr.errorf("binary responses alongside other success responses are not supported")
+			leave()
+			continue
+		case *pkg.SliceType:
 		default:
-			errs[code] = resolveErrRefs(r, doc, tr)
+			types[i] = tr.indirect(t)
 		}
+
+		field := statusField(code)
+		fields = append(fields, pkg.Field{
+			ID:      field,
+			Type:    types[i],
+			Comment: fmt.Sprintf("Set for %d responses", code),
+		})
+		results = append(results, pkg.Result{Code: code, Field: field})
 	}
 
-	// XXX handle when this is the success case (no 2XX responses)
-	if resp.Default != nil {
-		errs[-1] = resolveErrRefs(*resp.Default, doc, tr)
+	tr.add(pkg.TypeDecl{
+		Name:    name,
+		Comment: fmt.Sprintf("%s holds a successful response, in the field for its status.", name),
+		Type:    &pkg.StructType{Fields: fields},
+	})
+
+	return []pkg.Type{
+		&pkg.PointerType{Type: &pkg.IdentType{Name: name}},
+		&pkg.IdentType{Name: "error"},
+	}, errs, results
+}
+
+// convertResponse converts the body of the response r, for the status code,
+// into its type. Inline schemas are declared as name. nil is returned if the
+// body can't be converted.
+func convertResponse(tr *typeRegistry, code int, r v3.Response, name string) pkg.Type {
+	at := "default"
+	if code != -1 {
+		at = strconv.Itoa(code)
 	}
+	leave := tr.enter(at)
+	defer leave()
 
-	if !iter {
-		rets = append(rets, &pkg.IdentType{Name: "error"})
+	if streamed(r.Content) {
+		return &pkg.StreamType{}
 	}
 
-	return rets, errs
+	mt := jsonMediaType(r.Content)
+	leave = tr.enter("content", mt, "schema")
+	defer leave()
+
+	return tr.convertSchema(jsonSchema(r.Content), &pkg.TypeDecl{Name: name}, false)
 }
 
-func convertParameter(tr *typeRegistry, def *v2.Document, methodName string, pathParams []pkg.Param, p v2.Parameter, client *pkg.Client) (*pkg.Param, []pkg.Param, []pkg.Field) {
-	if ref, ok := p.(*v2.ReferenceParamter); ok {
-		parts := strings.Split(ref.Reference, "/")
-		p = (*def.Parameters)[parts[len(parts)-1]]
-	}
-
-	switch p.GetIn() {
-	case "body":
-		// body will always be the last argument
-		b := p.(*v2.BodyParameter)
-		body := &pkg.Param{
-			ID:   "request",
-			Arg:  "request",
-			Kind: pkg.Body,
-			Type: tr.convertSchema(b.Schema, &pkg.TypeDecl{
-				Name: methodName + "Request",
-			}, false),
-		}
-
-		if t, ok := body.Type.(*pkg.IdentType); ok {
-			if t.Name != methodName+"Request" {
-				body.ID = formatVar(t.Name)
-				body.Arg = formatReserved(body.ID, client.ContextName)
-			}
+// returnTypes returns the return values of a method with the single success
+// response type t. Arrays are returned as iterators, which hold any error.
+func returnTypes(tr *typeRegistry, t pkg.Type, p *pkg.Package) []pkg.Type {
+	switch rt := t.(type) {
+	case nil:
+		return []pkg.Type{&pkg.IdentType{Name: "error"}}
+	case *pkg.StreamType:
+		return []pkg.Type{t, &pkg.IdentType{Name: "error"}}
+	case *pkg.SliceType:
+		elem, ok := rt.Type.(*pkg.IdentType)
+		if !ok {
+			tr.errorf("unsupported array response items")
+			return []pkg.Type{&pkg.IdentType{Name: "error"}}
+		}
+
+		return iterTypes(p, pkg.Iter{
+			Name:   elem.Name + "Iter",
+			Return: tr.indirect(rt.Type),
+		})
+	}
+
+	return []pkg.Type{tr.indirect(t), &pkg.IdentType{Name: "error"}}
+}
+
+// statusField names the field holding the body of responses with the status
+// code, ie Created for 201.
+func statusField(code int) string {
+	if text := http.StatusText(code); text != "" {
+		return formatID(text)
+	}
+
+	return fmt.Sprintf("Status%d", code)
+}
+
+// convertRequestBody converts the request body of an operation into the
+// params holding it, and any optional fields of form bodies. JSON bodies are
+// preferred, and form bodies are sent as the returned media type. Bodies with
+// neither are not yet supported, and are skipped.
+func convertRequestBody(tr *typeRegistry, def *v3.Document, methodName, typePrefix string, rb *v3.RequestBody, client *pkg.Client) ([]pkg.Param, []pkg.Field, string) {
+	if rb.Reference != "" {
+		var err error
+		if rb, err = def.ResolveRequestBody(rb.Reference); err != nil {
+			tr.errorf("%s", err)
+			return nil, nil, ""
+		}
+	}
+
+	mt := jsonMediaType(rb.Content)
+	if mt == "" {
+		if mt = formMediaType(rb.Content); mt != "" {
+			leave := tr.enter("content", mt, "schema")
+			defer leave()
+
+			params, opts := convertForm(tr, def, mt, rb.Content[mt].Schema, client, typePrefix)
+			return params, opts, mt
+		}
+
+		if len(rb.Content) > 0 {
+			tr.warnf("request body has no JSON or form media type, and is skipped")
+		}
+		return nil, nil, ""
+	}
+
+	leave := tr.enter("content", mt, "schema")
+	defer leave()
+
+	body := pkg.Param{
+		ID:   "request",
+		Arg:  "request",
+		Kind: pkg.Body,
+		Type: tr.convertSchema(rb.Content[mt].Schema, &pkg.TypeDecl{
+			Name: methodName + "Request",
+		}, false),
+	}
+
+	if t, ok := body.Type.(*pkg.IdentType); ok {
+		if t.Name != methodName+"Request" {
+			body.ID = formatVar(t.Name)
+			body.Arg = formatReserved(body.ID, client.ContextName)
+		}
+	}
+	body.Type = tr.indirect(body.Type)
+
+	return []pkg.Param{body}, nil, ""
+}
+
+func convertParameter(tr *typeRegistry, def *v3.Document, pathParams []pkg.Param, p v3.Parameter, client *pkg.Client, typePrefix string) ([]pkg.Param, []pkg.Field) {
+	typeName := typePrefix + formatID(p.Name)
+	if ref := p.Reference; ref != "" {
+		rp, err := def.ResolveParameter(ref)
+		if err != nil {
+			tr.errorf("%s", err)
+			return nil, nil
 		}
-		body.Type = tr.indirect(body.Type)
+		p = *rp
 
-		return body, nil, nil
+		// shared parameters name their types as a reference to their schema
+		// would, ie ParamName for #/components/parameters/paramName/schema.
+		tokens, _ := jsonpointer.Parse(ref) // checked when resolved
+		typeName = pointerName(append(tokens, "schema"))
+	}
+
+	switch p.In {
 	case "path":
 		for i := range pathParams {
-			if pathParams[i].ID != p.GetName() {
+			if pathParams[i].ID != p.Name {
 				continue
 			}
 
-			typ, _ := tr.typeForParameter(p)
+			typ, cf := tr.typeForParameter(&p, typeName)
 			pathParams[i].ID = formatVar(pathParams[i].ID)
 			pathParams[i].Arg = formatReserved(pathParams[i].ID, client.ContextName)
 			pathParams[i].Type = typ
+			pathParams[i].Collection = cf
+			pathParams[i].Constraints = parameterConstraints(tr, &p)
 			break
 		}
 
-		return nil, nil, nil
+		return nil, nil
 	case "header", "query":
 		k := pkg.Query
-		if p.GetIn() == "header" {
+		if p.In == "header" {
 			k = pkg.Header
 		}
 
-		typ, cf := tr.typeForParameter(p)
+		typ, cf := tr.typeForParameter(&p, typeName)
+		if k == pkg.Header && cf != pkg.None {
+			tr.errorf("array header parameter %s is not supported", p.Name)
+		}
 
-		if p.IsRequired() {
-			paramID := formatVar(p.GetName())
+		if p.Required {
+			paramID := formatVar(p.Name)
 			param := pkg.Param{
-				ID:         paramID,
-				Orig:       p.GetName(),
-				Arg:        formatReserved(paramID, client.ContextName),
-				Type:       typ,
-				Kind:       k,
-				Collection: cf,
+				ID:          paramID,
+				Orig:        p.Name,
+				Arg:         formatReserved(paramID, client.ContextName),
+				Type:        typ,
+				Kind:        k,
+				Collection:  cf,
+				Constraints: parameterConstraints(tr, &p),
 			}
-			return nil, []pkg.Param{param}, nil
+			return []pkg.Param{param}, nil
 		}
 
 		opt := pkg.Field{
-			ID:         formatID(p.GetName()),
-			Orig:       p.GetName(),
-			Type:       &pkg.PointerType{Type: typ},
-			Kind:       k,
-			Collection: cf,
+			ID:          formatID(p.Name),
+			Orig:        p.Name,
+			Type:        &pkg.PointerType{Type: typ},
+			Kind:        k,
+			Collection:  cf,
+			Constraints: parameterConstraints(tr, &p),
 		}
 
-		if p.GetDescription() != nil {
-			opt.Comment = *p.GetDescription()
+		if p.Description != nil {
+			opt.Comment = *p.Description
 		}
 
-		return nil, nil, []pkg.Field{opt}
-	case "$ref":
+		if k == pkg.Header {
+			// XXX support optional headers
+			tr.warnf("optional header parameter %s is not supported, and is skipped", p.Name)
+			return nil, nil
+		}
 
+		return nil, []pkg.Field{opt}
+	case "cookie":
+		tr.warnf("cookie parameter %s is not supported, and is skipped", p.Name)
 	}
 
-	return nil, nil, nil
+	return nil, nil
+}
+
+// parameterConstraints returns the limits on values of the parameter p.
+func parameterConstraints(tr *typeRegistry, p *v3.Parameter) *pkg.Constraints {
+	leave := tr.enter("schema")
+	defer leave()
+
+	return tr.constraintsFor(p.Schema)
+}
+
+// streamed reports if a response with content is returned as a stream, as
+// it is a binary string, or has no JSON representation.
+func streamed(content map[string]v3.MediaType) bool {
+	if mt := jsonMediaType(content); mt != "" {
+		return isFile(content[mt].Schema)
+	}
+
+	return len(content) > 0
+}
+
+// jsonSchema picks the schema of the preferred media type from content, as
+// chosen by jsonMediaType. nil is returned if none are available.
+func jsonSchema(content map[string]v3.MediaType) v3.Schema {
+	if mt := jsonMediaType(content); mt != "" {
+		return content[mt].Schema
+	}
+
+	return nil
+}
+
+// jsonMediaType picks the preferred media type from content. JSON is
+// preferred, followed by any JSON based media type, then any media type at
+// all. The empty string is returned if none are available.
+func jsonMediaType(content map[string]v3.MediaType) string {
+	if _, ok := content["application/json"]; ok {
+		return "application/json"
+	}
+
+	var types []string
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types) // for stable output
+
+	for _, t := range types {
+		if strings.Contains(t, "json") {
+			return t
+		}
+	}
+
+	if _, ok := content["*/*"]; ok {
+		return "*/*"
+	}
+
+	return ""
+}
+
+// successResponses returns the successful responses of resp: those with a 2xx
+// code, or else the default response. Responses that fail to resolve are
+// skipped, to be reported when converted.
+func successResponses(doc *v3.Document, resp *v3.Responses) []v3.Response {
+	var out []v3.Response
+	success := false
+	for code, r := range resp.Codes {
+		if code < 200 || code >= 300 {
+			continue
+		}
+
+		success = true
+		if r, err := resolveResponse(doc, r); err == nil {
+			out = append(out, r)
+		}
+	}
+
+	if !success && resp.Default != nil {
+		if r, err := resolveResponse(doc, *resp.Default); err == nil {
+			out = append(out, r)
+		}
+	}
+
+	return out
+}
+
+// resolveResponse returns the response referenced by r, or r if it is not a
+// reference.
+func resolveResponse(doc *v3.Document, r v3.Response) (v3.Response, error) {
+	if r.Reference == "" {
+		return r, nil
+	}
+
+	rr, err := doc.ResolveResponse(r.Reference)
+	if err != nil {
+		return r, err
+	}
+
+	return *rr, nil
+}
+
+// operationIDParts splits the operationId id into the words of a method name
+// for client, without any leading words of the client's name, so the
+// operation listPets of the PetsClient is ListPets, and petsList is List.
+// It returns nil if id can't name a method.
+func operationIDParts(id string, client *pkg.Client) []string {
+	var words []string
+	for w := range partsChan(id) {
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 || !unicode.IsLetter([]rune(words[0])[0]) {
+		return nil
+	}
+
+	var base []string
+	for w := range partsChan(strings.TrimSuffix(client.Name, "Client")) {
+		base = append(base, w)
+	}
+
+	// nested clients may be named after only their own resource, so try the
+	// shorter names held in the client name too, ie issuesList of the
+	// OrgsReposIssuesClient.
+	for i := range base {
+		if hasPrefixFold(words, base[i:]) && len(words) > len(base)-i {
+			return words[len(base)-i:]
+		}
+	}
+
+	return words
+}
+
+// hasPrefixFold reports if words starts with prefix, ignoring case.
+func hasPrefixFold(words, prefix []string) bool {
+	if len(prefix) > len(words) {
+		return false
+	}
+	for i := range prefix {
+		if !strings.EqualFold(words[i], prefix[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortedMethods returns the HTTP methods of handlers in order, so operations
+// are always translated, and their problems reported, in the same order.
+func sortedMethods(handlers map[string]*v3.Operation) []string {
+	methods := make([]string, 0, len(handlers))
+	for m := range handlers {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+
+	return methods
 }
 
 // methodMap converts HTTP methods to preferred method name prefixes, based on
 // which HTTP methods are supported on the given url.
 // It does not handle conversion of Get to List.
-func methodMap(methods map[string]*v2.Operation) map[string]string {
+func methodMap(methods map[string]*v3.Operation) map[string]string {
 	out := make(map[string]string)
 	for k := range methods {
 		out[k] = k
@@ -382,22 +961,13 @@ func methodMap(methods map[string]*v2.Operation) map[string]string {
 	return out
 }
 
-// resolveErrRefs resolves a given response with a refname according to doc and type registry
-// into an error type
-func resolveErrRefs(r v2.Response, doc *v2.Document, tr *typeRegistry) pkg.Type {
-	if r.Reference != "" {
-		parts := strings.Split(r.Reference, "/")
-		refname := parts[len(parts)-1]
-		r = (*doc.Responses)[refname]
-	}
-
-	t, ok := r.Schema.(*v2.ReferenceSchema)
+// resolveErrRefs resolves a given response with a refname according to the
+// type registry into an error type
+func resolveErrRefs(r v3.Response, tr *typeRegistry) pkg.Type {
+	t, ok := jsonSchema(r.Content).(*v3.ReferenceSchema)
 	if !ok {
 		return nil
 	}
 
-	parts := strings.Split(t.Reference, "/")
-	refname := parts[len(parts)-1] // XXX rename for snake case
-
-	return tr.indirect(&pkg.IdentType{Name: refname})
+	return tr.indirect(tr.refType(t.Reference))
 }
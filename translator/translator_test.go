@@ -4,22 +4,30 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/jbowes/oag/openapi/v2"
+	"github.com/lithammer/dedent"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jbowes/oag/config"
+	"github.com/jbowes/oag/diag"
+	"github.com/jbowes/oag/openapi"
+	"github.com/jbowes/oag/openapi/v3"
 	"github.com/jbowes/oag/pkg"
 )
 
 func TestConvertOperationResponses(t *testing.T) {
+	binary := "binary"
 	tcs := []struct {
-		name string
-		resp v2.Responses
-		ret  []pkg.Type
-		errs map[int]pkg.Type
+		name    string
+		resp    v3.Responses
+		ret     []pkg.Type
+		errs    map[int]pkg.Type
+		results []pkg.Result
 	}{
 		{
 			name: "204 response only",
-			resp: v2.Responses{
-				Codes: map[int]v2.Response{
-					204: {Schema: &v2.ObjectSchema{}},
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					204: {Content: map[string]v3.MediaType{"application/json": {Schema: &v3.ObjectSchema{}}}},
 				},
 			},
 			ret:  []pkg.Type{&pkg.IdentType{Name: "error"}},
@@ -27,9 +35,9 @@ func TestConvertOperationResponses(t *testing.T) {
 		},
 		{
 			name: "200 response only",
-			resp: v2.Responses{
-				Codes: map[int]v2.Response{
-					200: {Schema: &v2.ObjectSchema{}},
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					200: {Content: map[string]v3.MediaType{"application/json": {Schema: &v3.ObjectSchema{}}}},
 				},
 			},
 			ret: []pkg.Type{
@@ -40,9 +48,9 @@ func TestConvertOperationResponses(t *testing.T) {
 		},
 		{
 			name: "2XX reference iterator response",
-			resp: v2.Responses{
-				Codes: map[int]v2.Response{
-					200: {Schema: &v2.ArraySchema{Items: &v2.ReferenceSchema{Reference: "Fake"}}},
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					200: {Content: map[string]v3.MediaType{"application/json": {Schema: &v3.ArraySchema{Items: &v3.ReferenceSchema{Reference: "#/components/schemas/Fake"}}}}},
 				},
 			},
 			ret: []pkg.Type{
@@ -50,11 +58,37 @@ func TestConvertOperationResponses(t *testing.T) {
 			},
 			errs: make(map[int]pkg.Type),
 		},
+		{
+			name: "binary response",
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					200: {Content: map[string]v3.MediaType{"application/json": {Schema: &v3.StringSchema{StringFields: v3.StringFields{Format: &binary}}}}},
+				},
+			},
+			ret: []pkg.Type{
+				&pkg.StreamType{},
+				&pkg.IdentType{Name: "error"},
+			},
+			errs: make(map[int]pkg.Type),
+		},
+		{
+			name: "non JSON response",
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					200: {Content: map[string]v3.MediaType{"text/csv": {}}},
+				},
+			},
+			ret: []pkg.Type{
+				&pkg.StreamType{},
+				&pkg.IdentType{Name: "error"},
+			},
+			errs: make(map[int]pkg.Type),
+		},
 		{
 			name: "4XX reference response only",
-			resp: v2.Responses{
-				Codes: map[int]v2.Response{
-					400: {Schema: &v2.ReferenceSchema{Reference: "BadRequest"}},
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					400: {Content: map[string]v3.MediaType{"application/json": {Schema: &v3.ReferenceSchema{Reference: "#/components/schemas/BadRequest"}}}},
 				},
 			},
 			ret: []pkg.Type{
@@ -64,11 +98,69 @@ func TestConvertOperationResponses(t *testing.T) {
 				400: &pkg.PointerType{Type: &pkg.IdentType{Name: "BadRequest"}},
 			},
 		},
+		{
+			name: "2XX responses of the same type",
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					200: {Content: map[string]v3.MediaType{"application/json": {Schema: &v3.ReferenceSchema{Reference: "#/components/schemas/Pet"}}}},
+					201: {Content: map[string]v3.MediaType{"application/json": {Schema: &v3.ReferenceSchema{Reference: "#/components/schemas/Pet"}}}},
+					204: {},
+				},
+			},
+			ret: []pkg.Type{
+				&pkg.PointerType{Type: &pkg.IdentType{Name: "Pet"}},
+				&pkg.IdentType{Name: "error"},
+			},
+			errs: make(map[int]pkg.Type),
+		},
+		{
+			name: "2XX responses of different types",
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					200: {Content: map[string]v3.MediaType{"application/json": {Schema: &v3.ReferenceSchema{Reference: "#/components/schemas/Pet"}}}},
+					202: {Content: map[string]v3.MediaType{"application/json": {Schema: &v3.ArraySchema{Items: &v3.ReferenceSchema{Reference: "#/components/schemas/Job"}}}}},
+					204: {},
+				},
+				Default: &v3.Response{
+					Content: map[string]v3.MediaType{
+						"application/json": {Schema: &v3.ReferenceSchema{Reference: "#/components/schemas/Error"}},
+					},
+				},
+			},
+			ret: []pkg.Type{
+				&pkg.PointerType{Type: &pkg.IdentType{Name: "GetResult"}},
+				&pkg.IdentType{Name: "error"},
+			},
+			errs: map[int]pkg.Type{
+				-1: &pkg.PointerType{Type: &pkg.IdentType{Name: "Error"}},
+			},
+			results: []pkg.Result{{Code: 200, Field: "OK"}, {Code: 202, Field: "Accepted"}},
+		},
+		{
+			name: "Default response only",
+			resp: v3.Responses{
+				Default: &v3.Response{
+					Content: map[string]v3.MediaType{
+						"application/json": {Schema: &v3.ReferenceSchema{Reference: "#/components/schemas/Pet"}},
+					},
+				},
+			},
+			ret: []pkg.Type{
+				&pkg.PointerType{Type: &pkg.IdentType{Name: "Pet"}},
+				&pkg.IdentType{Name: "error"},
+			},
+			errs: make(map[int]pkg.Type),
+		},
 		{
 			name: "Default response",
-			resp: v2.Responses{
-				Default: &v2.Response{
-					Schema: &v2.ReferenceSchema{Reference: "Error"},
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					204: {},
+				},
+				Default: &v3.Response{
+					Content: map[string]v3.MediaType{
+						"application/json": {Schema: &v3.ReferenceSchema{Reference: "#/components/schemas/Error"}},
+					},
 				},
 			},
 			ret: []pkg.Type{
@@ -78,12 +170,25 @@ func TestConvertOperationResponses(t *testing.T) {
 				-1: &pkg.PointerType{Type: &pkg.IdentType{Name: "Error"}},
 			},
 		},
+		{
+			name: "Default response after 4XX response",
+			resp: v3.Responses{
+				Codes: map[int]v3.Response{
+					404: {},
+				},
+				Default: &v3.Response{},
+			},
+			ret: []pkg.Type{
+				&pkg.IdentType{Name: "error"},
+			},
+			errs: make(map[int]pkg.Type),
+		},
 	}
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			tr := &typeRegistry{}
-			ret, errs := convertOperationResponses(nil, tr, "Get", &tc.resp, &pkg.Package{})
+			ret, errs, results := convertOperationResponses(nil, tr, "Get", &tc.resp, false, &pkg.Package{})
 
 			if !reflect.DeepEqual(ret, tc.ret) {
 				t.Error("got:", ret, "expected:", tc.ret)
@@ -91,32 +196,130 @@ func TestConvertOperationResponses(t *testing.T) {
 			if !reflect.DeepEqual(errs, tc.errs) {
 				t.Error("got:", errs, "expected:", tc.errs)
 			}
+			if !reflect.DeepEqual(results, tc.results) {
+				t.Error("got:", results, "expected:", tc.results)
+			}
 		})
 	}
 }
 
+func TestTranslateDiagnostics(t *testing.T) {
+	var doc v3.Document
+	err := yaml.Unmarshal([]byte(dedent.Dedent(`
+        openapi: 3.0.3
+        paths:
+          /pets/{id}:
+            get:
+              parameters:
+                - name: tags
+                  in: query
+                  style: deepObject
+                  schema:
+                    type: array
+                    items:
+                      type: string
+                - name: X-Trace
+                  in: header
+                  schema:
+                    type: string
+                - $ref: '#/components/parameters/missing'
+                - name: grid
+                  in: query
+                  schema:
+                    type: array
+                    items:
+                      type: array
+                      items:
+                        type: integer
+                - name: ids
+                  in: query
+                  style: form
+                  explode: false
+                  schema:
+                    type: array
+                    items:
+                      type: integer
+              responses:
+                200:
+                  description: ok
+                  content:
+                    application/json:
+                      schema:
+                        $ref: '#/components/schemas/Pet/properties/nope'
+        components:
+          schemas:
+            Pet:
+              type: object
+              properties:
+                name:
+                  not:
+                    type: string
+                owner:
+                  $ref: '#/components/schemas/Nope'
+                home:
+                  $ref: '#/$defs/Home'
+                toy:
+                  $ref: '#/$defs/Toy'
+              $defs:
+                Home:
+                  type: string
+            PetsGetOpts:
+              type: object
+              properties:
+                tags:
+                  type: string
+        `)), &doc)
+	if err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	_, diags := Translate(&doc, &config.Config{Naming: config.NamingPath})
+
+	expected := diag.List{
+		{Pointer: "#/components/schemas/Pet/properties/name", Message: "unsupported schema not"},
+		{Pointer: "#/components/schemas/Pet/properties/owner", Message: "unresolved reference #/components/schemas/Nope"},
+		{Pointer: "#/components/schemas/Pet/properties/toy", Message: "unresolved reference #/$defs/Toy"},
+		{Pointer: "#/paths/~1pets~1{id}/get/parameters/0", Message: "unsupported style deepObject for query parameter tags"},
+		{
+			Severity: diag.Warning,
+			Pointer:  "#/paths/~1pets~1{id}/get/parameters/1",
+			Message:  "optional header parameter X-Trace is not supported, and is skipped",
+		},
+		{Pointer: "#/paths/~1pets~1{id}/get/parameters/2", Message: "unresolved reference #/components/parameters/missing"},
+		{Pointer: "#/paths/~1pets~1{id}/get/parameters/3", Message: "unsupported nested array parameter"},
+		{Pointer: "#/paths/~1pets~1{id}/get", Message: "type PetsGetOpts for optional arguments is already declared by a schema"},
+		{
+			Pointer: "#/paths/~1pets~1{id}/get/responses/200/content/application~1json/schema",
+			Message: "unresolved reference #/components/schemas/Pet/properties/nope",
+		},
+	}
+	if !reflect.DeepEqual(diags, expected) {
+		t.Error("got:", diags, "expected:", expected)
+	}
+}
+
 func TestMethodMap(t *testing.T) {
 	tcs := []struct {
 		name string
-		in   map[string]*v2.Operation
+		in   map[string]*v3.Operation
 		out  map[string]string
 	}{
-		{"get", map[string]*v2.Operation{"Get": nil}, map[string]string{"Get": "Get"}},
-		{"post", map[string]*v2.Operation{"Post": nil}, map[string]string{"Post": "Create"}},
-		{"patch", map[string]*v2.Operation{"Patch": nil}, map[string]string{"Patch": "Update"}},
+		{"get", map[string]*v3.Operation{"Get": nil}, map[string]string{"Get": "Get"}},
+		{"post", map[string]*v3.Operation{"Post": nil}, map[string]string{"Post": "Create"}},
+		{"patch", map[string]*v3.Operation{"Patch": nil}, map[string]string{"Patch": "Update"}},
 		{
 			"post and put",
-			map[string]*v2.Operation{"Post": nil, "Put": nil},
+			map[string]*v3.Operation{"Post": nil, "Put": nil},
 			map[string]string{"Post": "Create", "Put": "Update"},
 		},
 		{
 			"put and patch",
-			map[string]*v2.Operation{"Put": nil, "Patch": nil},
+			map[string]*v3.Operation{"Put": nil, "Patch": nil},
 			map[string]string{"Put": "Create", "Patch": "Update"},
 		},
 		{
 			"post, put and patch",
-			map[string]*v2.Operation{"Post": nil, "Put": nil, "Patch": nil},
+			map[string]*v3.Operation{"Post": nil, "Put": nil, "Patch": nil},
 			map[string]string{"Post": "Create", "Put": "Replace", "Patch": "Update"},
 		},
 	}
@@ -131,6 +334,205 @@ func TestMethodMap(t *testing.T) {
 	}
 }
 
+func TestOperationIDParts(t *testing.T) {
+	tcs := []struct {
+		name   string
+		id     string
+		client string
+		out    []string
+	}{
+		{"verb first", "listPets", "PetsClient", []string{"list", "Pets"}},
+		{"client prefix", "pets_list", "PetsClient", []string{"list"}},
+		{"client prefix case", "PetsList", "PetsClient", []string{"List"}},
+		{"only client", "pets", "PetsClient", []string{"pets"}},
+		{"word prefix", "petstoreList", "PetsClient", []string{"petstore", "List"}},
+		{"nested", "issues.list", "OrgsReposIssuesClient", []string{"list"}},
+		{"nested full", "orgsReposIssuesList", "OrgsReposIssuesClient", []string{"List"}},
+		{"digit", "2fa", "PetsClient", nil},
+		{"empty", "--", "PetsClient", nil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out := operationIDParts(tc.id, &pkg.Client{Name: tc.client})
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestTranslateNaming(t *testing.T) {
+	var doc v3.Document
+	err := yaml.Unmarshal([]byte(dedent.Dedent(`
+        openapi: 3.0.3
+        paths:
+          /pets:
+            get:
+              operationId: listPets
+              responses:
+                204:
+                  description: ok
+          /pets/search:
+            get:
+              operationId: pets_listPets
+              responses:
+                204:
+                  description: ok
+          /pets/{id}:
+            get:
+              responses:
+                204:
+                  description: ok
+          /pets/{id}:cancel:
+            post:
+              operationId: pets.cancel
+              responses:
+                204:
+                  description: ok
+          /pets/{id}:feed:
+            post:
+              operationId: 1feed
+              responses:
+                204:
+                  description: ok
+        `)), &doc)
+	if err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	p, diags := Translate(&doc, &config.Config{Naming: config.NamingOperationID})
+
+	expectedDiags := diag.List{
+		{
+			Severity: diag.Warning,
+			Pointer:  "#/paths/~1pets~1{id}:feed/post",
+			Message:  "operationId \"1feed\" is not a valid method name, and the path is used instead",
+		},
+		{
+			Pointer: "#/paths/~1pets~1search/get",
+			Message: "method ListPets of PetsClient is also generated for GET /pets",
+		},
+	}
+	if !reflect.DeepEqual(diags, expectedDiags) {
+		t.Error("got:", diags, "expected:", expectedDiags)
+	}
+
+	var names []string
+	for _, m := range p.Clients[0].Methods {
+		names = append(names, m.Name)
+	}
+	expected := []string{"Cancel", "CreateFeed", "Get", "ListPets"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Error("got:", names, "expected:", expected)
+	}
+}
+
+func TestTranslateNamingV2(t *testing.T) {
+	doc, err := openapi.Load([]byte(dedent.Dedent(`
+        swagger: "2.0"
+        info:
+          title: pets
+          version: "1"
+        paths:
+          /pets:
+            get:
+              operationId: findAllPets
+              responses:
+                204:
+                  description: ok
+          /pets/{id}:
+            get:
+              operationId: findPetById
+              parameters:
+                - name: id
+                  in: path
+                  required: true
+                  type: integer
+              responses:
+                204:
+                  description: ok
+        `)))
+	if err != nil {
+		t.Fatal("could not load. got error:", err)
+	}
+
+	p, diags := Translate(doc, &config.Config{Naming: config.NamingOperationID})
+	if len(diags) > 0 {
+		t.Fatal("unexpected diagnostics:", diags)
+	}
+
+	var names []string
+	for _, m := range p.Clients[0].Methods {
+		names = append(names, m.Name)
+	}
+	expected := []string{"FindAllPets", "FindPetByID"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Error("got:", names, "expected:", expected)
+	}
+}
+
+func TestTranslateListNames(t *testing.T) {
+	var doc v3.Document
+	err := yaml.Unmarshal([]byte(dedent.Dedent(`
+        openapi: 3.0.3
+        paths:
+          /pets:
+            get:
+              responses:
+                default:
+                  description: ok
+                  content:
+                    application/json:
+                      schema:
+                        type: array
+                        items:
+                          $ref: '#/components/schemas/Pet'
+          /toys:
+            get:
+              responses:
+                204:
+                  description: ok
+                default:
+                  description: error
+                  content:
+                    application/json:
+                      schema:
+                        type: array
+                        items:
+                          $ref: '#/components/schemas/Pet'
+        components:
+          schemas:
+            Pet:
+              type: object
+              properties:
+                message:
+                  type: string
+        `)), &doc)
+	if err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	p, diags := Translate(&doc, &config.Config{Naming: config.NamingPath})
+	if len(diags) > 0 {
+		t.Fatal("unexpected diagnostics:", diags)
+	}
+	if len(p.Clients) != 2 {
+		t.Fatal("expected 2 clients. got:", p.Clients)
+	}
+
+	for _, c := range p.Clients {
+		m := c.Methods[0]
+		_, iter := m.Return[0].(*pkg.IterType)
+		switch {
+		case c.Name == "PetsClient" && (m.Name != "List" || !iter):
+			t.Error("expected List returning an iterator. got:", m.Name, m.Return)
+		case c.Name == "ToysClient" && (m.Name != "Get" || iter):
+			t.Error("expected Get returning an error. got:", m.Name, m.Return)
+		}
+	}
+}
+
 func TestFormatReserved(t *testing.T) {
 	tcs := []struct {
 		name    string
@@ -140,6 +542,9 @@ func TestFormatReserved(t *testing.T) {
 	}{
 		{"not reserved", "value", "testing", "value"},
 		{"reserved", "type", "testing", "testingType"},
+		{"form local", "buf", "testing", "testingBuf"},
+		{"header local", "meta", "testing", "testingMeta"},
+		{"result local", "result", "testing", "testingResult"},
 	}
 
 	for _, tc := range tcs {
@@ -150,3 +555,513 @@ func TestFormatReserved(t *testing.T) {
 		})
 	}
 }
+
+func TestOperationParameters(t *testing.T) {
+	var doc v3.Document
+	err := yaml.Unmarshal([]byte(dedent.Dedent(`
+        openapi: 3.0.3
+        components:
+          parameters:
+            limit:
+              name: limit
+              in: query
+              schema:
+                type: integer
+        `)), &doc)
+	if err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	param := func(name, in string) v3.Parameter { return v3.Parameter{Name: name, In: in} }
+	ref := v3.Parameter{Reference: "#/components/parameters/limit"}
+	at := []string{"paths", "/orgs/{id}", "get"}
+
+	tcs := []struct {
+		name       string
+		pathParams []v3.Parameter
+		params     []v3.Parameter
+		out        []opParam
+	}{
+		{"none", nil, nil, []opParam{}},
+		{
+			"operation only",
+			nil,
+			[]v3.Parameter{param("limit", "query")},
+			[]opParam{{param("limit", "query"), []string{"paths", "/orgs/{id}", "get", "parameters", "0"}}},
+		},
+		{
+			"path item only",
+			[]v3.Parameter{param("id", "path")},
+			nil,
+			[]opParam{{param("id", "path"), []string{"paths", "/orgs/{id}", "parameters", "0"}}},
+		},
+		{
+			"merged",
+			[]v3.Parameter{param("id", "path"), param("X-Trace", "header")},
+			[]v3.Parameter{param("limit", "query")},
+			[]opParam{
+				{param("limit", "query"), []string{"paths", "/orgs/{id}", "get", "parameters", "0"}},
+				{param("id", "path"), []string{"paths", "/orgs/{id}", "parameters", "0"}},
+				{param("X-Trace", "header"), []string{"paths", "/orgs/{id}", "parameters", "1"}},
+			},
+		},
+		{
+			"overridden",
+			[]v3.Parameter{param("id", "path"), param("limit", "query")},
+			[]v3.Parameter{param("limit", "query")},
+			[]opParam{
+				{param("limit", "query"), []string{"paths", "/orgs/{id}", "get", "parameters", "0"}},
+				{param("id", "path"), []string{"paths", "/orgs/{id}", "parameters", "0"}},
+			},
+		},
+		{
+			"same name in another location",
+			[]v3.Parameter{param("limit", "header")},
+			[]v3.Parameter{param("limit", "query")},
+			[]opParam{
+				{param("limit", "query"), []string{"paths", "/orgs/{id}", "get", "parameters", "0"}},
+				{param("limit", "header"), []string{"paths", "/orgs/{id}", "parameters", "0"}},
+			},
+		},
+		{
+			"overridden by reference",
+			[]v3.Parameter{param("limit", "query")},
+			[]v3.Parameter{ref},
+			[]opParam{{ref, []string{"paths", "/orgs/{id}", "get", "parameters", "0"}}},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out := operationParameters(&doc, at, tc.pathParams, &v3.Operation{Parameters: tc.params})
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestResolvePathItem(t *testing.T) {
+	var doc v3.Document
+	err := yaml.Unmarshal([]byte(dedent.Dedent(`
+        openapi: 3.1.0
+        paths:
+          /pets:
+            $ref: '#/components/pathItems/pets'
+          /animals:
+            $ref: '#/paths/~1pets'
+            post:
+              operationId: createAnimal
+          /loop:
+            $ref: '#/paths/~1loop'
+          /missing:
+            $ref: '#/components/pathItems/missing'
+        components:
+          pathItems:
+            pets:
+              parameters:
+                - name: limit
+                  in: query
+              get:
+                operationId: listPets
+              post:
+                operationId: createPet
+        `)), &doc)
+	if err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	listPets := "listPets"
+	createPet := "createPet"
+	createAnimal := "createAnimal"
+	params := []v3.Parameter{{Name: "limit", In: "query"}}
+
+	tcs := []struct {
+		name string
+		path string
+		out  v3.PathItem
+		err  bool
+	}{
+		{"components", "/pets", v3.PathItem{
+			Parameters: params,
+			Get:        &v3.Operation{OperationID: &listPets},
+			Post:       &v3.Operation{OperationID: &createPet},
+		}, false},
+		{"other path with override", "/animals", v3.PathItem{
+			Parameters: params,
+			Get:        &v3.Operation{OperationID: &listPets},
+			Post:       &v3.Operation{OperationID: &createAnimal},
+		}, false},
+		{"cycle", "/loop", v3.PathItem{}, true},
+		{"missing", "/missing", v3.PathItem{}, true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := resolvePathItem(&doc, doc.Paths[tc.path])
+			if tc.err {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("could not resolve. got error:", err)
+			}
+
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
+func TestTranslatePolymorphic(t *testing.T) {
+	var doc v3.Document
+	err := yaml.Unmarshal([]byte(dedent.Dedent(`
+        openapi: 3.0.3
+        paths:
+          /events/{id}:
+            get:
+              parameters:
+                - name: id
+                  in: path
+                  required: true
+                  schema:
+                    type: string
+              responses:
+                200:
+                  description: ok
+                  content:
+                    application/json:
+                      schema:
+                        $ref: '#/components/schemas/Event'
+        components:
+          schemas:
+            Event:
+              type: object
+              required: [kind]
+              properties:
+                kind:
+                  type: string
+                parent:
+                  $ref: '#/components/schemas/Event'
+              discriminator:
+                propertyName: kind
+                mapping:
+                  tap: '#/components/schemas/ClickEvent'
+                  gone: '#/components/schemas/Missing'
+            ClickEvent:
+              allOf:
+                - $ref: '#/components/schemas/Event'
+                - type: object
+                  required: [x]
+                  properties:
+                    x:
+                      type: integer
+            Plain:
+              type: object
+              properties:
+                kind:
+                  type: string
+              discriminator:
+                propertyName: kind
+        `)), &doc)
+	if err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	p, diags := Translate(&doc, &config.Config{Naming: config.NamingPath})
+
+	expectedDiags := diag.List{
+		{Pointer: "#/components/schemas/Event/discriminator/mapping/gone", Message: "mapping to unknown schema Missing"},
+	}
+	if !reflect.DeepEqual(diags, expectedDiags) {
+		t.Error("got:", diags, "expected:", expectedDiags)
+	}
+
+	decls := make(map[string]pkg.TypeDecl)
+	for _, d := range p.TypeDecls {
+		decls[d.Name] = d
+	}
+
+	expectedDisc := &pkg.Discriminator{
+		Property: "kind",
+		Base:     "EventBase",
+		Variants: []pkg.Variant{{Value: "tap", Type: "ClickEvent"}},
+	}
+	if d := decls["Event"].Discriminator; !reflect.DeepEqual(d, expectedDisc) {
+		t.Error("got:", d, "expected:", expectedDisc)
+	}
+
+	if base, ok := decls["EventBase"].Type.(*pkg.StructType); !ok {
+		t.Error("expected base struct. got:", decls["EventBase"].Type)
+	} else if typ := base.Fields[1].Type; !typ.Equal(&pkg.IdentType{Name: "Event"}) {
+		t.Error("expected unwrapped interface field. got:", typ)
+	}
+
+	if click, ok := decls["ClickEvent"].Type.(*pkg.StructType); !ok {
+		t.Error("expected variant struct. got:", decls["ClickEvent"].Type)
+	} else if typ := click.Fields[0].Type; !typ.Equal(&pkg.IdentType{Name: "EventBase"}) {
+		t.Error("expected embedded base. got:", typ)
+	}
+
+	if d := decls["Plain"].Discriminator; d != nil {
+		t.Error("expected no discriminator without variants. got:", d)
+	}
+
+	ret := p.Clients[0].Methods[0].Return[0]
+	if !ret.Equal(&pkg.IdentType{Name: "Event"}) {
+		t.Error("expected interface return. got:", ret)
+	}
+}
+
+func TestTranslatePolymorphicV2(t *testing.T) {
+	doc, err := openapi.Load([]byte(dedent.Dedent(`
+        swagger: "2.0"
+        info:
+          title: events
+          version: "1"
+        paths:
+          /events/{id}:
+            get:
+              parameters:
+                - name: id
+                  in: path
+                  required: true
+                  type: string
+              responses:
+                200:
+                  description: ok
+                  schema:
+                    $ref: '#/definitions/Event'
+        definitions:
+          Event:
+            type: object
+            required: [kind]
+            discriminator: kind
+            properties:
+              kind:
+                type: string
+          ClickEvent:
+            allOf:
+              - $ref: '#/definitions/Event'
+              - type: object
+                properties:
+                  x:
+                    type: integer
+        `)))
+	if err != nil {
+		t.Fatal("could not load. got error:", err)
+	}
+
+	p, diags := Translate(doc, &config.Config{Naming: config.NamingPath})
+	if len(diags) > 0 {
+		t.Fatal("unexpected diagnostics:", diags)
+	}
+
+	expected := &pkg.Discriminator{
+		Property: "kind",
+		Base:     "EventBase",
+		Variants: []pkg.Variant{{Value: "ClickEvent", Type: "ClickEvent"}},
+	}
+	for _, d := range p.TypeDecls {
+		if d.Name == "Event" && !reflect.DeepEqual(d.Discriminator, expected) {
+			t.Error("got:", d.Discriminator, "expected:", expected)
+		}
+	}
+
+	ret := p.Clients[0].Methods[0].Return[0]
+	if !ret.Equal(&pkg.IdentType{Name: "Event"}) {
+		t.Error("expected interface return. got:", ret)
+	}
+}
+
+func TestTranslateConstraintsV2(t *testing.T) {
+	doc, err := openapi.Load([]byte(dedent.Dedent(`
+        swagger: "2.0"
+        info:
+          title: pets
+          version: "1"
+        paths:
+          /pets:
+            get:
+              parameters:
+                - name: limit
+                  in: query
+                  required: true
+                  type: integer
+                  minimum: 1
+                  maximum: 50
+                  exclusiveMaximum: true
+              responses:
+                200:
+                  description: ok
+                  schema:
+                    $ref: '#/definitions/Pet'
+        definitions:
+          Pet:
+            type: object
+            properties:
+              name:
+                type: string
+                minLength: 1
+                maxLength: 10
+              weight:
+                type: number
+                multipleOf: 0.5
+              tags:
+                type: array
+                minItems: 1
+                maxItems: 3
+                items:
+                  type: string
+                  maxLength: 5
+        `)))
+	if err != nil {
+		t.Fatal("could not load. got error:", err)
+	}
+
+	p, diags := Translate(doc, &config.Config{Naming: config.NamingPath})
+	if len(diags) > 0 {
+		t.Fatal("unexpected diagnostics:", diags)
+	}
+
+	one, five, ten := int64(1), int64(5), int64(10)
+	onef, fifty, half := 1.0, 50.0, 0.5
+	minItems, maxItems := uint64(1), uint64(3)
+
+	expected := map[string]*pkg.Constraints{
+		"Name":   {MinLength: &one, MaxLength: &ten},
+		"Weight": {MultipleOf: &half},
+		"Tags":   {MinItems: &minItems, MaxItems: &maxItems, Items: &pkg.Constraints{MaxLength: &five}},
+	}
+	for _, d := range p.TypeDecls {
+		if d.Name != "Pet" {
+			continue
+		}
+		for _, f := range d.Type.(*pkg.StructType).Fields {
+			if !reflect.DeepEqual(f.Constraints, expected[f.ID]) {
+				t.Errorf("%s: got: %+v expected: %+v", f.ID, f.Constraints, expected[f.ID])
+			}
+		}
+	}
+
+	limit := p.Clients[0].Methods[0].Params[0]
+	expectedLimit := &pkg.Constraints{Minimum: &onef, Maximum: &fifty, ExclusiveMaximum: true}
+	if !reflect.DeepEqual(limit.Constraints, expectedLimit) {
+		t.Errorf("limit: got: %+v expected: %+v", limit.Constraints, expectedLimit)
+	}
+}
+
+func TestTranslateUnion(t *testing.T) {
+	var doc v3.Document
+	err := yaml.Unmarshal([]byte(dedent.Dedent(`
+        openapi: 3.0.3
+        paths: {}
+        components:
+          schemas:
+            Card:
+              type: object
+              properties:
+                type:
+                  type: string
+            BankAccount:
+              type: object
+              properties:
+                type:
+                  type: string
+            PaymentMethod:
+              oneOf:
+                - $ref: '#/components/schemas/Card'
+                - $ref: '#/components/schemas/BankAccount'
+                - type: string
+                - type: string
+                - title: other
+                  type: object
+                  properties:
+                    name:
+                      type: string
+              discriminator:
+                propertyName: type
+                mapping:
+                  card: '#/components/schemas/Card'
+                  credit: Card
+                  gone: '#/components/schemas/Missing'
+        `)), &doc)
+	if err != nil {
+		t.Fatal("could not unmarshal. got error:", err)
+	}
+
+	p, diags := Translate(&doc, &config.Config{Naming: config.NamingPath})
+
+	expectedDiags := diag.List{
+		{
+			Severity: diag.Warning,
+			Pointer:  "#/components/schemas/PaymentMethod/oneOf/3",
+			Message:  "variant has the same type as variant String, and is skipped",
+		},
+		{Pointer: "#/components/schemas/PaymentMethod/discriminator/mapping/gone", Message: "mapping to schema Missing, which is not a variant"},
+	}
+	if !reflect.DeepEqual(diags, expectedDiags) {
+		t.Error("got:", diags, "expected:", expectedDiags)
+	}
+
+	expected := &pkg.UnionType{
+		Discriminator: "type",
+		Variants: []pkg.UnionVariant{
+			{Name: "Card", Type: &pkg.IdentType{Name: "Card"}, Values: []string{"card", "credit"}},
+			{Name: "BankAccount", Type: &pkg.IdentType{Name: "BankAccount"}, Values: []string{"BankAccount"}},
+			{Name: "String", Type: &pkg.IdentType{Name: "string"}},
+			{Name: "Other", Type: &pkg.IdentType{Name: "PaymentMethodOther"}},
+		},
+	}
+
+	for _, d := range p.TypeDecls {
+		if d.Name != "PaymentMethod" {
+			continue
+		}
+
+		if !reflect.DeepEqual(d.Type, expected) {
+			t.Error("got:", d.Type, "expected:", expected)
+		}
+		if d.Comment != "PaymentMethod is a data type for API communication.\n\nIt holds one of: Card, BankAccount, String, Other." {
+			t.Error("got comment:", d.Comment)
+		}
+		return
+	}
+
+	t.Error("PaymentMethod not declared")
+}
+
+func TestCivilDateName(t *testing.T) {
+	tcs := []struct {
+		name    string
+		schemas []string
+		out     string
+	}{
+		{"no schemas", nil, "Date"},
+		{"no conflict", []string{"Pet"}, "Date"},
+		{"conflict", []string{"Pet", "date"}, "CivilDate"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := &v3.Document{}
+			if tc.schemas != nil {
+				var schemas v3.SchemaMap
+				for _, s := range tc.schemas {
+					schemas = append(schemas, struct {
+						Name   string
+						Schema v3.Schema
+					}{s, &v3.ObjectSchema{}})
+				}
+				doc.Components = &v3.Components{Schemas: &schemas}
+			}
+
+			if out := civilDateName(doc); out != tc.out {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
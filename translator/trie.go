@@ -10,7 +10,7 @@ import (
 	"sort"
 	"unicode/utf8"
 
-	"github.com/jbowes/oag/openapi/v2"
+	"github.com/jbowes/oag/openapi/v3"
 )
 
 type (
@@ -25,15 +25,16 @@ func (l literal) value() string { return string(l) }
 func (p param) value() string   { return "{" + string(p) + "}" }
 
 type node struct {
-	prefix   token
-	handlers map[string]*v2.Operation
+	prefix     token
+	handlers   map[string]*v3.Operation
+	parameters []v3.Parameter // shared by all handlers
 
 	// The two child types
 	literals []*node
 	params   []*node
 }
 
-func (n *node) add(path string, pi *v2.PathItem) {
+func (n *node) add(path string, pi *v3.PathItem) {
 	tokens := tokenize(path)
 	tok := tokens[0]
 	switch tok.(type) {
@@ -44,7 +45,7 @@ func (n *node) add(path string, pi *v2.PathItem) {
 	}
 }
 
-func addChild(path string, tokens []token, children []*node, pi *v2.PathItem) []*node {
+func addChild(path string, tokens []token, children []*node, pi *v3.PathItem) []*node {
 	tok := tokens[0]
 	for _, child := range children {
 		if child.prefix != tok {
@@ -73,8 +74,33 @@ func addChild(path string, tokens []token, children []*node, pi *v2.PathItem) []
 	return children
 }
 
-func (n *node) setHandlers(pi *v2.PathItem) {
-	n.handlers = make(map[string]*v2.Operation)
+// find returns the descendant of n at path, or nil if there is none.
+func (n *node) find(path []token) *node {
+	for _, tok := range path {
+		children := n.literals
+		if _, ok := tok.(param); ok {
+			children = n.params
+		}
+
+		var next *node
+		for _, child := range children {
+			if child.prefix == tok {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+
+	return n
+}
+
+func (n *node) setHandlers(pi *v3.PathItem) {
+	n.parameters = pi.Parameters
+	n.handlers = make(map[string]*v3.Operation)
 	if pi.Get != nil {
 		n.handlers["Get"] = pi.Get
 	}
@@ -96,6 +122,9 @@ func (n *node) setHandlers(pi *v2.PathItem) {
 	if pi.Head != nil {
 		n.handlers["Head"] = pi.Head
 	}
+	if pi.Trace != nil {
+		n.handlers["Trace"] = pi.Trace
+	}
 }
 
 type visited struct {
@@ -116,14 +145,18 @@ func (n *node) visit() <-chan *visited {
 			cur, stack = stack[end], stack[:end]
 
 			c <- cur
+
+			// Each child's path needs its own copy, or siblings would share
+			// (and overwrite) the same backing array.
+			path := cur.path[:len(cur.path):len(cur.path)]
 			for i := 0; i < len(cur.n.literals); i++ {
 				child := cur.n.literals[i]
-				stack = append(stack, &visited{append(cur.path, child.prefix), child})
+				stack = append(stack, &visited{append(path, child.prefix), child})
 			}
 
 			for i := 0; i < len(cur.n.params); i++ {
 				child := cur.n.params[i]
-				stack = append(stack, &visited{append(cur.path, child.prefix), child})
+				stack = append(stack, &visited{append(path, child.prefix), child})
 			}
 		}
 
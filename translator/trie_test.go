@@ -4,22 +4,23 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/jbowes/oag/openapi/v2"
+	"github.com/jbowes/oag/openapi/v3"
 )
 
 func TestAdd(t *testing.T) {
-	op := &v2.Operation{}
+	op := &v3.Operation{}
 
 	out := &node{}
-	out.add("/parent/child", &v2.PathItem{
+	out.add("/parent/child", &v3.PathItem{
 		Get:     op,
 		Put:     op,
 		Patch:   op,
 		Delete:  op,
 		Head:    op,
 		Options: op,
+		Trace:   op,
 	})
-	out.add("/parent/{id}", &v2.PathItem{Post: op})
+	out.add("/parent/{id}", &v3.PathItem{Post: op})
 
 	expected := &node{
 		literals: []*node{{
@@ -32,18 +33,19 @@ func TestAdd(t *testing.T) {
 							prefix: literal("/"),
 							literals: []*node{{
 								prefix: literal("child"),
-								handlers: map[string]*v2.Operation{
+								handlers: map[string]*v3.Operation{
 									"Get":     op,
 									"Put":     op,
 									"Patch":   op,
 									"Delete":  op,
 									"Head":    op,
 									"Options": op,
+									"Trace":   op,
 								},
 							}},
 							params: []*node{{
 								prefix: param("id"),
-								handlers: map[string]*v2.Operation{
+								handlers: map[string]*v3.Operation{
 									"Post": op,
 								},
 							}},
@@ -69,7 +71,13 @@ func TestVisit(t *testing.T) {
 						{
 							prefix:   literal("/"),
 							literals: []*node{{prefix: literal("child")}},
-							params:   []*node{{prefix: param("id")}},
+							params: []*node{{
+								prefix: param("id"),
+								literals: []*node{{
+									prefix:   literal("/"),
+									literals: []*node{{prefix: literal("members")}, {prefix: literal("teams")}},
+								}},
+							}},
 						},
 					},
 				},
@@ -97,6 +105,9 @@ func TestVisit(t *testing.T) {
 		nilTokenize("/parent"),
 		nilTokenize("/parent/"),
 		nilTokenize("/parent/{id}"),
+		nilTokenize("/parent/{id}/"),
+		nilTokenize("/parent/{id}/teams"),
+		nilTokenize("/parent/{id}/members"),
 		nilTokenize("/parent/child"),
 	}
 	if !reflect.DeepEqual(out, expected) {
@@ -0,0 +1,133 @@
+package translator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gedex/inflector"
+
+	"github.com/jbowes/oag/openapi/v3"
+	"github.com/jbowes/oag/pkg"
+)
+
+// convertUnion declares td as a union of the schemas variants, listed under
+// kind (oneOf or anyOf). Variants are named after their type, and selected by
+// the values of disc, if set. Variants with the same type as an earlier
+// variant can't be told apart, and are skipped.
+//
+// XXX variants of named primitive types are compared before they are inlined.
+func (tr *typeRegistry) convertUnion(kind string, variants []v3.Schema, disc *v3.Discriminator, td *pkg.TypeDecl) pkg.Type {
+	u := &pkg.UnionType{}
+	if disc != nil {
+		u.Discriminator = disc.PropertyName
+	}
+
+	// Mapped values replace the default value of a schema, its name.
+	mapped := make(map[string][]string)
+	if disc != nil {
+		for value, target := range disc.Mapping {
+			target = strings.TrimPrefix(target, schemaPrefix)
+			mapped[target] = append(mapped[target], value)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, s := range variants {
+		if _, ok := s.(*v3.NullSchema); ok {
+			continue
+		}
+
+		sn := fmt.Sprintf("%s%s%d", td.Name, formatID(kind), i)
+		if t := s.GetTitle(); t != nil {
+			sn = td.Name + formatID(*t)
+		}
+
+		leave := tr.enter(kind, strconv.Itoa(i))
+		v := pkg.UnionVariant{
+			Type: tr.convertSchema(s, &pkg.TypeDecl{
+				Name:    sn,
+				Comment: fmt.Sprintf("%s is a data type for API communication.", sn),
+			}, false),
+		}
+
+		dupe := false
+		for _, o := range u.Variants {
+			if o.Type.Equal(v.Type) {
+				tr.warnf("variant has the same type as variant %s, and is skipped", o.Name)
+				dupe = true
+				break
+			}
+		}
+		leave()
+		if dupe {
+			continue
+		}
+
+		v.Name = variantName(td.Name, v.Type)
+		for n := 2; names[v.Name]; n++ {
+			v.Name = variantName(td.Name, v.Type) + strconv.Itoa(n)
+		}
+		names[v.Name] = true
+
+		if r, ok := s.(*v3.ReferenceSchema); ok && disc != nil && strings.HasPrefix(r.Reference, schemaPrefix) {
+			target := r.Reference[len(schemaPrefix):]
+			v.Values = mapped[target]
+			if v.Values == nil {
+				v.Values = []string{target}
+			}
+			delete(mapped, target)
+		}
+
+		u.Variants = append(u.Variants, v)
+	}
+
+	for target, values := range mapped {
+		for _, value := range values {
+			leave := tr.enter("discriminator", "mapping", value)
+			tr.errorf("mapping to schema %s, which is not a variant", target)
+			leave()
+		}
+	}
+
+	for _, v := range u.Variants {
+		sort.Strings(v.Values)
+	}
+
+	var kinds []string
+	for _, v := range u.Variants {
+		kinds = append(kinds, v.Name)
+	}
+	if td.Comment == "" {
+		td.Comment = fmt.Sprintf("%s is a data type for API communication.", td.Name)
+	}
+	td.Comment += fmt.Sprintf("\n\nIt holds one of: %s.", strings.Join(kinds, ", "))
+
+	td.Type = u
+	tr.add(*td)
+
+	return &pkg.IdentType{Name: td.Name}
+}
+
+// variantName names a variant of the union owner with type t, dropping the
+// name of the union from variants declared for it.
+func variantName(owner string, t pkg.Type) string {
+	switch tt := t.(type) {
+	case *pkg.IdentType:
+		if tt.Qualifier == "" {
+			if n := strings.TrimPrefix(tt.Name, owner); n != tt.Name && n != "" {
+				return n
+			}
+		}
+		return formatID(tt.Name)
+	case *pkg.PointerType:
+		return variantName(owner, tt.Type)
+	case *pkg.SliceType:
+		return inflector.Pluralize(variantName(owner, tt.Type))
+	case *pkg.MapType:
+		return variantName(owner, tt.Value) + "Map"
+	default:
+		return "Value"
+	}
+}
@@ -23,6 +23,9 @@ func defineClient(f *jen.File, subclients []pkg.Client, prefix string) {
 		)
 		g.Line()
 		for _, c := range subclients {
+			if c.Parent != "" {
+				continue
+			}
 			bare := c.Name[0 : len(c.Name)-len("Client")]
 			g.Id(bare).Op("*").Id(c.Name)
 		}
@@ -38,8 +41,11 @@ func defineClient(f *jen.File, subclients []pkg.Client, prefix string) {
 		g.Line()
 
 		for _, c := range subclients {
+			if c.Parent != "" {
+				continue
+			}
 			bare := c.Name[0 : len(c.Name)-len("Client")]
-			g.Id("c").Dot(bare).Op("=").Parens(jen.Op("*").Id(c.Name)).Parens(jen.Op("&").Id("c").Dot("common"))
+			setClient(g, jen.Id("c").Dot(bare), &c, subclients)
 		}
 		g.Line()
 
@@ -47,7 +53,54 @@ func defineClient(f *jen.File, subclients []pkg.Client, prefix string) {
 	})
 }
 
-func defineBackend(f *jen.File, prefix string) {
+// defineClientType defines the type of the client c. Clients holding nested
+// clients embed the shared endpoint, with a field for each nested client.
+func defineClientType(f *jen.File, c *pkg.Client, clients []pkg.Client) {
+	nested := nestedClients(c, clients)
+	if len(nested) == 0 {
+		f.Type().Id(c.Name).Id("endpoint")
+		return
+	}
+
+	f.Type().Id(c.Name).StructFunc(func(g *jen.Group) {
+		g.Op("*").Id("endpoint")
+		g.Line()
+		for _, n := range nested {
+			g.Id(n.Field).Op("*").Id(n.Name)
+		}
+	})
+}
+
+// setClient sets v to the client c on the shared endpoint, along with any
+// clients nested in it.
+func setClient(g *jen.Group, v *jen.Statement, c *pkg.Client, clients []pkg.Client) {
+	nested := nestedClients(c, clients)
+	if len(nested) == 0 {
+		g.Add(v).Op("=").Parens(jen.Op("*").Id(c.Name)).Parens(jen.Op("&").Id("c").Dot("common"))
+		return
+	}
+
+	g.Add(v).Op("=").Op("&").Id(c.Name).Values(jen.Dict{
+		jen.Id("endpoint"): jen.Op("&").Id("c").Dot("common"),
+	})
+	for i := range nested {
+		setClient(g, v.Clone().Dot(nested[i].Field), &nested[i], clients)
+	}
+}
+
+// nestedClients returns the clients held by c.
+func nestedClients(c *pkg.Client, clients []pkg.Client) []pkg.Client {
+	var nested []pkg.Client
+	for _, n := range clients {
+		if n.Parent == c.Name {
+			nested = append(nested, n)
+		}
+	}
+
+	return nested
+}
+
+func defineBackend(f *jen.File, prefix string, streams bool) {
 	newReqSig := jen.Id("NewRequest").Params(
 		jen.Id("method"),
 		jen.Id("path").Id("string"),
@@ -65,14 +118,24 @@ func defineBackend(f *jen.File, prefix string) {
 		jen.Error(),
 	)
 
-	f.Comment(formatComment(`
-		Backend defines the low-level interface for communicating with the remote api.
-	`))
-	f.Type().Id("Backend").Interface(
-		newReqSig.Clone(),
-		doSig.Clone(),
+	doStreamSig := jen.Id("DoStream").Params(
+		jen.Id("ctx").Qual("context", "Context"),
+		jen.Id("request").Op("*").Qual("net/http", "Request"),
+		jen.Id("errFn").Func().Params(jen.Int()).Params(jen.Error()),
+	).Params(
+		jen.Op("*").Qual("net/http", "Response"),
+		jen.Error(),
 	)
 
+	comment := "Backend defines the low-level interface for communicating with the remote api."
+	sigs := []jen.Code{newReqSig.Clone(), doSig.Clone()}
+	if streams {
+		comment += "\n\nDoStream returns successful responses with their body unread, to be closed\nby the caller."
+		sigs = append(sigs, doStreamSig.Clone())
+	}
+	f.Comment(formatComment(comment))
+	f.Type().Id("Backend").Interface(sigs...)
+
 	f.Comment(formatComment(`
 		DefaultBackend returns an instance of the default Backend configuration.
 	`))
@@ -96,6 +159,13 @@ func defineBackend(f *jen.File, prefix string) {
 	f.Func().Params(jen.Id("b").Op("*").Id("defaultBackend")).Add(doSig.Clone()).BlockFunc(
 		defineDo,
 	)
+
+	if streams {
+		f.Line()
+		f.Func().Params(jen.Id("b").Op("*").Id("defaultBackend")).Add(doStreamSig.Clone()).BlockFunc(
+			defineDoStream,
+		)
+	}
 }
 
 func defineNewRequest(g *jen.Group) {
@@ -177,3 +247,93 @@ func defineDo(g *jen.Group) {
 
 	g.Return(jen.Id("resp"), jen.Nil())
 }
+
+func defineDoStream(g *jen.Group) {
+	g.Id("request").Op("=").Id("request").Dot("WithContext").Call(jen.Id("ctx"))
+	g.Line()
+
+	g.List(jen.Id("resp"), jen.Err()).Op(":=").Id("b").Dot("client").Dot("Do").Call(jen.Id("request"))
+	g.If(jen.Err().Op("!=").Nil()).Block(
+		jen.Return(jen.Nil(), jen.Err()),
+	)
+	g.Line()
+
+	g.If(jen.Id("resp").Dot("StatusCode").Op(">=").Lit(300)).BlockFunc(func(g *jen.Group) {
+		g.Defer().Id("resp").Dot("Body").Dot("Close").Call()
+		g.Line()
+
+		// Unlike Do, there is no empty value to return for a stream.
+		g.Var().Id("apiErr").Error()
+		g.If(jen.Id("errFn").Op("!=").Nil()).Block(
+			jen.Id("apiErr").Op("=").Id("errFn").Call(jen.Id("resp").Dot("StatusCode")),
+		)
+		g.If(jen.Id("apiErr").Op("==").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("unexpected response status: %s"), jen.Id("resp").Dot("Status"))),
+		)
+		g.Line()
+
+		g.Id("dec").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Id("resp").Dot("Body"))
+		g.If(jen.Err().Op(":=").Id("dec").Dot("Decode").Call(jen.Id("apiErr")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		)
+
+		g.Return(jen.Nil(), jen.Id("apiErr"))
+	})
+	g.Line()
+
+	g.Return(jen.Id("resp"), jen.Nil())
+}
+
+// defineStream defines the Stream type, for response bodies returned unread.
+func defineStream(f *jen.File) {
+	f.Comment(formatComment(`
+		Stream is a response body returned unread. It must be closed once read.
+	`))
+	f.Type().Id("Stream").Struct(
+		jen.Qual("io", "ReadCloser"),
+		jen.Line(),
+		jen.Id("ContentType").String(),
+		jen.Id("ContentLength").Int64().Comment("-1 if unknown"),
+	)
+}
+
+// defineDate defines the civil date type name, used for the date format.
+func defineDate(f *jen.File, name string) {
+	f.Comment(formatComment(`
+		%s is a calendar date, without a time or time zone. It is encoded in the
+		form 2006-01-02.
+	`, name))
+	f.Type().Id(name).Struct(
+		jen.Id("Year").Int(),
+		jen.Id("Month").Qual("time", "Month"),
+		jen.Id("Day").Int(),
+	)
+	f.Line()
+
+	f.Comment(formatComment("%sOf returns the %s of t, in its location.", name, name))
+	f.Func().Id(name+"Of").Params(jen.Id("t").Qual("time", "Time")).Id(name).Block(
+		jen.List(jen.Id("y"), jen.Id("m"), jen.Id("d")).Op(":=").Id("t").Dot("Date").Call(),
+		jen.Return(jen.Id(name).Values(jen.Id("y"), jen.Id("m"), jen.Id("d"))),
+	)
+	f.Line()
+
+	f.Comment(formatComment("String returns d in the form 2006-01-02."))
+	f.Func().Params(jen.Id("d").Id(name)).Id("String").Params().String().Block(
+		jen.Return(jen.Qual("fmt", "Sprintf").Call(jen.Lit("%04d-%02d-%02d"), jen.Id("d").Dot("Year"), jen.Id("d").Dot("Month"), jen.Id("d").Dot("Day"))),
+	)
+	f.Line()
+
+	f.Comment(formatComment("MarshalText implements the encoding.TextMarshaler interface."))
+	f.Func().Params(jen.Id("d").Id(name)).Id("MarshalText").Params().Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.Return(jen.Index().Byte().Call(jen.Id("d").Dot("String").Call()), jen.Nil()),
+	)
+	f.Line()
+
+	f.Comment(formatComment("UnmarshalText implements the encoding.TextUnmarshaler interface."))
+	f.Func().Params(jen.Id("d").Op("*").Id(name)).Id("UnmarshalText").Params(jen.Id("b").Index().Byte()).Error().Block(
+		jen.List(jen.Id("t"), jen.Err()).Op(":=").Qual("time", "Parse").Call(jen.Lit("2006-01-02"), jen.String().Call(jen.Id("b"))),
+		jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err())),
+		jen.Op("*").Id("d").Op("=").Id(name+"Of").Call(jen.Id("t")),
+		jen.Return(jen.Nil()),
+	)
+}
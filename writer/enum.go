@@ -0,0 +1,42 @@
+package writer
+
+import (
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+// defineEnum writes the values of the enum type d as constants, along with
+// methods to list and check them.
+func defineEnum(f *jen.File, d *pkg.TypeDecl) {
+	var names []jen.Code
+	f.Comment(formatComment("Values of %s.", d.Name))
+	f.Const().DefsFunc(func(g *jen.Group) {
+		for _, c := range d.Enum {
+			g.Id(c.Name).Do(writeType(c.Type)).Op("=").Lit(c.Value)
+			names = append(names, jen.Id(c.Name))
+		}
+	})
+
+	recv := strings.ToLower(d.Name[:1])
+
+	f.Comment(formatComment("IsValid reports whether %s is one of the values of %s.", recv, d.Name))
+	f.Func().Params(jen.Id(recv).Id(d.Name)).Id("IsValid").Params().Bool().Block(
+		jen.Switch(jen.Id(recv)).Block(
+			jen.Case(names...).Block(jen.Return(jen.True())),
+		),
+		jen.Return(jen.False()),
+	)
+
+	f.Comment(formatComment("Values returns all values of %s.", d.Name))
+	f.Func().Params(jen.Id(d.Name)).Id("Values").Params().Index().Id(d.Name).Block(
+		jen.Return(jen.Index().Id(d.Name).Values(names...)),
+	)
+
+	f.Comment(formatComment("String returns %s in the form used by the API.", recv))
+	f.Func().Params(jen.Id(recv).Id(d.Name)).Id("String").Params().String().Block(
+		jen.Return(stringFor(d.Type, jen.Do(writeType(d.Type)).Call(jen.Id(recv)))),
+	)
+}
@@ -0,0 +1,101 @@
+package writer
+
+import (
+	"bytes"
+	"go/format"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestDefineEnum(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   pkg.TypeDecl
+		out  string
+	}{
+		{"string",
+			pkg.TypeDecl{Name: "Status", Type: &pkg.IdentType{Name: "string"}, Enum: []pkg.ConstDecl{
+				{Name: "StatusOn", Type: &pkg.IdentType{Name: "Status"}, Value: "on"},
+				{Name: "StatusOff", Type: &pkg.IdentType{Name: "Status"}, Value: "off"},
+			}},
+			`
+			// Values of Status.
+			const (
+				StatusOn  Status = "on"
+				StatusOff Status = "off"
+			)
+
+			// IsValid reports whether s is one of the values of Status.
+			func (s Status) IsValid() bool {
+				switch s {
+				case StatusOn, StatusOff:
+					return true
+				}
+				return false
+			}
+
+			// Values returns all values of Status.
+			func (Status) Values() []Status {
+				return []Status{StatusOn, StatusOff}
+			}
+
+			// String returns s in the form used by the API.
+			func (s Status) String() string {
+				return string(s)
+			}
+			`,
+		},
+		{"int",
+			pkg.TypeDecl{Name: "Level", Type: &pkg.IdentType{Name: "int"}, Enum: []pkg.ConstDecl{
+				{Name: "Level1", Type: &pkg.IdentType{Name: "Level"}, Value: 1},
+			}},
+			`
+			import "strconv"
+
+			// Values of Level.
+			const (
+				Level1 Level = 1
+			)
+
+			// IsValid reports whether l is one of the values of Level.
+			func (l Level) IsValid() bool {
+				switch l {
+				case Level1:
+					return true
+				}
+				return false
+			}
+
+			// Values returns all values of Level.
+			func (Level) Values() []Level {
+				return []Level{Level1}
+			}
+
+			// String returns l in the form used by the API.
+			func (l Level) String() string {
+				return strconv.Itoa(int(l))
+			}
+			`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			f := jen.NewFile("test")
+			defineEnum(f, &tc.in)
+
+			var buf bytes.Buffer
+			if err := f.Render(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			formatted, _ := format.Source([]byte("package test\n" + tc.out))
+			if buf.String() != string(formatted) {
+				t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), formatted)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package writer
+
+import (
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+// isFile reports if typ is the io.Reader a file form arg is read from.
+func isFile(typ pkg.Type) bool {
+	it, ok := typ.(*pkg.IdentType)
+	return ok && it.Qualifier == "io" && it.Name == "Reader"
+}
+
+// filename returns the name of the arg holding the filename of the file arg.
+func filename(arg string) string {
+	return formatVarName(arg, "filename")
+}
+
+// setFormArgs encodes the form args of a method into a body of media type mt,
+// returning the expressions for its content type and the *bytes.Buffer
+// holding it. Both are nil if the method has no form body.
+func setFormArgs(g *jen.Group, errRet []jen.Code, mt string, args []pkg.Param, opts []pkg.Field, files []pkg.Param) (jen.Code, jen.Code) {
+	if mt == "" {
+		return nil, nil
+	}
+
+	setQueryArgs(g, errRet, "form", args)
+	form := setOptQueryArgs(g, errRet, "form", len(args) > 0, opts)
+	if len(args) > 0 || len(opts) > 0 {
+		g.Line()
+	}
+
+	if mt != "multipart/form-data" {
+		if len(args) == 0 && len(opts) == 0 {
+			return jen.Lit(mt), jen.New(jen.Qual("bytes", "Buffer"))
+		}
+
+		return jen.Lit(mt), jen.Qual("bytes", "NewBufferString").Call(jen.Add(form).Dot("Encode").Call())
+	}
+
+	g.Var().Id("buf").Qual("bytes", "Buffer")
+	g.Id("mw").Op(":=").Qual("mime/multipart", "NewWriter").Call(jen.Op("&").Id("buf"))
+	if len(args) > 0 || len(opts) > 0 {
+		g.For(jen.List(jen.Id("k"), jen.Id("vs")).Op(":=").Range().Add(form)).Block(
+			jen.For(jen.List(jen.Id("_"), jen.Id("v")).Op(":=").Range().Id("vs")).Block(
+				jen.If(
+					jen.Err().Op(":=").Id("mw").Dot("WriteField").Call(jen.Id("k"), jen.Id("v")),
+					jen.Err().Op("!=").Nil(),
+				).Block(jen.Return(errRet...)),
+			),
+		)
+	}
+	for _, f := range files {
+		orig := f.ID
+		if f.Orig != "" {
+			orig = f.Orig
+		}
+
+		g.If(jen.Id(f.Arg).Op("!=").Nil()).Block(
+			jen.If(
+				jen.Err().Op(":=").Id("writeFormFile").Call(jen.Id("mw"), jen.Lit(orig), jen.Id(filename(f.Arg)), jen.Id(f.Arg)),
+				jen.Err().Op("!=").Nil(),
+			).Block(jen.Return(errRet...)),
+		)
+	}
+	g.If(jen.Err().Op(":=").Id("mw").Dot("Close").Call(), jen.Err().Op("!=").Nil()).Block(jen.Return(errRet...))
+	g.Line()
+
+	return jen.Id("mw").Dot("FormDataContentType").Call(), jen.Op("&").Id("buf")
+}
+
+// defineFormBody writes the helper that sets the encoded form body of a
+// request, after it is created by the backend.
+func defineFormBody(f *jen.File) {
+	f.Comment(formatComment(`
+		setFormBody sets the body of req to the encoded form body, of type
+		contentType.
+	`))
+	f.Func().Id("setFormBody").Params(
+		jen.Id("req").Op("*").Qual("net/http", "Request"),
+		jen.Id("contentType").String(),
+		jen.Id("body").Op("*").Qual("bytes", "Buffer"),
+	).Block(
+		jen.Id("b").Op(":=").Id("body").Dot("Bytes").Call(),
+		jen.Id("req").Dot("Body").Op("=").Qual("io", "NopCloser").Call(jen.Id("body")),
+		jen.Id("req").Dot("GetBody").Op("=").Func().Params().Params(jen.Qual("io", "ReadCloser"), jen.Error()).Block(
+			jen.Return(jen.Qual("io", "NopCloser").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("b"))), jen.Nil()),
+		),
+		jen.Id("req").Dot("ContentLength").Op("=").Int64().Call(jen.Len(jen.Id("b"))),
+		jen.Id("req").Dot("Header").Dot("Set").Call(jen.Lit("Content-Type"), jen.Id("contentType")),
+	)
+}
+
+// defineWriteFormFile writes the helper that copies a file arg into a
+// multipart form.
+//
+// XXX files are read into memory before the request is sent.
+func defineWriteFormFile(f *jen.File) {
+	f.Comment(formatComment(`
+		writeFormFile adds the file field to mw, copying its contents from r.
+	`))
+	f.Func().Id("writeFormFile").Params(
+		jen.Id("mw").Op("*").Qual("mime/multipart", "Writer"),
+		jen.List(jen.Id("field"), jen.Id("filename")).String(),
+		jen.Id("r").Qual("io", "Reader"),
+	).Error().Block(
+		jen.List(jen.Id("w"), jen.Err()).Op(":=").Id("mw").Dot("CreateFormFile").Call(jen.Id("field"), jen.Id("filename")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err())),
+		jen.Line(),
+		jen.List(jen.Id("_"), jen.Err()).Op("=").Qual("io", "Copy").Call(jen.Id("w"), jen.Id("r")),
+		jen.Return(jen.Err()),
+	)
+}
@@ -0,0 +1,92 @@
+package writer
+
+import (
+	"fmt"
+	"go/format"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestSetFormArgs(t *testing.T) {
+	tcs := []struct {
+		name  string
+		mt    string
+		args  []pkg.Param
+		files []pkg.Param
+		out   string
+		body  string
+	}{
+		{"no form", "", nil, nil, "", ""},
+		{"empty urlencoded", "application/x-www-form-urlencoded", nil, nil, "", "new(bytes.Buffer)"},
+		{"urlencoded",
+			"application/x-www-form-urlencoded",
+			[]pkg.Param{{ID: "name", Arg: "name", Type: &pkg.IdentType{Name: "string"}, Kind: pkg.Form}},
+			nil,
+			`
+
+				form := make(url.Values)
+				form.Set("name", name)
+
+			`,
+			"bytes.NewBufferString(form.Encode())",
+		},
+		{"multipart",
+			"multipart/form-data",
+			[]pkg.Param{{ID: "name", Arg: "name", Type: &pkg.IdentType{Name: "string"}, Kind: pkg.Form}},
+			[]pkg.Param{{ID: "image", Orig: "image_file", Arg: "image", Type: &pkg.IdentType{Qualifier: "io", Name: "Reader"}, Kind: pkg.Form}},
+			`
+
+				form := make(url.Values)
+				form.Set("name", name)
+
+				var buf bytes.Buffer
+				mw := multipart.NewWriter(&buf)
+				for k, vs := range form {
+					for _, v := range vs {
+						if err := mw.WriteField(k, v); err != nil {
+							return
+						}
+					}
+				}
+				if image != nil {
+					if err := writeFormFile(mw, "image_file", imageFilename, image); err != nil {
+						return
+					}
+				}
+				if err := mw.Close(); err != nil {
+					return
+				}
+
+			`,
+			"&buf",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var body jen.Code
+			sfa := jen.Id("v").Op("=").Func().Params().BlockFunc(func(g *jen.Group) {
+				_, body = setFormArgs(g, nil, tc.mt, tc.args, nil, tc.files)
+			})
+
+			out := fmt.Sprintf("%#v", sfa)
+			formatted, _ := format.Source([]byte("v = func() {" + tc.out + "}"))
+			if out != string(formatted) {
+				t.Error("got:", out, "expected:", string(formatted))
+			}
+
+			if body == nil {
+				if tc.body != "" {
+					t.Error("got no body, expected:", tc.body)
+				}
+				return
+			}
+			if b := fmt.Sprintf("%#v", body); b != tc.body {
+				t.Error("got body:", b, "expected:", tc.body)
+			}
+		})
+	}
+}
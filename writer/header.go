@@ -0,0 +1,133 @@
+package writer
+
+import (
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+// setMeta declares meta, holding the parsed headers of the *http.Response
+// named resp, as described by the Meta type of m. When guard is set, resp may
+// be nil, leaving meta empty. Headers that fail to parse run fail.
+func setMeta(g *jen.Group, m *pkg.Method, resp string, guard bool, fail []jen.Code, decls []pkg.TypeDecl) {
+	name := typeName(m.Meta.(*pkg.PointerType).Type)
+	g.Id("meta").Op(":=").Op("&").Id(name).Values()
+
+	var fields []pkg.Field
+	for _, d := range decls {
+		if d.Name == name {
+			fields = d.Type.(*pkg.StructType).Fields
+		}
+	}
+
+	header := jen.Id(resp).Dot("Header")
+	parse := func(g *jen.Group) {
+		for _, f := range fields {
+			switch t := f.Type.(type) {
+			case *pkg.SliceType:
+				if isBytes(t) {
+					g.If(jen.Id("v").Op(":=").Add(header.Clone()).Dot("Get").Call(jen.Lit(f.Orig)), jen.Id("v").Op("!=").Lit("")).BlockFunc(func(g *jen.Group) {
+						v := parseValue(g, t, "v", fail, decls)
+						g.Id("meta").Dot(f.ID).Op("=").Id(v)
+					})
+					continue
+				}
+
+				g.For(jen.List(jen.Id("_"), jen.Id("vs")).Op(":=").Range().Add(header.Clone()).Dot("Values").Call(jen.Lit(f.Orig))).Block(
+					jen.For(jen.List(jen.Id("_"), jen.Id("v")).Op(":=").Range().Qual("strings", "Split").Call(jen.Id("vs"), jen.Lit(","))).BlockFunc(func(g *jen.Group) {
+						g.Id("v").Op("=").Qual("strings", "TrimSpace").Call(jen.Id("v"))
+						v := parseValue(g, t.Type, "v", fail, decls)
+						g.Id("meta").Dot(f.ID).Op("=").Append(jen.Id("meta").Dot(f.ID), jen.Id(v))
+					}),
+				)
+			case *pkg.PointerType:
+				g.If(jen.Id("v").Op(":=").Add(header.Clone()).Dot("Get").Call(jen.Lit(f.Orig)), jen.Id("v").Op("!=").Lit("")).BlockFunc(func(g *jen.Group) {
+					v := parseValue(g, t.Type, "v", fail, decls)
+					g.Id("meta").Dot(f.ID).Op("=").Op("&").Id(v)
+				})
+			default:
+				panic("unhandled header type")
+			}
+		}
+	}
+
+	if guard {
+		g.If(jen.Id(resp).Op("!=").Nil()).BlockFunc(parse)
+	} else {
+		parse(g)
+	}
+	g.Line()
+}
+
+// parseValue parses the string named v into a value of typ, returning the
+// name of the variable holding it. Failures run fail.
+func parseValue(g *jen.Group, typ pkg.Type, v string, fail []jen.Code, decls []pkg.TypeDecl) string {
+	check := func() {
+		g.If(jen.Err().Op("!=").Nil()).Block(fail...)
+	}
+
+	if isBytes(typ) {
+		g.List(jen.Id("b"), jen.Err()).Op(":=").Qual("encoding/base64", "StdEncoding").Dot("DecodeString").Call(jen.Id(v))
+		check()
+		return "b"
+	}
+
+	it, ok := typ.(*pkg.IdentType)
+	if !ok {
+		panic("unknown type for string parsing")
+	}
+
+	if it.Marshal {
+		g.Var().Id("x").Do(writeType(it))
+		g.If(jen.Err().Op(":=").Id("x").Dot("UnmarshalText").Call(jen.Index().Byte().Params(jen.Id(v))), jen.Err().Op("!=").Nil()).Block(fail...)
+		return "x"
+	}
+
+	if isEnum(it) {
+		for _, d := range decls {
+			if d.Name == it.Name {
+				u := parseValue(g, d.Type, v, fail, decls)
+				g.Id("e").Op(":=").Id(it.Name).Params(jen.Id(u))
+				return "e"
+			}
+		}
+	}
+
+	// the bit size of the type, for sized ints and floats, which are parsed
+	// into their 64 bit form and converted.
+	var bits int
+	switch it.Name {
+	case "int8", "uint8":
+		bits = 8
+	case "int16", "uint16":
+		bits = 16
+	case "int32", "uint32", "float32":
+		bits = 32
+	case "int64", "uint64", "float64", "uint":
+		bits = 64
+	}
+
+	switch it.Name {
+	case "int":
+		g.List(jen.Id("n"), jen.Err()).Op(":=").Qual("strconv", "Atoi").Call(jen.Id(v))
+	case "int8", "int16", "int32", "int64":
+		g.List(jen.Id("n"), jen.Err()).Op(":=").Qual("strconv", "ParseInt").Call(jen.Id(v), jen.Lit(10), jen.Lit(bits))
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		g.List(jen.Id("n"), jen.Err()).Op(":=").Qual("strconv", "ParseUint").Call(jen.Id(v), jen.Lit(10), jen.Lit(bits))
+	case "float32", "float64":
+		g.List(jen.Id("n"), jen.Err()).Op(":=").Qual("strconv", "ParseFloat").Call(jen.Id(v), jen.Lit(bits))
+	case "bool":
+		g.List(jen.Id("n"), jen.Err()).Op(":=").Qual("strconv", "ParseBool").Call(jen.Id(v))
+	default:
+		return v // treat as string
+	}
+	check()
+
+	switch it.Name {
+	case "int", "int64", "uint64", "float64", "bool":
+		return "n"
+	}
+
+	g.Id("x").Op(":=").Id(it.Name).Params(jen.Id("n"))
+	return "x"
+}
@@ -0,0 +1,72 @@
+package writer
+
+import (
+	"fmt"
+	"go/format"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestSetMeta(t *testing.T) {
+	decls := []pkg.TypeDecl{
+		{Name: "Kind", Type: &pkg.IdentType{Name: "string"}},
+		{Name: "GetResponseMeta", Type: &pkg.StructType{Fields: []pkg.Field{
+			{ID: "ETag", Orig: "ETag", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "string"}}},
+			{ID: "Limit", Orig: "X-Limit", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "int32"}}},
+			{ID: "Kind", Orig: "X-Kind", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "Kind"}}},
+			{ID: "Modified", Orig: "Last-Modified", Type: &pkg.PointerType{Type: &pkg.IdentType{Qualifier: "time", Name: "Time", Marshal: true}}},
+			{ID: "Sizes", Orig: "X-Sizes", Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "int"}}},
+		}}},
+	}
+	m := &pkg.Method{Meta: &pkg.PointerType{Type: &pkg.IdentType{Name: "GetResponseMeta"}}}
+
+	sm := jen.Id("v").Op("=").Func().Params().BlockFunc(func(g *jen.Group) {
+		setMeta(g, m, "resp", true, []jen.Code{jen.Return(jen.Nil(), jen.Err())}, decls)
+	})
+
+	out := fmt.Sprintf("%#v", sm)
+	formatted, _ := format.Source([]byte(`v = func() {
+		meta := &GetResponseMeta{}
+		if resp != nil {
+			if v := resp.Header.Get("ETag"); v != "" {
+				meta.ETag = &v
+			}
+			if v := resp.Header.Get("X-Limit"); v != "" {
+				n, err := strconv.ParseInt(v, 10, 32)
+				if err != nil {
+					return nil, err
+				}
+				x := int32(n)
+				meta.Limit = &x
+			}
+			if v := resp.Header.Get("X-Kind"); v != "" {
+				e := Kind(v)
+				meta.Kind = &e
+			}
+			if v := resp.Header.Get("Last-Modified"); v != "" {
+				var x time.Time
+				if err := x.UnmarshalText([]byte(v)); err != nil {
+					return nil, err
+				}
+				meta.Modified = &x
+			}
+			for _, vs := range resp.Header.Values("X-Sizes") {
+				for _, v := range strings.Split(vs, ",") {
+					v = strings.TrimSpace(v)
+					n, err := strconv.Atoi(v)
+					if err != nil {
+						return nil, err
+					}
+					meta.Sizes = append(meta.Sizes, n)
+				}
+			}
+		}
+
+	}`))
+	if out != string(formatted) {
+		t.Error("got:", out, "expected:", string(formatted))
+	}
+}
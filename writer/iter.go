@@ -4,35 +4,89 @@ import (
 	"github.com/dave/jennifer/jen"
 	"github.com/gedex/inflector"
 
+	"github.com/jbowes/oag/config"
 	"github.com/jbowes/oag/pkg"
 )
 
-func defineIter(f *jen.File, iter *pkg.Iter) {
+// defineIter defines the iterator type iter. When paging has a strategy, the
+// iterator requests each page after the first as the one before it is
+// consumed, and otherwise holds a single page. With ranged, the iterator can
+// also be used in for range loops.
+func defineIter(f *jen.File, iter *pkg.Iter, decls []pkg.TypeDecl, poly polymorphism, paging config.Pagination, ranged bool) {
+	current := jen.Op("&").Id("i").Dot("page").Index(jen.Id("i").Dot("i"))
 	page := jen.Id("page")
 	switch t := iter.Return.(type) {
+	case *pkg.IdentType:
+		if !poly.isInterface(t) {
+			page.Do(writeType(&pkg.SliceType{Type: iter.Return}))
+			break
+		}
+		if iter.Envelope != nil {
+			// Items in an envelope are decoded by the envelope.
+			page.Do(writeType(&pkg.SliceType{Type: iter.Return}))
+			current = jen.Id("i").Dot("page").Index(jen.Id("i").Dot("i"))
+			break
+		}
+
+		// Pages of interface values are decoded by their holder type.
+		page.Index().Id(holder(t.Name))
+		current = jen.Id("i").Dot("page").Index(jen.Id("i").Dot("i")).Dot(t.Name)
 	case *pkg.PointerType:
 		page.Do(writeType(&pkg.SliceType{Type: t.Type}))
 	default:
 		page.Do(writeType(&pkg.SliceType{Type: iter.Return}))
 	}
-	// XXX add comment about following pagination when supported, and where appropriate.
-	f.Comment(formatComment(`
-		%s Iterates over a result set of %s.
-	`, iter.Name, inflector.Pluralize(typeName(iter.Return))))
-	f.Type().Id(iter.Name).Struct(
+
+	paged := paging.Strategy != ""
+	fields := []jen.Code{
 		page,
 		jen.Id("i").Int(),
+	}
+	if iter.Envelope != nil {
+		fields = append(fields, jen.Id("envelope").Id(iter.Envelope.Name).Comment("the response holding page"))
+	}
+	fields = append(fields,
 		jen.Empty(),
 		jen.Err().Error(),
 		jen.Id("first").Bool(),
 	)
 
+	if !paged {
+		f.Comment(formatComment(`
+			%s Iterates over a result set of %s.
+		`, iter.Name, inflector.Pluralize(typeName(iter.Return))))
+	} else {
+		f.Comment(formatComment(`
+			%s Iterates over a result set of %s, requesting each page as the
+			one before it is consumed.
+		`, iter.Name, inflector.Pluralize(typeName(iter.Return))))
+		fields = append(fields,
+			jen.Empty(),
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("cancel").Qual("context", "CancelFunc"),
+			jen.Id("backend").Id("Backend"),
+			jen.Id("req").Op("*").Qual("net/http", "Request").Comment("for the next page, or nil after the last"),
+			jen.Id("errFn").Func().Params(jen.Int()).Error(),
+		)
+	}
+	f.Type().Id(iter.Name).Struct(fields...)
+
 	// XXX should return something? err?
-	f.Comment(formatComment(`
-		Close closes the %s and releases any associated resources.
-		After Close, any calls to Current will return an error.
-	`, iter.Name))
+	if !paged {
+		f.Comment(formatComment(`
+			Close closes the %s and releases any associated resources.
+			After Close, any calls to Current will return an error.
+		`, iter.Name))
+	} else {
+		f.Comment(formatComment(`
+			Close closes the %s, canceling any request for a page in progress.
+			After Close, any calls to Current will return an error.
+		`, iter.Name))
+	}
 	f.Func().Params(jen.Id("i").Op("*").Id(iter.Name)).Id("Close").Params().BlockFunc(func(g *jen.Group) {
+		if paged {
+			g.Id("i").Dot("cancel").Call()
+		}
 	})
 
 	f.Comment(formatComment(`
@@ -46,8 +100,29 @@ func defineIter(f *jen.File, iter *pkg.Iter) {
 			g.Return(jen.True())
 		})
 		g.Id("i").Dot("first").Op("=").False()
+
+		if !paged {
+			g.Id("i").Dot("i").Op("++")
+			g.Return(jen.Id("i").Dot("i").Op("<").Len(jen.Id("i").Dot("page")))
+			return
+		}
+
+		g.If(jen.Id("i").Dot("err").Op("!=").Nil()).Block(jen.Return(jen.False()))
+		g.Line()
+		g.Comment("Report why iteration stopped once closed.")
+		g.If(jen.Id("i").Dot("err").Op("=").Id("i").Dot("ctx").Dot("Err").Call(), jen.Id("i").Dot("err").Op("!=").Nil()).Block(
+			jen.Return(jen.True()),
+		)
+		g.Line()
+
 		g.Id("i").Dot("i").Op("++")
-		g.Return(jen.Id("i").Dot("i").Op("<").Len(jen.Id("i").Dot("page")))
+		g.For(jen.Id("i").Dot("i").Op(">=").Len(jen.Id("i").Dot("page"))).Block(
+			jen.If(jen.Id("i").Dot("req").Op("==").Nil()).Block(jen.Return(jen.False())),
+			jen.If(jen.Id("i").Dot("err").Op("=").Id("i").Dot("fetch").Call(), jen.Id("i").Dot("err").Op("!=").Nil()).Block(
+				jen.Return(jen.True()),
+			),
+		)
+		g.Return(jen.True())
 	})
 
 	f.Comment(formatComment(`
@@ -60,6 +135,267 @@ func defineIter(f *jen.File, iter *pkg.Iter) {
 		g.If(jen.Id("i").Dot("err").Op("!=").Nil()).BlockFunc(func(g *jen.Group) {
 			g.Return(jen.Nil(), jen.Id("i").Dot("err"))
 		})
-		g.Return(jen.Op("&").Id("i").Dot("page").Index(jen.Id("i").Dot("i")), jen.Nil())
+		if paged {
+			g.If(jen.Err().Op(":=").Id("i").Dot("ctx").Dot("Err").Call(), jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			)
+		}
+		g.Return(current, jen.Nil())
+	})
+
+	if iter.Envelope != nil {
+		f.Comment(formatComment(`
+			Page returns the %s holding the current page of %s, for its
+			fields other than the items.
+		`, iter.Envelope.Name, inflector.Pluralize(typeName(iter.Return))))
+		f.Func().Params(jen.Id("i").Op("*").Id(iter.Name)).Id("Page").Params().Op("*").Id(iter.Envelope.Name).Block(
+			jen.Return(jen.Op("&").Id("i").Dot("envelope")),
+		)
+	}
+
+	if ranged {
+		defineRange(f, iter)
+	}
+
+	if paged {
+		defineFetch(f, iter, decls, paging)
+	}
+}
+
+// defineRange defines the All and Collect methods of iter, for ranging over
+// its items with Go 1.23 range-over-func loops, built on Next and Current.
+func defineRange(f *jen.File, iter *pkg.Iter) {
+	f.ImportName("iter", "iter") // not known to jen as a standard package
+	item := jen.Do(writeType(iter.Return))
+	plural := inflector.Pluralize(typeName(iter.Return))
+
+	f.Comment(formatComment(`
+		All returns an iterator over the %s of the %s, for use in for range
+		loops. Iteration stops after the first error, which is returned with a nil
+		%s, or once ctx is done. The %s is closed when iteration stops.
+	`, plural, iter.Name, typeName(iter.Return), iter.Name))
+	f.Func().Params(jen.Id("i").Op("*").Id(iter.Name)).Id("All").Params(jen.Id("ctx").Qual("context", "Context")).Qual("iter", "Seq2").Index(jen.List(item.Clone(), jen.Error())).Block(
+		jen.Return(jen.Func().Params(jen.Id("yield").Func().Params(item.Clone(), jen.Error()).Bool()).Block(
+			jen.Defer().Id("i").Dot("Close").Call(),
+			jen.Line(),
+			jen.For().Block(
+				jen.If(jen.Err().Op(":=").Id("ctx").Dot("Err").Call(), jen.Err().Op("!=").Nil()).Block(
+					jen.Id("yield").Call(jen.Nil(), jen.Err()),
+					jen.Return(),
+				),
+				jen.If(jen.Op("!").Id("i").Dot("Next").Call()).Block(jen.Return()),
+				jen.Line(),
+				jen.List(jen.Id("v"), jen.Err()).Op(":=").Id("i").Dot("Current").Call(),
+				jen.If(jen.Op("!").Id("yield").Call(jen.Id("v"), jen.Err()).Op("||").Err().Op("!=").Nil()).Block(jen.Return()),
+			),
+		)),
+	)
+
+	f.Comment(formatComment(`
+		Collect returns up to limit of the %s of the %s, or all of them when
+		limit is 0 or less, stopping at the first error. The %s is closed once
+		they are collected.
+	`, plural, iter.Name, iter.Name))
+	f.Func().Params(jen.Id("i").Op("*").Id(iter.Name)).Id("Collect").Params(jen.Id("ctx").Qual("context", "Context"), jen.Id("limit").Int()).Params(jen.Index().Add(item.Clone()), jen.Error()).Block(
+		jen.Var().Id("all").Index().Add(item.Clone()),
+		jen.For(jen.List(jen.Id("v"), jen.Err()).Op(":=").Range().Id("i").Dot("All").Call(jen.Id("ctx"))).Block(
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Id("all"), jen.Err())),
+			jen.Line(),
+			jen.Id("all").Op("=").Append(jen.Id("all"), jen.Id("v")),
+			jen.If(jen.Len(jen.Id("all")).Op("==").Id("limit")).Block(jen.Break()),
+		),
+		jen.Return(jen.Id("all"), jen.Nil()),
+	)
+}
+
+// setPage sets the page of the iterator named recv to the items of its
+// envelope.
+func setPage(g *jen.Group, iter *pkg.Iter, recv string, decls []pkg.TypeDecl) {
+	items := jen.Id(recv).Dot("envelope").Dot(iter.Field)
+	for _, d := range decls {
+		if d.Name != iter.Envelope.Name {
+			continue
+		}
+
+		for _, f := range d.Type.(*pkg.StructType).Fields {
+			if _, ok := f.Type.(*pkg.PointerType); ok && f.ID == iter.Field {
+				g.If(items.Clone().Op("!=").Nil()).Block(
+					jen.Id(recv).Dot("page").Op("=").Op("*").Add(items),
+				)
+				return
+			}
+		}
+	}
+
+	g.Id(recv).Dot("page").Op("=").Add(items)
+}
+
+// defineFetch defines the fetch method of the paginated iterator iter, which
+// requests the next page, replacing the current one.
+func defineFetch(f *jen.File, iter *pkg.Iter, decls []pkg.TypeDecl, paging config.Pagination) {
+	f.Comment(formatComment("fetch requests the next page of the %s.", iter.Name))
+	f.Func().Params(jen.Id("i").Op("*").Id(iter.Name)).Id("fetch").Params().Error().BlockFunc(func(g *jen.Group) {
+		g.Id("req").Op(":=").Id("i").Dot("req")
+		g.List(jen.Id("i").Dot("page"), jen.Id("i").Dot("i"), jen.Id("i").Dot("req")).Op("=").List(jen.Nil(), jen.Lit(0), jen.Nil())
+
+		into := jen.Op("&").Id("i").Dot("page")
+		if iter.Envelope != nil {
+			g.Id("i").Dot("envelope").Op("=").Id(iter.Envelope.Name).Values()
+			into = jen.Op("&").Id("i").Dot("envelope")
+		}
+		g.Line()
+		fill := func() {
+			if iter.Envelope != nil {
+				setPage(g, iter, "i", decls)
+			}
+		}
+
+		do := func(v jen.Code) *jen.Statement {
+			return jen.Id("i").Dot("backend").Dot("Do").Call(jen.Id("i").Dot("ctx"), jen.Id("req"), v, jen.Id("i").Dot("errFn"))
+		}
+		fail := jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err()))
+
+		switch paging.Strategy {
+		case config.PageLink:
+			g.List(jen.Id("resp"), jen.Err()).Op(":=").Add(do(into))
+			g.Add(fail)
+			fill()
+			g.Line()
+			g.Id("i").Dot("req").Op("=").Id("nextPage").Call(jen.Id("req"), jen.Id("resp"))
+		case config.PageCursor:
+			g.Comment("The body is decoded twice, for the page and its cursor.")
+			g.Var().Id("body").Qual("encoding/json", "RawMessage")
+			g.List(jen.Id("resp"), jen.Err()).Op(":=").Add(do(jen.Op("&").Id("body")))
+			g.If(jen.Err().Op("!=").Nil().Op("||").Id("resp").Op("==").Nil()).Block(jen.Return(jen.Err()))
+			g.If(jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("body"), into), jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Err()),
+			)
+			fill()
+			g.Line()
+			g.Id("i").Dot("req").Op("=").Id("nextPage").Call(jen.Id("req"), jen.Id("body"))
+		default:
+			g.List(jen.Id("resp"), jen.Err()).Op(":=").Add(do(into))
+			g.If(jen.Err().Op("!=").Nil().Op("||").Id("resp").Op("==").Nil()).Block(jen.Return(jen.Err()))
+			fill()
+			g.Line()
+			g.Id("i").Dot("req").Op("=").Id("nextPage").Call(jen.Id("req"), jen.Len(jen.Id("i").Dot("page")))
+		}
+		g.Return(jen.Nil())
 	})
 }
+
+// definePaging defines nextPage, which builds the request for the page after
+// the one requested, following the strategy of paging.
+func definePaging(f *jen.File, paging config.Pagination) {
+	q := jen.Id("q").Op(":=").Id("req").Dot("URL").Dot("Query").Call()
+	next := func(g *jen.Group) {
+		g.Line()
+		g.Id("u").Op(":=").Op("*").Id("req").Dot("URL")
+		g.Id("u").Dot("RawQuery").Op("=").Id("q").Dot("Encode").Call()
+		g.Return(jen.Id("pageRequest").Call(jen.Id("req"), jen.Op("&").Id("u")))
+	}
+	atoi := func(name string) *jen.Statement {
+		return jen.Qual("strconv", "Atoi").Call(jen.Id("q").Dot("Get").Call(jen.Lit(name)))
+	}
+
+	switch paging.Strategy {
+	case config.PageLink:
+		f.Comment(formatComment(`
+			nextPage returns the request for the page linked to as next by the Link
+			header of resp, the response to req, or nil if there is none.
+		`))
+		f.Func().Id("nextPage").Params(jen.Id("req").Op("*").Qual("net/http", "Request"), jen.Id("resp").Op("*").Qual("net/http", "Response")).Op("*").Qual("net/http", "Request").Block(
+			jen.If(jen.Id("resp").Op("==").Nil()).Block(jen.Return(jen.Nil())),
+			jen.Line(),
+			jen.For(jen.List(jen.Id("_"), jen.Id("header")).Op(":=").Range().Id("resp").Dot("Header").Dot("Values").Call(jen.Lit("Link"))).Block(
+				jen.For(jen.List(jen.Id("_"), jen.Id("link")).Op(":=").Range().Qual("strings", "Split").Call(jen.Id("header"), jen.Lit(","))).Block(
+					jen.Id("parts").Op(":=").Qual("strings", "Split").Call(jen.Id("link"), jen.Lit(";")),
+					jen.For(jen.List(jen.Id("_"), jen.Id("param")).Op(":=").Range().Id("parts").Index(jen.Lit(1), jen.Empty())).Block(
+						jen.Id("param").Op("=").Qual("strings", "TrimSpace").Call(jen.Id("param")),
+						jen.If(jen.Op("!").Qual("strings", "HasPrefix").Call(jen.Id("param"), jen.Lit("rel="))).Block(jen.Continue()),
+						jen.Line(),
+						jen.Id("rels").Op(":=").Qual("strings", "Trim").Call(jen.Id("param").Index(jen.Len(jen.Lit("rel=")), jen.Empty()), jen.Lit(`"`)),
+						jen.For(jen.List(jen.Id("_"), jen.Id("r")).Op(":=").Range().Qual("strings", "Fields").Call(jen.Id("rels"))).Block(
+							jen.If(jen.Op("!").Qual("strings", "EqualFold").Call(jen.Id("r"), jen.Lit("next"))).Block(jen.Continue()),
+							jen.Line(),
+							jen.Id("target").Op(":=").Qual("strings", "Trim").Call(jen.Qual("strings", "TrimSpace").Call(jen.Id("parts").Index(jen.Lit(0))), jen.Lit("<>")),
+							jen.List(jen.Id("u"), jen.Err()).Op(":=").Id("req").Dot("URL").Dot("Parse").Call(jen.Id("target")),
+							jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil())),
+							jen.Return(jen.Id("pageRequest").Call(jen.Id("req"), jen.Id("u"))),
+						),
+					),
+				),
+			),
+			jen.Line(),
+			jen.Return(jen.Nil()),
+		)
+	case config.PageCursor:
+		f.Comment(formatComment(`
+			nextPage returns the request for the page after the one requested by req,
+			from the %s field of its response body, or nil if it is empty.
+		`, paging.Field))
+		f.Func().Id("nextPage").Params(jen.Id("req").Op("*").Qual("net/http", "Request"), jen.Id("body").Qual("encoding/json", "RawMessage")).Op("*").Qual("net/http", "Request").BlockFunc(func(g *jen.Group) {
+			g.Var().Id("page").Struct(
+				jen.Id("Cursor").Op("*").String().Tag(map[string]string{"json": paging.Field}),
+			)
+			g.If(jen.Qual("encoding/json", "Unmarshal").Call(jen.Id("body"), jen.Op("&").Id("page")).Op("!=").Nil().Op("||").Id("page").Dot("Cursor").Op("==").Nil().Op("||").Op("*").Id("page").Dot("Cursor").Op("==").Lit("")).Block(
+				jen.Return(jen.Nil()),
+			)
+			g.Line()
+			g.Add(q)
+			g.Id("q").Dot("Set").Call(jen.Lit(paging.Param), jen.Op("*").Id("page").Dot("Cursor"))
+			next(g)
+		})
+	case config.PageOffset:
+		f.Comment(formatComment(`
+			nextPage returns the request for the page after the one requested by req,
+			which held n items, or nil if it was the last.
+		`))
+		f.Func().Id("nextPage").Params(jen.Id("req").Op("*").Qual("net/http", "Request"), jen.Id("n").Int()).Op("*").Qual("net/http", "Request").BlockFunc(func(g *jen.Group) {
+			g.If(jen.Id("n").Op("==").Lit(0)).Block(jen.Return(jen.Nil()))
+			g.Line()
+			g.Add(q)
+			if paging.Limit != "" {
+				g.If(jen.List(jen.Id("limit"), jen.Err()).Op(":=").Add(atoi(paging.Limit)), jen.Err().Op("==").Nil().Op("&&").Id("n").Op("<").Id("limit")).Block(
+					jen.Return(jen.Nil()),
+				)
+			}
+			g.List(jen.Id("offset"), jen.Id("_")).Op(":=").Add(atoi(paging.Param))
+			g.Id("q").Dot("Set").Call(jen.Lit(paging.Param), jen.Qual("strconv", "Itoa").Call(jen.Id("offset").Op("+").Id("n")))
+			next(g)
+		})
+	case config.PageNumber:
+		f.Comment(formatComment(`
+			nextPage returns the request for the page after the one requested by req,
+			which held n items, or nil if it was the last.
+		`))
+		f.Func().Id("nextPage").Params(jen.Id("req").Op("*").Qual("net/http", "Request"), jen.Id("n").Int()).Op("*").Qual("net/http", "Request").BlockFunc(func(g *jen.Group) {
+			g.If(jen.Id("n").Op("==").Lit(0)).Block(jen.Return(jen.Nil()))
+			g.Line()
+			g.Add(q)
+			if paging.Limit != "" {
+				g.If(jen.List(jen.Id("limit"), jen.Err()).Op(":=").Add(atoi(paging.Limit)), jen.Err().Op("==").Nil().Op("&&").Id("n").Op("<").Id("limit")).Block(
+					jen.Return(jen.Nil()),
+				)
+			}
+			g.List(jen.Id("page"), jen.Err()).Op(":=").Add(atoi(paging.Param))
+			g.If(jen.Err().Op("!=").Nil()).Block(jen.Id("page").Op("=").Lit(1))
+			g.Id("q").Dot("Set").Call(jen.Lit(paging.Param), jen.Qual("strconv", "Itoa").Call(jen.Id("page").Op("+").Lit(1)))
+			next(g)
+		})
+	}
+
+	f.Comment(formatComment(`
+		pageRequest returns a copy of req for the URL u, with a fresh body.
+	`))
+	f.Func().Id("pageRequest").Params(jen.Id("req").Op("*").Qual("net/http", "Request"), jen.Id("u").Op("*").Qual("net/url", "URL")).Op("*").Qual("net/http", "Request").Block(
+		jen.Id("next").Op(":=").Id("req").Dot("Clone").Call(jen.Id("req").Dot("Context").Call()),
+		jen.List(jen.Id("next").Dot("URL"), jen.Id("next").Dot("Host")).Op("=").List(jen.Id("u"), jen.Lit("")),
+		jen.If(jen.Id("req").Dot("GetBody").Op("!=").Nil()).Block(
+			jen.If(jen.List(jen.Id("body"), jen.Err()).Op(":=").Id("req").Dot("GetBody").Call(), jen.Err().Op("==").Nil()).Block(
+				jen.Id("next").Dot("Body").Op("=").Id("body"),
+			),
+		),
+		jen.Return(jen.Id("next")),
+	)
+}
@@ -0,0 +1,255 @@
+package writer
+
+import (
+	"bytes"
+	"go/format"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/config"
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestDefineFetch(t *testing.T) {
+	pets := pkg.Iter{Name: "PetIter", Return: &pkg.PointerType{Type: &pkg.IdentType{Name: "Pet"}}}
+	pages := pkg.Iter{
+		Name:     "PetPageIter",
+		Return:   &pkg.PointerType{Type: &pkg.IdentType{Name: "Pet"}},
+		Envelope: &pkg.IdentType{Name: "PetPage"},
+		Field:    "Data",
+	}
+	decls := []pkg.TypeDecl{{Name: "PetPage", Type: &pkg.StructType{Fields: []pkg.Field{
+		{ID: "Data", Type: &pkg.PointerType{Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "Pet"}}}},
+		{ID: "NextCursor", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "string"}}},
+	}}}}
+
+	tcs := []struct {
+		name     string
+		strategy string
+		iter     pkg.Iter
+		out      string
+	}{
+		{"link", config.PageLink, pets, `
+			// fetch requests the next page of the PetIter.
+			func (i *PetIter) fetch() error {
+				req := i.req
+				i.page, i.i, i.req = nil, 0, nil
+
+				resp, err := i.backend.Do(i.ctx, req, &i.page, i.errFn)
+				if err != nil {
+					return err
+				}
+
+				i.req = nextPage(req, resp)
+				return nil
+			}
+			`,
+		},
+		{"cursor", config.PageCursor, pets, `
+			import "encoding/json"
+
+			// fetch requests the next page of the PetIter.
+			func (i *PetIter) fetch() error {
+				req := i.req
+				i.page, i.i, i.req = nil, 0, nil
+
+				// The body is decoded twice, for the page and its cursor.
+				var body json.RawMessage
+				resp, err := i.backend.Do(i.ctx, req, &body, i.errFn)
+				if err != nil || resp == nil {
+					return err
+				}
+				if err := json.Unmarshal(body, &i.page); err != nil {
+					return err
+				}
+
+				i.req = nextPage(req, body)
+				return nil
+			}
+			`,
+		},
+		{"offset", config.PageOffset, pets, `
+			// fetch requests the next page of the PetIter.
+			func (i *PetIter) fetch() error {
+				req := i.req
+				i.page, i.i, i.req = nil, 0, nil
+
+				resp, err := i.backend.Do(i.ctx, req, &i.page, i.errFn)
+				if err != nil || resp == nil {
+					return err
+				}
+
+				i.req = nextPage(req, len(i.page))
+				return nil
+			}
+			`,
+		},
+		{"cursor envelope", config.PageCursor, pages, `
+			import "encoding/json"
+
+			// fetch requests the next page of the PetPageIter.
+			func (i *PetPageIter) fetch() error {
+				req := i.req
+				i.page, i.i, i.req = nil, 0, nil
+				i.envelope = PetPage{}
+
+				// The body is decoded twice, for the page and its cursor.
+				var body json.RawMessage
+				resp, err := i.backend.Do(i.ctx, req, &body, i.errFn)
+				if err != nil || resp == nil {
+					return err
+				}
+				if err := json.Unmarshal(body, &i.envelope); err != nil {
+					return err
+				}
+				if i.envelope.Data != nil {
+					i.page = *i.envelope.Data
+				}
+
+				i.req = nextPage(req, body)
+				return nil
+			}
+			`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			f := jen.NewFile("test")
+			defineFetch(f, &tc.iter, decls, config.Pagination{Strategy: tc.strategy})
+
+			var buf bytes.Buffer
+			if err := f.Render(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			formatted, _ := format.Source([]byte("package test\n" + tc.out))
+			if buf.String() != string(formatted) {
+				t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), formatted)
+			}
+		})
+	}
+}
+
+func TestDefinePaging(t *testing.T) {
+	f := jen.NewFile("test")
+	definePaging(f, config.Pagination{Strategy: config.PageNumber, Param: "page", Limit: "per_page"})
+
+	var buf bytes.Buffer
+	if err := f.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, _ := format.Source([]byte(`package test
+
+	import (
+		"net/http"
+		"net/url"
+		"strconv"
+	)
+
+	// nextPage returns the request for the page after the one requested by req,
+	// which held n items, or nil if it was the last.
+	func nextPage(req *http.Request, n int) *http.Request {
+		if n == 0 {
+			return nil
+		}
+
+		q := req.URL.Query()
+		if limit, err := strconv.Atoi(q.Get("per_page")); err == nil && n < limit {
+			return nil
+		}
+		page, err := strconv.Atoi(q.Get("page"))
+		if err != nil {
+			page = 1
+		}
+		q.Set("page", strconv.Itoa(page+1))
+
+		u := *req.URL
+		u.RawQuery = q.Encode()
+		return pageRequest(req, &u)
+	}
+
+	// pageRequest returns a copy of req for the URL u, with a fresh body.
+	func pageRequest(req *http.Request, u *url.URL) *http.Request {
+		next := req.Clone(req.Context())
+		next.URL, next.Host = u, ""
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				next.Body = body
+			}
+		}
+		return next
+	}
+	`))
+	if buf.String() != string(formatted) {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), formatted)
+	}
+}
+
+func TestDefineRange(t *testing.T) {
+	f := jen.NewFile("test")
+	defineRange(f, &pkg.Iter{
+		Name:   "PetIter",
+		Return: &pkg.PointerType{Type: &pkg.IdentType{Name: "Pet"}},
+	})
+
+	var buf bytes.Buffer
+	if err := f.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, _ := format.Source([]byte(`package test
+
+	import (
+		"context"
+		"iter"
+	)
+
+	// All returns an iterator over the Pets of the PetIter, for use in for range
+	// loops. Iteration stops after the first error, which is returned with a nil
+	// Pet, or once ctx is done. The PetIter is closed when iteration stops.
+	func (i *PetIter) All(ctx context.Context) iter.Seq2[*Pet, error] {
+		return func(yield func(*Pet, error) bool) {
+			defer i.Close()
+
+			for {
+				if err := ctx.Err(); err != nil {
+					yield(nil, err)
+					return
+				}
+				if !i.Next() {
+					return
+				}
+
+				v, err := i.Current()
+				if !yield(v, err) || err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	// Collect returns up to limit of the Pets of the PetIter, or all of them when
+	// limit is 0 or less, stopping at the first error. The PetIter is closed once
+	// they are collected.
+	func (i *PetIter) Collect(ctx context.Context, limit int) ([]*Pet, error) {
+		var all []*Pet
+		for v, err := range i.All(ctx) {
+			if err != nil {
+				return all, err
+			}
+
+			all = append(all, v)
+			if len(all) == limit {
+				break
+			}
+		}
+		return all, nil
+	}
+	`))
+	if buf.String() != string(formatted) {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), formatted)
+	}
+}
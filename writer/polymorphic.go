@@ -0,0 +1,191 @@
+package writer
+
+import (
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+// polymorphism holds the interface types of a package, by name.
+type polymorphism map[string]*pkg.TypeDecl
+
+func newPolymorphism(p *pkg.Package) polymorphism {
+	poly := make(polymorphism)
+	for i := range p.TypeDecls {
+		if d := &p.TypeDecls[i]; d.Discriminator != nil {
+			poly[d.Name] = d
+		}
+	}
+
+	return poly
+}
+
+// isInterface reports if typ is one of the interface types.
+func (poly polymorphism) isInterface(typ pkg.Type) bool {
+	it, ok := typ.(*pkg.IdentType)
+	return ok && it.Qualifier == "" && poly[it.Name] != nil
+}
+
+// holder returns the name of the unexported struct that decodes values of the
+// interface type name.
+func holder(name string) string {
+	return formatVarName(name, "JSON")
+}
+
+// marker returns the name of the unexported method that seals the interface
+// type name.
+func marker(name string) string {
+	return "is" + name
+}
+
+// definePolymorphic writes the interface type d, sealed to its variants, and
+// the holder type that selects the variant to decode by the value of the
+// discriminator property.
+func definePolymorphic(f *jen.File, d *pkg.TypeDecl) {
+	disc := d.Discriminator
+
+	f.Type().Id(d.Name).Interface(jen.Id(marker(d.Name)).Params())
+
+	types := []string{disc.Base}
+	for _, v := range disc.Variants {
+		if !containsString(types, v.Type) {
+			types = append(types, v.Type)
+		}
+	}
+	for _, t := range types {
+		f.Func().Params(jen.Op("*").Id(t)).Id(marker(d.Name)).Params().Block()
+	}
+
+	h := holder(d.Name)
+	f.Comment(formatComment("%s decodes values of %s, by their %s property.", h, d.Name, disc.Property))
+	f.Type().Id(h).Struct(jen.Id(d.Name))
+
+	f.Comment(formatComment("UnmarshalJSON decodes b into the kind of %s selected by its %s property.", d.Name, disc.Property))
+	f.Func().Params(jen.Id("h").Op("*").Id(h)).Id("UnmarshalJSON").Params(jen.Id("b").Index().Byte()).Error().BlockFunc(func(g *jen.Group) {
+		g.If(jen.String().Call(jen.Id("b")).Op("==").Lit("null")).Block(
+			jen.Id("h").Dot(d.Name).Op("=").Nil(),
+			jen.Return(jen.Nil()),
+		)
+		g.Line()
+
+		g.Var().Id("d").Struct(
+			jen.Id("Value").String().Tag(map[string]string{"json": disc.Property}),
+		)
+		g.If(
+			jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("b"), jen.Op("&").Id("d")),
+			jen.Err().Op("!=").Nil(),
+		).Block(jen.Return(jen.Err()))
+		g.Line()
+
+		g.Switch(jen.Id("d").Dot("Value")).BlockFunc(func(g *jen.Group) {
+			for _, t := range types[1:] {
+				var values []jen.Code
+				for _, v := range disc.Variants {
+					if v.Type == t {
+						values = append(values, jen.Lit(v.Value))
+					}
+				}
+				g.Case(values...).Block(jen.Id("h").Dot(d.Name).Op("=").Op("&").Id(t).Values())
+			}
+			g.Default().Block(jen.Id("h").Dot(d.Name).Op("=").Op("&").Id(disc.Base).Values())
+		})
+		g.Line()
+
+		g.Return(jen.Qual("encoding/json", "Unmarshal").Call(jen.Id("b"), jen.Id("h").Dot(d.Name)))
+	})
+}
+
+// defineFieldDecoding writes an UnmarshalJSON method for the struct type d, if
+// any of its fields hold interface types, decoding them through their holder
+// types.
+//
+// XXX interface values in maps, and in fields of embedded structs, are not
+// decoded.
+func defineFieldDecoding(f *jen.File, poly polymorphism, d *pkg.TypeDecl) {
+	st, ok := d.Type.(*pkg.StructType)
+	if !ok {
+		return
+	}
+
+	var shadows []jen.Code
+	var copies []jen.Code
+	for _, fl := range st.Fields {
+		if fl.ID == "" {
+			continue
+		}
+
+		orig := fl.Orig
+		if orig == "" {
+			orig = fl.ID
+		}
+		src := jen.Id("v").Dot(fl.ID)
+		dst := jen.Id("x").Dot(fl.ID)
+
+		switch t := fl.Type.(type) {
+		case *pkg.IdentType:
+			if !poly.isInterface(t) {
+				continue
+			}
+			shadows = append(shadows, jen.Id(fl.ID).Id(holder(t.Name)).Tag(map[string]string{"json": orig}))
+			copies = append(copies, dst.Op("=").Add(src).Dot(t.Name))
+		case *pkg.SliceType:
+			if !poly.isInterface(t.Type) {
+				continue
+			}
+			name := typeName(t.Type)
+			shadows = append(shadows, jen.Id(fl.ID).Index().Id(holder(name)).Tag(map[string]string{"json": orig}))
+			copies = append(copies, jen.If(src.Clone().Op("!=").Nil()).Block(
+				dst.Clone().Op("=").Make(jen.Index().Id(name), jen.Len(src)),
+				jen.For(jen.List(jen.Id("i"), jen.Id("h")).Op(":=").Range().Add(src)).Block(
+					dst.Clone().Index(jen.Id("i")).Op("=").Id("h").Dot(name),
+				),
+			))
+		case *pkg.PointerType:
+			sl, ok := t.Type.(*pkg.SliceType)
+			if !ok || !poly.isInterface(sl.Type) {
+				continue
+			}
+			name := typeName(sl.Type)
+			shadows = append(shadows, jen.Id(fl.ID).Op("*").Index().Id(holder(name)).Tag(map[string]string{"json": orig}))
+			copies = append(copies, jen.If(src.Clone().Op("!=").Nil()).Block(
+				jen.Id("s").Op(":=").Make(jen.Index().Id(name), jen.Len(jen.Op("*").Add(src))),
+				jen.For(jen.List(jen.Id("i"), jen.Id("h")).Op(":=").Range().Op("*").Add(src)).Block(
+					jen.Id("s").Index(jen.Id("i")).Op("=").Id("h").Dot(name),
+				),
+				dst.Clone().Op("=").Op("&").Id("s"),
+			))
+		}
+	}
+
+	if len(shadows) == 0 {
+		return
+	}
+
+	alias := formatVarName(d.Name)
+	f.Comment(formatComment("UnmarshalJSON decodes b into x, selecting the kinds of its polymorphic fields."))
+	f.Func().Params(jen.Id("x").Op("*").Id(d.Name)).Id("UnmarshalJSON").Params(jen.Id("b").Index().Byte()).Error().BlockFunc(func(g *jen.Group) {
+		g.Type().Id(alias).Id(d.Name)
+		g.Var().Id("v").Struct(append([]jen.Code{jen.Op("*").Id(alias)}, shadows...)...)
+		g.Id("v").Dot(alias).Op("=").Parens(jen.Op("*").Id(alias)).Parens(jen.Id("x"))
+		g.If(
+			jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("b"), jen.Op("&").Id("v")),
+			jen.Err().Op("!=").Nil(),
+		).Block(jen.Return(jen.Err()))
+		g.Line()
+
+		for _, c := range copies {
+			g.Add(c)
+		}
+		g.Return(jen.Nil())
+	})
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,142 @@
+package writer
+
+import (
+	"bytes"
+	"go/format"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestDefinePolymorphic(t *testing.T) {
+	d := &pkg.TypeDecl{Name: "Event", Type: &pkg.InterfaceType{}, Discriminator: &pkg.Discriminator{
+		Property: "kind",
+		Base:     "EventBase",
+		Variants: []pkg.Variant{
+			{Value: "click", Type: "ClickEvent"},
+			{Value: "scroll", Type: "ScrollEvent"},
+			{Value: "tap", Type: "ClickEvent"},
+		},
+	}}
+
+	f := jen.NewFile("test")
+	definePolymorphic(f, d)
+
+	var buf bytes.Buffer
+	if err := f.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := format.Source([]byte(`package test
+
+import "encoding/json"
+
+type Event interface {
+	isEvent()
+}
+
+func (*EventBase) isEvent()   {}
+func (*ClickEvent) isEvent()  {}
+func (*ScrollEvent) isEvent() {}
+
+// eventJSON decodes values of Event, by their kind property.
+type eventJSON struct {
+	Event
+}
+
+// UnmarshalJSON decodes b into the kind of Event selected by its kind property.
+func (h *eventJSON) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		h.Event = nil
+		return nil
+	}
+
+	var d struct {
+		Value string ` + "`json:\"kind\"`" + `
+	}
+	if err := json.Unmarshal(b, &d); err != nil {
+		return err
+	}
+
+	switch d.Value {
+	case "click", "tap":
+		h.Event = &ClickEvent{}
+	case "scroll":
+		h.Event = &ScrollEvent{}
+	default:
+		h.Event = &EventBase{}
+	}
+
+	return json.Unmarshal(b, h.Event)
+}
+`))
+	if buf.String() != string(expected) {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestDefineFieldDecoding(t *testing.T) {
+	p := &pkg.Package{TypeDecls: []pkg.TypeDecl{
+		{Name: "Event", Type: &pkg.InterfaceType{}, Discriminator: &pkg.Discriminator{Property: "kind", Base: "EventBase"}},
+		{Name: "Feed", Type: &pkg.StructType{Fields: []pkg.Field{
+			{ID: "Name", Orig: "name", Type: &pkg.IdentType{Name: "string"}},
+			{ID: "Latest", Orig: "latest", Type: &pkg.IdentType{Name: "Event"}},
+			{ID: "Events", Orig: "events", Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "Event"}}},
+			{ID: "Older", Orig: "older", Type: &pkg.PointerType{Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "Event"}}}},
+		}}},
+		{Name: "Plain", Type: &pkg.StructType{Fields: []pkg.Field{
+			{ID: "Name", Orig: "name", Type: &pkg.IdentType{Name: "string"}},
+		}}},
+	}}
+	poly := newPolymorphism(p)
+
+	f := jen.NewFile("test")
+	defineFieldDecoding(f, poly, &p.TypeDecls[1])
+	defineFieldDecoding(f, poly, &p.TypeDecls[2])
+
+	var buf bytes.Buffer
+	if err := f.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := format.Source([]byte(`package test
+
+import "encoding/json"
+
+// UnmarshalJSON decodes b into x, selecting the kinds of its polymorphic fields.
+func (x *Feed) UnmarshalJSON(b []byte) error {
+	type feed Feed
+	var v struct {
+		*feed
+		Latest eventJSON    ` + "`json:\"latest\"`" + `
+		Events []eventJSON  ` + "`json:\"events\"`" + `
+		Older  *[]eventJSON ` + "`json:\"older\"`" + `
+	}
+	v.feed = (*feed)(x)
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	x.Latest = v.Latest.Event
+	if v.Events != nil {
+		x.Events = make([]Event, len(v.Events))
+		for i, h := range v.Events {
+			x.Events[i] = h.Event
+		}
+	}
+	if v.Older != nil {
+		s := make([]Event, len(*v.Older))
+		for i, h := range *v.Older {
+			s[i] = h.Event
+		}
+		x.Older = &s
+	}
+	return nil
+}
+`))
+	if buf.String() != string(expected) {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
@@ -23,6 +23,8 @@ func writeType(typ pkg.Type) func(s *jen.Statement) {
 			s.Op("*").Do(writeType(t.Type))
 		case *pkg.IterType:
 			s.Do(writeType(t.Type))
+		case *pkg.StreamType:
+			s.Op("*").Id("Stream")
 		case *pkg.StructType:
 			s.Struct(convertFields(t.Fields)...)
 		case *pkg.MapType:
@@ -93,6 +95,8 @@ func typeName(typ pkg.Type) string {
 		return t.Name
 	case *pkg.IterType:
 		return typeName(t.Type)
+	case *pkg.StreamType:
+		return "Stream"
 	case *pkg.SliceType:
 		return typeName(t.Type)
 	case *pkg.PointerType:
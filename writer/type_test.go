@@ -22,6 +22,7 @@ func TestWriteType(t *testing.T) {
 		{"pointer", &pkg.PointerType{Type: &pkg.IdentType{Name: "string"}}, "*string"},
 		{"empty struct", &pkg.StructType{}, "struct{}"},
 		{"empty interface", &pkg.InterfaceType{}, "interface{}"},
+		{"stream", &pkg.StreamType{}, "*Stream"},
 		{"struct",
 			&pkg.StructType{Fields: []pkg.Field{
 				{ID: "Foo", Type: &pkg.IdentType{Name: "string"}},
@@ -0,0 +1,143 @@
+package writer
+
+import (
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+// defineUnion writes the union type d as a struct holding the value of one of
+// its variants, with methods to get and set each variant, and to encode and
+// decode the value held.
+func defineUnion(f *jen.File, poly polymorphism, d *pkg.TypeDecl, u *pkg.UnionType) {
+	recv := strings.ToLower(d.Name[:1])
+
+	f.Type().Id(d.Name).Struct(jen.Id("value").Interface())
+
+	for _, v := range u.Variants {
+		f.Comment(formatComment("As%s returns the value of %s, if it holds the %s variant.", v.Name, recv, v.Name))
+		f.Func().Params(jen.Id(recv).Id(d.Name)).Id("As"+v.Name).Params().Params(jen.Do(writeType(v.Type)), jen.Bool()).Block(
+			jen.List(jen.Id("val"), jen.Id("ok")).Op(":=").Id(recv).Dot("value").Assert(jen.Do(writeType(v.Type))),
+			jen.Return(jen.Id("val"), jen.Id("ok")),
+		)
+
+		f.Comment(formatComment("Set%s sets %s to hold the %s variant val.", v.Name, recv, v.Name))
+		f.Func().Params(jen.Id(recv).Op("*").Id(d.Name)).Id("Set" + v.Name).Params(jen.Id("val").Do(writeType(v.Type))).Block(
+			jen.Id(recv).Dot("value").Op("=").Id("val"),
+		)
+	}
+
+	f.Comment(formatComment("MarshalJSON encodes the variant held by %s.", recv))
+	f.Func().Params(jen.Id(recv).Id(d.Name)).Id("MarshalJSON").Params().Params(jen.Index().Byte(), jen.Error()).Block(
+		jen.Return(jen.Qual("encoding/json", "Marshal").Call(jen.Id(recv).Dot("value"))),
+	)
+
+	comment := "UnmarshalJSON decodes data into the first variant of %s that accepts it."
+	if u.Discriminator != "" {
+		comment = "UnmarshalJSON decodes data into the variant of %s selected by its " + u.Discriminator +
+			" property,\nor else the first variant that accepts it."
+	}
+	f.Comment(formatComment(comment, d.Name))
+	f.Func().Params(jen.Id(recv).Op("*").Id(d.Name)).Id("UnmarshalJSON").Params(jen.Id("data").Index().Byte()).Error().BlockFunc(func(g *jen.Group) {
+		g.If(jen.String().Call(jen.Id("data")).Op("==").Lit("null")).Block(
+			jen.Id(recv).Dot("value").Op("=").Nil(),
+			jen.Return(jen.Nil()),
+		)
+		g.Line()
+
+		if selectable(u) {
+			g.Var().Id("disc").Struct(
+				jen.Id("Value").String().Tag(map[string]string{"json": u.Discriminator}),
+			)
+			g.If(
+				jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Op("&").Id("disc")),
+				jen.Err().Op("==").Nil(),
+			).Block(
+				jen.Switch(jen.Id("disc").Dot("Value")).BlockFunc(func(g *jen.Group) {
+					for _, v := range u.Variants {
+						if len(v.Values) == 0 {
+							continue
+						}
+
+						var values []jen.Code
+						for _, val := range v.Values {
+							values = append(values, jen.Lit(val))
+						}
+						g.Case(values...).BlockFunc(func(g *jen.Group) {
+							g.Add(declareVariant(poly, v.Type))
+							g.If(
+								jen.Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(jen.Id("data"), jen.Op("&").Id("val")),
+								jen.Err().Op("!=").Nil(),
+							).Block(jen.Return(jen.Err()))
+							g.Id(recv).Dot("value").Op("=").Add(variantValue(poly, v.Type))
+							g.Return(jen.Nil())
+						})
+					}
+				}),
+			)
+			g.Line()
+		}
+
+		for _, v := range u.Variants {
+			g.BlockFunc(func(g *jen.Group) {
+				g.Add(declareVariant(poly, v.Type))
+				g.If(
+					jen.Err().Op(":=").Id("unmarshalStrict").Call(jen.Id("data"), jen.Op("&").Id("val")),
+					jen.Err().Op("==").Nil(),
+				).Block(
+					jen.Id(recv).Dot("value").Op("=").Add(variantValue(poly, v.Type)),
+					jen.Return(jen.Nil()),
+				)
+			})
+		}
+		g.Line()
+
+		g.Return(jen.Qual("errors", "New").Call(jen.Lit("json: value matches no variant of " + d.Name)))
+	})
+}
+
+// selectable reports if any variant of u is selected by a discriminator value.
+func selectable(u *pkg.UnionType) bool {
+	for _, v := range u.Variants {
+		if len(v.Values) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// declareVariant declares val to decode a variant of type typ into, using the
+// holder type for interface types.
+func declareVariant(poly polymorphism, typ pkg.Type) jen.Code {
+	if poly.isInterface(typ) {
+		return jen.Var().Id("val").Id(holder(typeName(typ)))
+	}
+
+	return jen.Var().Id("val").Do(writeType(typ))
+}
+
+// variantValue returns the value decoded into val for a variant of type typ.
+func variantValue(poly polymorphism, typ pkg.Type) jen.Code {
+	if poly.isInterface(typ) {
+		return jen.Id("val").Dot(typeName(typ))
+	}
+
+	return jen.Id("val")
+}
+
+// defineUnmarshalStrict writes the helper used by unions to decode variants,
+// which fails for objects with fields the variant does not have.
+func defineUnmarshalStrict(f *jen.File) {
+	f.Comment(formatComment(`
+		unmarshalStrict decodes data into v, failing if data has fields that v
+		does not.
+	`))
+	f.Func().Id("unmarshalStrict").Params(jen.Id("data").Index().Byte(), jen.Id("v").Interface()).Error().Block(
+		jen.Id("dec").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Qual("bytes", "NewReader").Call(jen.Id("data"))),
+		jen.Id("dec").Dot("DisallowUnknownFields").Call(),
+		jen.Return(jen.Id("dec").Dot("Decode").Call(jen.Id("v"))),
+	)
+}
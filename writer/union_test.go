@@ -0,0 +1,133 @@
+package writer
+
+import (
+	"bytes"
+	"go/format"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestDefineUnion(t *testing.T) {
+	u := &pkg.UnionType{
+		Discriminator: "type",
+		Variants: []pkg.UnionVariant{
+			{Name: "Card", Type: &pkg.IdentType{Name: "Card"}, Values: []string{"card"}},
+			{Name: "Event", Type: &pkg.IdentType{Name: "Event"}},
+			{Name: "Strings", Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "string"}}},
+		},
+	}
+	d := &pkg.TypeDecl{Name: "Source", Type: u}
+	poly := polymorphism{"Event": {Name: "Event", Discriminator: &pkg.Discriminator{Property: "kind"}}}
+
+	f := jen.NewFile("test")
+	defineUnion(f, poly, d, u)
+
+	var buf bytes.Buffer
+	if err := f.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := format.Source([]byte(`package test
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+type Source struct {
+	value interface{}
+}
+
+// AsCard returns the value of s, if it holds the Card variant.
+func (s Source) AsCard() (Card, bool) {
+	val, ok := s.value.(Card)
+	return val, ok
+}
+
+// SetCard sets s to hold the Card variant val.
+func (s *Source) SetCard(val Card) {
+	s.value = val
+}
+
+// AsEvent returns the value of s, if it holds the Event variant.
+func (s Source) AsEvent() (Event, bool) {
+	val, ok := s.value.(Event)
+	return val, ok
+}
+
+// SetEvent sets s to hold the Event variant val.
+func (s *Source) SetEvent(val Event) {
+	s.value = val
+}
+
+// AsStrings returns the value of s, if it holds the Strings variant.
+func (s Source) AsStrings() ([]string, bool) {
+	val, ok := s.value.([]string)
+	return val, ok
+}
+
+// SetStrings sets s to hold the Strings variant val.
+func (s *Source) SetStrings(val []string) {
+	s.value = val
+}
+
+// MarshalJSON encodes the variant held by s.
+func (s Source) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.value)
+}
+
+// UnmarshalJSON decodes data into the variant of Source selected by its type property,
+// or else the first variant that accepts it.
+func (s *Source) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		s.value = nil
+		return nil
+	}
+
+	var disc struct {
+		Value string ` + "`json:\"type\"`" + `
+	}
+	if err := json.Unmarshal(data, &disc); err == nil {
+		switch disc.Value {
+		case "card":
+			var val Card
+			if err := json.Unmarshal(data, &val); err != nil {
+				return err
+			}
+			s.value = val
+			return nil
+		}
+	}
+
+	{
+		var val Card
+		if err := unmarshalStrict(data, &val); err == nil {
+			s.value = val
+			return nil
+		}
+	}
+	{
+		var val eventJSON
+		if err := unmarshalStrict(data, &val); err == nil {
+			s.value = val.Event
+			return nil
+		}
+	}
+	{
+		var val []string
+		if err := unmarshalStrict(data, &val); err == nil {
+			s.value = val
+			return nil
+		}
+	}
+
+	return errors.New("json: value matches no variant of Source")
+}
+`))
+	if buf.String() != string(expected) {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
@@ -0,0 +1,507 @@
+package writer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+// validator generates code checking values against the constraints of their
+// schemas.
+type validator struct {
+	decls     map[string]*pkg.TypeDecl
+	validated map[string]bool // struct types with a Validate method
+
+	patterns map[string]string // regular expressions to the vars holding them
+	used     bool              // if any checks have been written
+}
+
+// newValidator returns a validator for the types of p. Struct types used by
+// method parameters, directly or through other types, have a Validate
+// method if any of their fields have constraints to check.
+func newValidator(p *pkg.Package) *validator {
+	vd := &validator{
+		decls:     make(map[string]*pkg.TypeDecl, len(p.TypeDecls)),
+		validated: make(map[string]bool),
+		patterns:  make(map[string]string),
+	}
+	for i := range p.TypeDecls {
+		vd.decls[p.TypeDecls[i].Name] = &p.TypeDecls[i]
+	}
+
+	reached := make(map[string]bool)
+	var reach func(t pkg.Type)
+	reach = func(t pkg.Type) {
+		switch t := t.(type) {
+		case *pkg.IdentType:
+			d, ok := vd.decls[t.Name]
+			if t.Qualifier != "" || !ok || reached[t.Name] {
+				return
+			}
+			reached[t.Name] = true
+			reach(d.Type)
+		case *pkg.PointerType:
+			reach(t.Type)
+		case *pkg.SliceType:
+			reach(t.Type)
+		case *pkg.MapType:
+			reach(t.Value)
+		case *pkg.StructType:
+			for _, f := range t.Fields {
+				reach(f.Type)
+			}
+		}
+	}
+	for _, c := range p.Clients {
+		for _, m := range c.Methods {
+			for _, param := range m.Params {
+				reach(param.Type)
+			}
+		}
+	}
+
+	// Types with checks make the types holding them need checks too, so
+	// repeat until no more are found.
+	for found := true; found; {
+		found = false
+		for name := range reached {
+			st, ok := vd.decls[name].Type.(*pkg.StructType)
+			if !ok || vd.validated[name] {
+				continue
+			}
+
+			if len(vd.fieldChecks("v", name, st, returnErr)) > 0 {
+				vd.validated[name] = true
+				found = true
+			}
+		}
+	}
+
+	vd.patterns = make(map[string]string)
+	vd.used = false
+	return vd
+}
+
+// hasMethods reports if values of typ have their own methods for checking
+// them, as enums and validated structs do.
+func (vd *validator) hasMethods(typ pkg.Type) bool {
+	it, ok := typ.(*pkg.IdentType)
+	if !ok || it.Qualifier != "" {
+		return false
+	}
+
+	d, ok := vd.decls[it.Name]
+	return ok && (len(d.Enum) > 0 || vd.validated[it.Name])
+}
+
+// returnErr is the failure func for Validate methods.
+func returnErr(err jen.Code) []jen.Code {
+	return []jen.Code{jen.Return(err)}
+}
+
+// defineValidate writes the Validate method for the struct type d.
+func defineValidate(f *jen.File, vd *validator, d *pkg.TypeDecl) {
+	recv := strings.ToLower(d.Name[:1])
+
+	f.Comment(formatComment(`
+		Validate checks the values of %s against the constraints of the API,
+		returning a *ValidationError for the first that is not met.
+	`, recv))
+	f.Func().Params(jen.Id(recv).Op("*").Id(d.Name)).Id("Validate").Params().Error().BlockFunc(func(g *jen.Group) {
+		for _, c := range vd.fieldChecks(recv, d.Name, d.Type.(*pkg.StructType), returnErr) {
+			g.Add(c)
+		}
+		g.Return(jen.Nil())
+	})
+}
+
+// fieldChecks returns the checks for the fields of st, the type name, held by
+// the variable recv.
+func (vd *validator) fieldChecks(recv, name string, st *pkg.StructType, fail func(jen.Code) []jen.Code) []jen.Code {
+	var out []jen.Code
+	for _, field := range st.Fields {
+		field := field
+		v := value{
+			expr: func() *jen.Statement { return jen.Id(recv).Dot(field.ID) },
+			name: formatVarName(name, field.ID),
+		}
+
+		switch {
+		case field.ID == "": // embedded, so its fields are promoted
+			embedded := typeName(field.Type)
+			v.expr = func() *jen.Statement { return jen.Id(recv).Dot(embedded) }
+		case field.Orig != "":
+			v.path = valuePath{{lit: field.Orig}}
+		default:
+			v.path = valuePath{{lit: field.ID}}
+		}
+
+		out = append(out, vd.checks(v, field.Type, field.Constraints, fail)...)
+	}
+
+	return out
+}
+
+// paramChecks returns the checks for the parameters of m, to run before
+// sending a request.
+func (vd *validator) paramChecks(m *pkg.Method, fail func(jen.Code) []jen.Code) []jen.Code {
+	var out []jen.Code
+	for _, p := range m.Params {
+		p := p
+		v := value{
+			expr: func() *jen.Statement { return jen.Id(p.Arg) },
+			name: formatVarName(m.Name, p.ID),
+		}
+
+		switch {
+		case p.Kind == pkg.Body, p.Kind == pkg.Opts: // fields are named in the errors of Validate
+		case p.Orig != "":
+			v.path = valuePath{{lit: p.Orig}}
+		default:
+			v.path = valuePath{{lit: p.ID}}
+		}
+
+		out = append(out, vd.checks(v, p.Type, p.Constraints, fail)...)
+	}
+
+	return out
+}
+
+// formatVarName joins parts into an unexported camel case name.
+func formatVarName(parts ...string) string {
+	name := ""
+	for _, p := range parts {
+		if p != "" {
+			name += strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// value is a value to check.
+type value struct {
+	expr  func() *jen.Statement
+	path  valuePath // empty for values whose fields are reported on their own
+	name  string    // used to name package level vars, such as patterns
+	depth int       // of loops over items
+}
+
+// pathPart is a literal part of a path to a value, or an expression for an
+// index or key.
+type pathPart struct {
+	lit  string
+	expr func() *jen.Statement
+}
+
+// valuePath is the path to a value, for errors, ie pets[2].name.
+type valuePath []pathPart
+
+func (p valuePath) with(parts ...pathPart) valuePath {
+	return append(p[:len(p):len(p)], parts...)
+}
+
+func (p valuePath) index(i string) valuePath {
+	return p.with(
+		pathPart{lit: "["},
+		pathPart{expr: func() *jen.Statement { return jen.Qual("strconv", "Itoa").Call(jen.Id(i)) }},
+		pathPart{lit: "]"},
+	)
+}
+
+func (p valuePath) key(k string) valuePath {
+	return p.with(
+		pathPart{lit: "["},
+		pathPart{expr: func() *jen.Statement { return jen.Qual("strconv", "Quote").Call(jen.Id(k)) }},
+		pathPart{lit: "]"},
+	)
+}
+
+// code returns an expression for the path, joining adjacent literals.
+func (p valuePath) code() *jen.Statement {
+	var s *jen.Statement
+	add := func(c jen.Code) {
+		if s == nil {
+			s = jen.Add(c)
+		} else {
+			s.Op("+").Add(c)
+		}
+	}
+
+	lit := ""
+	for _, part := range p {
+		if part.expr == nil {
+			lit += part.lit
+			continue
+		}
+
+		if lit != "" {
+			add(jen.Lit(lit))
+			lit = ""
+		}
+		add(part.expr())
+	}
+	if lit != "" {
+		add(jen.Lit(lit))
+	}
+	if s == nil {
+		return jen.Lit("")
+	}
+
+	return s
+}
+
+// checks returns statements checking the value v, of type typ, against c.
+// fail returns the statements to run with the error for a failed check.
+func (vd *validator) checks(v value, typ pkg.Type, c *pkg.Constraints, fail func(jen.Code) []jen.Code) []jen.Code {
+	failed := func(reason string, args ...interface{}) []jen.Code {
+		vd.used = true
+		return fail(jen.Op("&").Id("ValidationError").Values(jen.Dict{
+			jen.Id("Field"):  v.path.code(),
+			jen.Id("Reason"): jen.Lit(fmt.Sprintf(reason, args...)),
+		}))
+	}
+
+	if c == nil {
+		c = &pkg.Constraints{}
+	}
+
+	var out []jen.Code
+	switch t := typ.(type) {
+	case *pkg.PointerType:
+		elem := v
+		if !vd.hasMethods(t.Type) {
+			elem.expr = func() *jen.Statement { return jen.Op("*").Add(v.expr()) }
+		}
+
+		if inner := vd.checks(elem, t.Type, c, fail); len(inner) > 0 {
+			out = append(out, jen.If(v.expr().Op("!=").Nil()).Block(inner...))
+		}
+	case *pkg.SliceType:
+		if c.Required {
+			out = append(out, jen.If(v.expr().Op("==").Nil()).Block(failed("is required")...))
+		}
+		if c.MinItems != nil {
+			out = append(out, jen.If(jen.Len(v.expr()).Op("<").Lit(int(*c.MinItems))).Block(
+				failed("must have at least %d items", *c.MinItems)...,
+			))
+		}
+		if c.MaxItems != nil {
+			out = append(out, jen.If(jen.Len(v.expr()).Op(">").Lit(int(*c.MaxItems))).Block(
+				failed("must have at most %d items", *c.MaxItems)...,
+			))
+		}
+
+		i, item := loopVar("i", v.depth), loopVar("v", v.depth)
+		iv := value{
+			expr:  func() *jen.Statement { return jen.Id(item) },
+			path:  v.path.index(i),
+			name:  v.name + "Items",
+			depth: v.depth + 1,
+		}
+		if inner := vd.checks(iv, t.Type, c.Items, fail); len(inner) > 0 {
+			out = append(out, jen.For(jen.List(jen.Id(i), jen.Id(item)).Op(":=").Range().Add(v.expr())).Block(inner...))
+		}
+	case *pkg.MapType:
+		if c.Required {
+			out = append(out, jen.If(v.expr().Op("==").Nil()).Block(failed("is required")...))
+		}
+
+		k, item := loopVar("k", v.depth), loopVar("v", v.depth)
+		kv := value{
+			expr:  func() *jen.Statement { return jen.Id(item) },
+			path:  v.path.key(k),
+			name:  v.name + "Value",
+			depth: v.depth + 1,
+		}
+		if inner := vd.checks(kv, t.Value, c.Items, fail); len(inner) > 0 {
+			out = append(out, jen.For(jen.List(jen.Id(k), jen.Id(item)).Op(":=").Range().Add(v.expr())).Block(inner...))
+		}
+	case *pkg.IdentType:
+		if t.Qualifier != "" {
+			break // XXX mapped types can't be checked
+		}
+
+		if d, ok := vd.decls[t.Name]; ok {
+			switch {
+			case len(d.Enum) > 0:
+				var values []string
+				for _, e := range d.Enum {
+					values = append(values, fmt.Sprint(e.Value))
+				}
+				out = append(out, jen.If(jen.Op("!").Add(v.expr()).Dot("IsValid").Call()).Block(
+					failed("must be one of %s", strings.Join(values, ", "))...,
+				))
+			case vd.validated[t.Name]:
+				vd.used = true
+				err := jen.Err()
+				if len(v.path) > 0 {
+					err = jen.Id("validationPath").Call(v.path.code(), jen.Err())
+				}
+				out = append(out, jen.If(
+					jen.Err().Op(":=").Add(v.expr()).Dot("Validate").Call(),
+					jen.Err().Op("!=").Nil(),
+				).Block(fail(err)...))
+			}
+			break
+		}
+
+		switch t.Name {
+		case "string":
+			if c.MinLength != nil {
+				out = append(out, jen.If(jen.Qual("unicode/utf8", "RuneCountInString").Call(v.expr()).Op("<").Lit(int(*c.MinLength))).Block(
+					failed("must be at least %d characters long", *c.MinLength)...,
+				))
+			}
+			if c.MaxLength != nil {
+				out = append(out, jen.If(jen.Qual("unicode/utf8", "RuneCountInString").Call(v.expr()).Op(">").Lit(int(*c.MaxLength))).Block(
+					failed("must be at most %d characters long", *c.MaxLength)...,
+				))
+			}
+			if c.Pattern != nil {
+				re := vd.patternVar(*c.Pattern, v.name+"Pattern")
+				out = append(out, jen.If(jen.Op("!").Id(re).Dot("MatchString").Call(v.expr())).Block(
+					failed("must match the pattern %s", *c.Pattern)...,
+				))
+			}
+		case "float32", "float64",
+			"int", "int8", "int16", "int32", "int64",
+			"uint", "uint8", "uint16", "uint32", "uint64":
+			float := strings.HasPrefix(t.Name, "float")
+			num := func(f float64) jen.Code {
+				if !float {
+					return jen.Lit(int(f))
+				}
+				return jen.Lit(f)
+			}
+
+			if c.Minimum != nil {
+				op, reason := "<", "must be at least %s"
+				if c.ExclusiveMinimum {
+					op, reason = "<=", "must be greater than %s"
+				}
+				out = append(out, jen.If(v.expr().Op(op).Add(num(*c.Minimum))).Block(
+					failed(reason, formatNumber(*c.Minimum))...,
+				))
+			}
+			if c.Maximum != nil {
+				op, reason := ">", "must be at most %s"
+				if c.ExclusiveMaximum {
+					op, reason = ">=", "must be less than %s"
+				}
+				out = append(out, jen.If(v.expr().Op(op).Add(num(*c.Maximum))).Block(
+					failed(reason, formatNumber(*c.Maximum))...,
+				))
+			}
+			if c.MultipleOf != nil && *c.MultipleOf != 0 {
+				cond := []jen.Code{v.expr().Op("%").Add(num(*c.MultipleOf)).Op("!=").Lit(0)}
+				if float {
+					// compare the quotient to a whole number, allowing for
+					// rounding errors, ie 0.3 / 0.1.
+					x := v.expr()
+					if t.Name == "float32" {
+						x = jen.Float64().Call(x)
+					}
+					cond = []jen.Code{
+						jen.Id("q").Op(":=").Add(x).Op("/").Add(num(*c.MultipleOf)),
+						jen.Qual("math", "Abs").Call(jen.Id("q").Op("-").Qual("math", "Round").Call(jen.Id("q"))).Op(">").Lit(1e-9),
+					}
+				}
+				out = append(out, jen.If(cond...).Block(
+					failed("must be a multiple of %s", formatNumber(*c.MultipleOf))...,
+				))
+			}
+		}
+	}
+
+	return out
+}
+
+// patternVar returns the name of the var holding the regular expression
+// pattern, declaring it as name if it is new.
+func (vd *validator) patternVar(pattern, name string) string {
+	if re, ok := vd.patterns[pattern]; ok {
+		return re
+	}
+
+	taken := make(map[string]bool, len(vd.patterns))
+	for _, re := range vd.patterns {
+		taken[re] = true
+	}
+	re := name
+	for i := 2; taken[re]; i++ {
+		re = name + strconv.Itoa(i)
+	}
+
+	vd.patterns[pattern] = re
+	return re
+}
+
+// loopVar names the variable for loops nested depth deep, so the variables
+// of outer loops remain available.
+func loopVar(name string, depth int) string {
+	if depth == 0 {
+		return name
+	}
+
+	return name + strconv.Itoa(depth)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// definePatterns declares the regular expressions used by checks.
+func definePatterns(f *jen.File, vd *validator) {
+	if len(vd.patterns) == 0 {
+		return
+	}
+
+	pats := make([]string, 0, len(vd.patterns))
+	for p := range vd.patterns {
+		pats = append(pats, p)
+	}
+	sort.Slice(pats, func(i, j int) bool { return vd.patterns[pats[i]] < vd.patterns[pats[j]] })
+
+	f.Var().DefsFunc(func(g *jen.Group) {
+		for _, p := range pats {
+			g.Id(vd.patterns[p]).Op("=").Qual("regexp", "MustCompile").Call(jen.Lit(p))
+		}
+	})
+}
+
+// defineValidationError defines the error type returned by checks, and the
+// helper used to add the path of a value to the errors from its fields.
+func defineValidationError(f *jen.File) {
+	f.Comment(formatComment(`
+		ValidationError is returned when a value does not meet the constraints of
+		the API, before a request is sent.
+	`))
+	f.Type().Id("ValidationError").Struct(
+		jen.Id("Field").String().Comment("path to the value, ie pets[2].name"),
+		jen.Id("Reason").String(),
+	)
+
+	f.Func().Params(jen.Id("e").Op("*").Id("ValidationError")).Id("Error").Params().String().Block(
+		jen.Return(jen.Lit("invalid ").Op("+").Id("e").Dot("Field").Op("+").Lit(": ").Op("+").Id("e").Dot("Reason")),
+	)
+	f.Line()
+
+	f.Func().Id("validationPath").Params(jen.Id("path").String(), jen.Err().Error()).Error().Block(
+		jen.If(
+			jen.List(jen.Id("ve"), jen.Id("ok")).Op(":=").Err().Assert(jen.Op("*").Id("ValidationError")),
+			jen.Id("ok"),
+		).Block(
+			jen.Return(jen.Op("&").Id("ValidationError").Values(jen.Dict{
+				jen.Id("Field"):  jen.Id("path").Op("+").Lit(".").Op("+").Id("ve").Dot("Field"),
+				jen.Id("Reason"): jen.Id("ve").Dot("Reason"),
+			})),
+		),
+		jen.Return(jen.Err()),
+	)
+}
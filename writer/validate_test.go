@@ -0,0 +1,216 @@
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/jbowes/oag/pkg"
+)
+
+func TestDefineValidate(t *testing.T) {
+	one, ten := int64(1), int64(10)
+	zero, hundred := 0.0, 100.0
+	three := uint64(3)
+	pattern := "^[a-z]+$"
+
+	p := &pkg.Package{
+		TypeDecls: []pkg.TypeDecl{
+			{Name: "Owner", Type: &pkg.StructType{Fields: []pkg.Field{
+				{ID: "Name", Type: &pkg.IdentType{Name: "string"}, Constraints: &pkg.Constraints{MinLength: &one}},
+			}}},
+			{Name: "Pet", Type: &pkg.StructType{Fields: []pkg.Field{
+				{ID: "Name", Orig: "name", Type: &pkg.IdentType{Name: "string"}, Constraints: &pkg.Constraints{
+					MaxLength: &ten, Pattern: &pattern,
+				}},
+				{ID: "Age", Orig: "age", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "int"}}, Constraints: &pkg.Constraints{
+					Minimum: &zero, Maximum: &hundred, ExclusiveMaximum: true,
+				}},
+				{ID: "Status", Orig: "status", Type: &pkg.IdentType{Name: "PetStatus"}},
+				{ID: "Tags", Orig: "tags", Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "string"}}, Constraints: &pkg.Constraints{
+					Required: true, MaxItems: &three, Items: &pkg.Constraints{MinLength: &one},
+				}},
+				{ID: "Owners", Orig: "owners", Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "Owner"}}},
+				{ID: "Size", Orig: "size", Type: &pkg.IdentType{Name: "float64"}},
+			}}},
+			{Name: "PetStatus", Type: &pkg.IdentType{Name: "string"}, Enum: []pkg.ConstDecl{
+				{Name: "PetStatusOn", Type: &pkg.IdentType{Name: "PetStatus"}, Value: "on"},
+				{Name: "PetStatusOff", Type: &pkg.IdentType{Name: "PetStatus"}, Value: "off"},
+			}},
+			{Name: "Unused", Type: &pkg.StructType{Fields: []pkg.Field{
+				{ID: "Name", Type: &pkg.IdentType{Name: "string"}, Constraints: &pkg.Constraints{MinLength: &one}},
+			}}},
+		},
+		Clients: []pkg.Client{{Methods: []pkg.Method{{Params: []pkg.Param{
+			{ID: "pet", Arg: "pet", Kind: pkg.Body, Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "Pet"}}},
+		}}}}},
+	}
+
+	vd := newValidator(p)
+	if !vd.validated["Owner"] || !vd.validated["Pet"] || vd.validated["Unused"] {
+		t.Fatal("got validated types:", vd.validated)
+	}
+
+	f := jen.NewFile("test")
+	defineValidate(f, vd, &p.TypeDecls[1])
+	definePatterns(f, vd)
+
+	var buf bytes.Buffer
+	if err := f.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := format.Source([]byte(`package test
+
+import (
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Validate checks the values of p against the constraints of the API,
+// returning a *ValidationError for the first that is not met.
+func (p *Pet) Validate() error {
+	if utf8.RuneCountInString(p.Name) > 10 {
+		return &ValidationError{
+			Field:  "name",
+			Reason: "must be at most 10 characters long",
+		}
+	}
+	if !petNamePattern.MatchString(p.Name) {
+		return &ValidationError{
+			Field:  "name",
+			Reason: "must match the pattern ^[a-z]+$",
+		}
+	}
+	if p.Age != nil {
+		if *p.Age < 0 {
+			return &ValidationError{
+				Field:  "age",
+				Reason: "must be at least 0",
+			}
+		}
+		if *p.Age >= 100 {
+			return &ValidationError{
+				Field:  "age",
+				Reason: "must be less than 100",
+			}
+		}
+	}
+	if !p.Status.IsValid() {
+		return &ValidationError{
+			Field:  "status",
+			Reason: "must be one of on, off",
+		}
+	}
+	if p.Tags == nil {
+		return &ValidationError{
+			Field:  "tags",
+			Reason: "is required",
+		}
+	}
+	if len(p.Tags) > 3 {
+		return &ValidationError{
+			Field:  "tags",
+			Reason: "must have at most 3 items",
+		}
+	}
+	for i, v := range p.Tags {
+		if utf8.RuneCountInString(v) < 1 {
+			return &ValidationError{
+				Field:  "tags[" + strconv.Itoa(i) + "]",
+				Reason: "must be at least 1 characters long",
+			}
+		}
+	}
+	for i, v := range p.Owners {
+		if err := v.Validate(); err != nil {
+			return validationPath("owners["+strconv.Itoa(i)+"]", err)
+		}
+	}
+	return nil
+}
+
+var (
+	petNamePattern = regexp.MustCompile("^[a-z]+$")
+)
+`))
+	if buf.String() != string(expected) {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestParamChecks(t *testing.T) {
+	three := int64(3)
+	half := 0.5
+
+	tcs := []struct {
+		name string
+		in   []pkg.Param
+		out  string
+	}{
+		{"no constraints",
+			[]pkg.Param{{ID: "id", Arg: "id", Kind: pkg.Path, Type: &pkg.IdentType{Name: "string"}}},
+			``,
+		},
+		{"path arg",
+			[]pkg.Param{{ID: "id", Arg: "id", Kind: pkg.Path, Type: &pkg.IdentType{Name: "string"},
+				Constraints: &pkg.Constraints{MinLength: &three}}},
+			`
+				if utf8.RuneCountInString(id) < 3 {
+					return &ValidationError{
+						Field:  "id",
+						Reason: "must be at least 3 characters long",
+					}
+				}
+			`,
+		},
+		{"query arg",
+			[]pkg.Param{{ID: "type", Orig: "type", Arg: "typ", Kind: pkg.Query, Type: &pkg.IdentType{Name: "float64"},
+				Constraints: &pkg.Constraints{MultipleOf: &half}}},
+			`
+				if q := typ / 0.5; math.Abs(q-math.Round(q)) > 1e-09 {
+					return &ValidationError{
+						Field:  "type",
+						Reason: "must be a multiple of 0.5",
+					}
+				}
+			`,
+		},
+		{"opts",
+			[]pkg.Param{{ID: "opts", Arg: "opts", Kind: pkg.Opts, Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "ListOpts"}}}},
+			`
+				if opts != nil {
+					if err := opts.Validate(); err != nil {
+						return err
+					}
+				}
+			`,
+		},
+	}
+
+	vd := &validator{
+		decls:     map[string]*pkg.TypeDecl{"ListOpts": {Name: "ListOpts", Type: &pkg.StructType{}}},
+		validated: map[string]bool{"ListOpts": true},
+		patterns:  make(map[string]string),
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := jen.Id("v").Op("=").Func().Params().BlockFunc(func(g *jen.Group) {
+				for _, c := range vd.paramChecks(&pkg.Method{Name: "List", Params: tc.in}, returnErr) {
+					g.Add(c)
+				}
+			})
+
+			out := fmt.Sprintf("%#v", pc)
+			formatted, _ := format.Source([]byte("v = func() {" + tc.out + "}"))
+			if out != string(formatted) {
+				t.Error("got:", out, "expected:", string(formatted))
+			}
+		})
+	}
+}
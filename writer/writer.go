@@ -11,9 +11,10 @@ import (
 	"github.com/jbowes/oag/pkg"
 )
 
-// Write writes the API Package definition p to the provided writer.
-func Write(w io.Writer, p *pkg.Package, boilerplate *config.Boilerplate) error {
-	f, err := convertPkg(p, boilerplate)
+// Write writes the API Package definition p to the provided writer, with the
+// code generation options of cfg.
+func Write(w io.Writer, p *pkg.Package, cfg *config.Config) error {
+	f, err := convertPkg(p, cfg)
 	if err != nil {
 		return err
 	}
@@ -21,8 +22,11 @@ func Write(w io.Writer, p *pkg.Package, boilerplate *config.Boilerplate) error {
 	return f.Render(w)
 }
 
-func convertPkg(p *pkg.Package, boilerplate *config.Boilerplate) (*jen.File, error) {
+func convertPkg(p *pkg.Package, cfg *config.Config) (*jen.File, error) {
 	f := jen.NewFilePathName(p.Qualifier, p.Name)
+	boilerplate := &cfg.Boilerplate
+	vd := newValidator(p)
+	poly := newPolymorphism(p)
 
 	f.Comment("This file is automatically generated by oag (https://github.com/jbowes/oag)")
 	f.Comment("DO NOT EDIT")
@@ -32,27 +36,86 @@ func convertPkg(p *pkg.Package, boilerplate *config.Boilerplate) (*jen.File, err
 		f.Const().Id("base" + boilerplate.ClientPrefix + "URL").Op("=").Lit(p.BaseURL)
 	}
 
+	for _, c := range p.Consts {
+		f.Comment(formatComment(c.Comment))
+		f.Const().Id(c.Name).Do(writeType(c.Type)).Op("=").Lit(c.Value)
+	}
+
+	unions := false
 	for _, d := range p.TypeDecls {
 		f.Comment(formatComment(d.Comment))
+		if d.Discriminator != nil {
+			definePolymorphic(f, &d)
+			continue
+		}
+		if u, ok := d.Type.(*pkg.UnionType); ok {
+			defineUnion(f, poly, &d, u)
+			unions = true
+			continue
+		}
+
 		td := f.Type().Id(d.Name)
 		td.Do(writeType(d.Type))
+
+		defineFieldDecoding(f, poly, &d)
+		if len(d.Enum) > 0 {
+			defineEnum(f, &d)
+		}
+		if vd.validated[d.Name] {
+			defineValidate(f, vd, &d)
+		}
 	}
 
 	for _, iter := range p.Iters {
-		defineIter(f, &iter)
+		defineIter(f, &iter, p.TypeDecls, poly, cfg.Pagination, cfg.RangeIterators)
 	}
 
+	forms, files, streams := false, false, false
+	doStream := false // a method reads the response body itself
 	for _, c := range p.Clients {
-		f.Comment(c.Comment)
-		f.Type().Id(c.Name).Id("endpoint")
+		f.Comment(formatComment(c.Comment))
+		defineClientType(f, &c, p.Clients)
 
 		for _, m := range c.Methods {
-			convertClientMethod(f, &m, p.TypeDecls)
+			convertClientMethod(f, &m, p.TypeDecls, p.Iters, vd, poly, cfg.Validate, cfg.Pagination.Strategy != "")
+
+			forms = forms || m.Form != ""
+			if _, ok := m.Return[0].(*pkg.StreamType); ok {
+				streams = true
+			}
+			doStream = doStream || streams || len(m.Results) > 0
+			for _, p := range m.Params {
+				files = files || p.Kind == pkg.Form && isFile(p.Type)
+			}
 		}
 	}
 
+	definePatterns(f, vd)
+	if unions {
+		defineUnmarshalStrict(f)
+	}
+	if forms {
+		defineFormBody(f)
+	}
+	if files {
+		defineWriteFormFile(f)
+	}
+	if streams {
+		defineStream(f)
+	}
+	if len(p.Iters) > 0 && cfg.Pagination.Strategy != "" {
+		definePaging(f, cfg.Pagination)
+	}
+	if vd.used && boilerplate.ValidationError != pkg.Disabled {
+		defineValidationError(f)
+	}
+
+	if p.DateType != "" && boilerplate.Date != pkg.Disabled {
+		defineDate(f, p.DateType)
+	}
+
 	if boilerplate.Backend != pkg.Disabled {
-		defineBackend(f, boilerplate.ClientPrefix)
+		defineBackend(f, boilerplate.ClientPrefix, doStream)
 	}
 
 	if boilerplate.Endpoint != pkg.Disabled {
@@ -64,12 +127,12 @@ func convertPkg(p *pkg.Package, boilerplate *config.Boilerplate) (*jen.File, err
 	return f, nil
 }
 
-func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
+func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl, iters []pkg.Iter, vd *validator, poly polymorphism, validate, paged bool) {
 	f.Comment(formatComment(m.Comment))
 	fn := f.Func().Params(jen.Id(m.Receiver.ID).Op("*").Id(m.Receiver.Type)).Id(m.Name)
 
-	var fmtArgs, queryArgs, headerArgs []pkg.Param
-	var optQueryArgs []pkg.Field
+	var fmtArgs, queryArgs, headerArgs, formArgs, fileArgs []pkg.Param
+	var optQueryArgs, optFormArgs []pkg.Field
 
 	body := jen.Nil()
 
@@ -84,6 +147,13 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 			queryArgs = append(queryArgs, p)
 		case pkg.Header:
 			headerArgs = append(headerArgs, p)
+		case pkg.Form:
+			if isFile(p.Type) {
+				fileArgs = append(fileArgs, p)
+				params = append(params, v, jen.Id(filename(p.Arg)).String())
+				continue
+			}
+			formArgs = append(formArgs, p)
 		case pkg.Body:
 			body = jen.Id(p.Arg)
 		case pkg.Opts:
@@ -98,6 +168,8 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 					switch f.Kind {
 					case pkg.Query:
 						optQueryArgs = append(optQueryArgs, f)
+					case pkg.Form:
+						optFormArgs = append(optFormArgs, f)
 					default:
 						panic("unhandled location for optional arg")
 					}
@@ -117,8 +189,12 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 
 		if _, ok := ret.(*pkg.PointerType); ok {
 			successRets = append(successRets, jen.Nil())
+		} else if _, ok := ret.(*pkg.StreamType); ok {
+			successRets = append(successRets, jen.Nil())
 		} else if _, ok := ret.(*pkg.IterType); ok {
 			successRets = append(successRets, jen.Nil())
+		} else if poly.isInterface(ret) {
+			successRets = append(successRets, jen.Nil())
 		} else if typeName(ret) == "error" {
 			successRets = append(successRets, jen.Nil())
 		}
@@ -129,15 +205,25 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 	copy(errRet, successRets)
 	errRet[len(errRet)-1] = jen.Err()
 
+	// the returns holding the body, without any meta and error
+	bodyRets := len(m.Return) - 1
+	if m.Meta != nil {
+		bodyRets--
+		successRets[len(successRets)-2] = jen.Id("meta")
+	}
+
 	fn.BlockFunc(func(g *jen.Group) {
 		reqDef := jen.Line()
 		reqOp := ":="
 		_, iter := m.Return[0].(*pkg.IterType)
 		errResp := jen.Err()
 
+		// the iterator returned, if it holds the responses wrapping its items
+		var envelope *pkg.Iter
+
 		var respDef jen.Code
 		var doResp jen.Code = jen.Nil()
-		if len(m.Return) > 1 || iter {
+		if bodyRets > 0 || iter {
 			ret := m.Return[0]
 			switch t := ret.(type) {
 			case *pkg.IterType:
@@ -146,6 +232,9 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 					jen.Id("i"):     jen.Lit(-1),
 					jen.Id("first"): jen.True(),
 				})
+				if paged {
+					g.List(jen.Id("iter").Dot("ctx"), jen.Id("iter").Dot("cancel")).Op("=").Qual("context", "WithCancel").Call(jen.Id("ctx"))
+				}
 
 				g.Line()
 
@@ -153,6 +242,12 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 				reqOp = "="
 
 				doResp = jen.Op("&").Id("iter").Dot("page")
+				for i, it := range iters {
+					if it.Name == typeName(t) && it.Envelope != nil {
+						envelope = &iters[i]
+						doResp = jen.Op("&").Id("iter").Dot("envelope")
+					}
+				}
 				errResp = jen.Id("iter").Dot("err")
 				errRet[0] = jen.Op("&").Id("iter")
 				successRets[0] = jen.Op("&").Id("iter")
@@ -161,6 +256,19 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 
 				doResp = jen.Op("&").Id("resp")
 				successRets[0] = doResp
+			case *pkg.IdentType:
+				if !poly.isInterface(t) {
+					respDef = jen.Var().Id("resp").Do(writeType(ret))
+
+					doResp = jen.Id("resp")
+					successRets[0] = doResp
+					break
+				}
+
+				respDef = jen.Var().Id("resp").Id(holder(t.Name))
+
+				doResp = jen.Op("&").Id("resp")
+				successRets[0] = jen.Id("resp").Dot(t.Name)
 			default:
 				respDef = jen.Var().Id("resp").Do(writeType(ret))
 
@@ -169,10 +277,30 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 			}
 		}
 
+		if validate {
+			fail := func(err jen.Code) []jen.Code {
+				return []jen.Code{jen.Return(append(errRet[:len(errRet)-1:len(errRet)-1], err)...)}
+			}
+			if iter {
+				fail = func(err jen.Code) []jen.Code {
+					return []jen.Code{errResp.Clone().Op("=").Add(err), jen.Return(errRet...)}
+				}
+			}
+
+			if checks := vd.paramChecks(m, fail); len(checks) > 0 {
+				for _, c := range checks {
+					g.Add(c)
+				}
+				g.Line()
+			}
+		}
+
 		setPathArgs(g, errRet, m.Path, fmtArgs)
 
-		setQueryArgs(g, errRet, queryArgs)
-		query := setOptQueryArgs(g, errRet, len(queryArgs) > 0, optQueryArgs)
+		setQueryArgs(g, errRet, "q", queryArgs)
+		query := setOptQueryArgs(g, errRet, "q", len(queryArgs) > 0, optQueryArgs)
+
+		contentType, form := setFormArgs(g, errRet, m.Form, formArgs, optFormArgs, fileArgs)
 
 		g.Add(reqDef)
 		g.List(jen.Id("req"), errResp.Clone()).Op(reqOp).Id(m.Receiver.ID).Dot("backend").Dot("NewRequest").Call(
@@ -186,12 +314,62 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 		})
 		g.Line()
 
+		if contentType != nil {
+			g.Id("setFormBody").Call(jen.Id("req"), contentType, form)
+			g.Line()
+		}
+
 		setHeaderArgs(g, errRet, headerArgs)
 
+		if _, ok := m.Return[0].(*pkg.StreamType); ok {
+			g.List(jen.Id("resp"), jen.Err()).Op(":=").Id(m.Receiver.ID).Dot("backend").Dot("DoStream").Call(
+				jen.Id("ctx"),
+				jen.Id("req"),
+				errSelectFunc(m),
+			)
+			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(errRet...))
+			g.Line()
+
+			if m.Meta != nil {
+				setMeta(g, m, "resp", false, []jen.Code{
+					jen.Id("resp").Dot("Body").Dot("Close").Call(),
+					jen.Return(errRet...),
+				}, decls)
+			}
+
+			successRets[0] = jen.Op("&").Id("Stream").Values(jen.Dict{
+				jen.Id("ReadCloser"):    jen.Id("resp").Dot("Body"),
+				jen.Id("ContentType"):   jen.Id("resp").Dot("Header").Dot("Get").Call(jen.Lit("Content-Type")),
+				jen.Id("ContentLength"): jen.Id("resp").Dot("ContentLength"),
+			})
+			g.Return(successRets...)
+			return
+		}
+
+		if iter && paged {
+			// The first page is requested by the first call to Next.
+			g.List(jen.Id("iter").Dot("backend"), jen.Id("iter").Dot("req"), jen.Id("iter").Dot("errFn")).Op("=").List(
+				jen.Id(m.Receiver.ID).Dot("backend"),
+				jen.Id("req"),
+				errSelectFunc(m),
+			)
+			g.Return(successRets...)
+			return
+		}
+
+		if len(m.Results) > 0 {
+			setResult(g, m, errRet, successRets, decls, poly)
+			return
+		}
+
 		if respDef != nil {
 			g.Add(respDef)
 		}
-		g.List(jen.Id("_"), errResp.Clone()).Op("=").Id(m.Receiver.ID).Dot("backend").Dot("Do").Call(
+		httpResp, op := jen.Id("_"), "="
+		if m.Meta != nil {
+			httpResp, op = jen.Id("httpResp"), ":="
+		}
+		g.List(httpResp, errResp.Clone()).Op(op).Id(m.Receiver.ID).Dot("backend").Dot("Do").Call(
 			jen.Id("ctx"),
 			jen.Id("req"),
 			doResp,
@@ -204,12 +382,99 @@ func convertClientMethod(f *jen.File, m *pkg.Method, decls []pkg.TypeDecl) {
 			})
 			g.Line()
 		}
+		if envelope != nil {
+			setPage(g, envelope, "iter", decls)
+		}
+
+		if m.Meta != nil {
+			setMeta(g, m, "httpResp", true, []jen.Code{jen.Return(errRet...)}, decls)
+		}
 
 		g.Return(successRets...)
 	})
 
 }
 
+// setResult sends the request of m, decoding the body of the response into
+// the field of its result type for the status code, and returns the result.
+// Fields holding interface values are decoded by their holder type.
+func setResult(g *jen.Group, m *pkg.Method, errRet, successRets []jen.Code, decls []pkg.TypeDecl, poly polymorphism) {
+	g.List(jen.Id("resp"), jen.Err()).Op(":=").Id(m.Receiver.ID).Dot("backend").Dot("DoStream").Call(
+		jen.Id("ctx"),
+		jen.Id("req"),
+		errSelectFunc(m),
+	)
+	g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(errRet...))
+	g.Defer().Id("resp").Dot("Body").Dot("Close").Call()
+	g.Line()
+
+	if m.Meta != nil {
+		setMeta(g, m, "resp", false, []jen.Code{jen.Return(errRet...)}, decls)
+	}
+
+	result := typeName(m.Return[0].(*pkg.PointerType).Type)
+	fields := make(map[string]pkg.Type)
+	for _, d := range decls {
+		if st, ok := d.Type.(*pkg.StructType); ok && d.Name == result {
+			for _, f := range st.Fields {
+				fields[f.ID] = f.Type
+			}
+		}
+	}
+
+	g.Id("result").Op(":=").Id(result).Values(jen.Dict{
+		jen.Id("Status"): jen.Id("resp").Dot("StatusCode"),
+	})
+	g.Var().Id("v").Interface()
+	g.Switch(jen.Id("resp").Dot("StatusCode")).BlockFunc(func(g *jen.Group) {
+		for _, r := range m.Results {
+			field := jen.Id("result").Dot(r.Field)
+			typ := fields[r.Field]
+			st, slice := typ.(*pkg.SliceType)
+			if slice {
+				typ = st.Type
+			}
+			if !poly.isInterface(typ) {
+				g.Case(jen.Lit(r.Code)).Block(jen.Id("v").Op("=").Op("&").Add(field))
+				continue
+			}
+
+			name := typeName(typ)
+			g.Case(jen.Lit(r.Code)).BlockFunc(func(g *jen.Group) {
+				decode := func(v string) {
+					g.If(
+						jen.Err().Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Id("resp").Dot("Body")).Dot("Decode").Call(jen.Op("&").Id(v)),
+						jen.Err().Op("!=").Nil(),
+					).Block(jen.Return(errRet...))
+				}
+
+				if !slice {
+					g.Var().Id("val").Id(holder(name))
+					decode("val")
+					g.Add(field).Op("=").Id("val").Dot(name)
+					return
+				}
+
+				g.Var().Id("vals").Index().Id(holder(name))
+				decode("vals")
+				g.For(jen.List(jen.Id("_"), jen.Id("val")).Op(":=").Range().Id("vals")).Block(
+					field.Clone().Op("=").Append(field.Clone(), jen.Id("val").Dot(name)),
+				)
+			})
+		}
+	})
+	g.If(jen.Id("v").Op("!=").Nil()).Block(
+		jen.Id("dec").Op(":=").Qual("encoding/json", "NewDecoder").Call(jen.Id("resp").Dot("Body")),
+		jen.If(jen.Err().Op(":=").Id("dec").Dot("Decode").Call(jen.Id("v")), jen.Err().Op("!=").Nil()).Block(
+			jen.Return(errRet...),
+		),
+	)
+	g.Line()
+
+	successRets[0] = jen.Op("&").Id("result")
+	g.Return(successRets...)
+}
+
 func setPathArgs(g *jen.Group, errRet []jen.Code, path string, args []pkg.Param) {
 	if len(args) == 0 {
 		g.Id("p").Op(":=").Lit(path)
@@ -219,26 +484,34 @@ func setPathArgs(g *jen.Group, errRet []jen.Code, path string, args []pkg.Param)
 	pArgs := []jen.Code{jen.Lit(path)}
 
 	for _, fa := range args {
-		if t, ok := fa.Type.(*pkg.IdentType); ok && t.Marshal {
-			g.List(jen.Id(fa.ID+"Bytes"), jen.Err()).Op(":=").Id(fa.ID).Dot("MarshalText").Call()
+		t, ok := fa.Type.(*pkg.IdentType)
+		switch {
+		case ok && t.Marshal:
+			g.List(jen.Id(fa.Arg+"Bytes"), jen.Err()).Op(":=").Id(fa.Arg).Dot("MarshalText").Call()
 			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(errRet...))
-			pArgs = append(pArgs, jen.String().Params(jen.Id(fa.ID+"Bytes")))
+			pArgs = append(pArgs, jen.String().Params(jen.Id(fa.Arg+"Bytes")))
 			g.Line()
-		} else {
-			pArgs = append(pArgs, jen.Id(fa.ID))
+		case ok, isBytes(fa.Type):
+			pArgs = append(pArgs, stringFor(fa.Type, jen.Id(fa.Arg)))
+		case fa.Collection != pkg.None:
+			pArgs = append(pArgs, joinValues(g, errRet, fa.Type.(*pkg.SliceType), jen.Id(fa.Arg), fa.Arg+"Strings", fa.Collection))
+		default:
+			pArgs = append(pArgs, jen.Id(fa.Arg))
 		}
 	}
 
 	g.Id("p").Op(":=").Qual("fmt", "Sprintf").Call(pArgs...)
 }
 
-func setQueryArgs(g *jen.Group, errRet []jen.Code, args []pkg.Param) {
+// setQueryArgs adds the values of the query or form args to the url.Values
+// named values.
+func setQueryArgs(g *jen.Group, errRet []jen.Code, values string, args []pkg.Param) {
 	if len(args) == 0 {
 		return
 	}
 
 	g.Line()
-	g.Id("q").Op(":=").Make(jen.Qual("net/url", "Values"))
+	g.Id(values).Op(":=").Make(jen.Qual("net/url", "Values"))
 	for i, q := range args {
 		orig := q.ID
 		if q.Orig != "" {
@@ -248,25 +521,25 @@ func setQueryArgs(g *jen.Group, errRet []jen.Code, args []pkg.Param) {
 		switch q.Collection {
 		case pkg.None:
 			if t, ok := q.Type.(*pkg.IdentType); ok && t.Marshal {
-				g.List(jen.Id(q.ID+"Bytes"), jen.Err()).Op(":=").Id(q.ID).Dot("MarshalText").Call()
+				g.List(jen.Id(q.Arg+"Bytes"), jen.Err()).Op(":=").Id(q.Arg).Dot("MarshalText").Call()
 				g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(errRet...))
-				g.Id("q").Dot("Set").Call(jen.Lit(orig), jen.String().Params(jen.Id(q.Arg+"Bytes")))
+				g.Id(values).Dot("Set").Call(jen.Lit(orig), jen.String().Params(jen.Id(q.Arg+"Bytes")))
 
 				if i != len(args)-1 {
 					g.Line()
 				}
 			} else {
-				g.Id("q").Dot("Set").Call(jen.Lit(orig), stringFor(q.Type, jen.Id(q.Arg)))
+				g.Id(values).Dot("Set").Call(jen.Lit(orig), stringFor(q.Type, jen.Id(q.Arg)))
 			}
 		case pkg.Multi:
 			st := q.Type.(*pkg.SliceType)
-			g.For(jen.List(jen.Id("_"), jen.Id("v")).Op(":=").Range().Id(q.ID)).BlockFunc(func(g *jen.Group) {
+			g.For(jen.List(jen.Id("_"), jen.Id("v")).Op(":=").Range().Id(q.Arg)).BlockFunc(func(g *jen.Group) {
 				if t, ok := st.Type.(*pkg.IdentType); ok && t.Marshal {
 					g.List(jen.Id("b"), jen.Err()).Op(":=").Id("v").Dot("MarshalText").Call()
 					g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(errRet...))
-					g.Id("q").Dot("Add").Call(jen.Lit(orig), jen.String().Params(jen.Id("b")))
+					g.Id(values).Dot("Add").Call(jen.Lit(orig), jen.String().Params(jen.Id("b")))
 				} else {
-					g.Id("q").Dot("Add").Call(jen.Lit(orig), stringFor(st.Type, jen.Id("v")))
+					g.Id(values).Dot("Add").Call(jen.Lit(orig), stringFor(st.Type, jen.Id("v")))
 				}
 			})
 
@@ -274,27 +547,38 @@ func setQueryArgs(g *jen.Group, errRet []jen.Code, args []pkg.Param) {
 				g.Line()
 			}
 
+		case pkg.CSV, pkg.SSV, pkg.TSV, pkg.Pipes:
+			joined := joinValues(g, errRet, q.Type.(*pkg.SliceType), jen.Id(q.Arg), q.Arg+"Strings", q.Collection)
+			g.Id(values).Dot("Set").Call(jen.Lit(orig), joined)
+
+			if i != len(args)-1 {
+				g.Line()
+			}
+
 		default:
 			panic("unhandled collection format")
 		}
 	}
 }
 
-func setOptQueryArgs(g *jen.Group, errRet []jen.Code, qDefined bool, args []pkg.Field) jen.Code {
+// setOptQueryArgs adds the values of the optional query or form args set in
+// opts to the url.Values named values, declaring it if it is not already
+// defined, and returns it.
+func setOptQueryArgs(g *jen.Group, errRet []jen.Code, values string, defined bool, args []pkg.Field) jen.Code {
 	if len(args) == 0 {
-		if !qDefined {
+		if !defined {
 			return jen.Nil()
 		}
 
-		return jen.Id("q")
+		return jen.Id(values)
 	}
 
 	g.Line()
 
 	init := jen.Empty()
-	if !qDefined {
-		g.Var().Id("q").Qual("net/url", "Values")
-		init = jen.Id("q").Op("=").Make(jen.Qual("net/url", "Values"))
+	if !defined {
+		g.Var().Id(values).Qual("net/url", "Values")
+		init = jen.Id(values).Op("=").Make(jen.Qual("net/url", "Values"))
 	}
 
 	g.If(jen.Id("opts").Op("!=").Nil()).BlockFunc(func(g *jen.Group) {
@@ -317,9 +601,11 @@ func setOptQueryArgs(g *jen.Group, errRet []jen.Code, qDefined bool, args []pkg.
 					if t, ok := typ.(*pkg.IdentType); ok && t.Marshal {
 						g.List(jen.Id("b"), jen.Err()).Op(":=").Id("opts").Dot(q.ID).Dot("MarshalText").Call()
 						g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(errRet...))
-						g.Id("q").Dot("Set").Call(jen.Lit(orig), jen.String().Params(jen.Id("b")))
+						g.Id(values).Dot("Set").Call(jen.Lit(orig), jen.String().Params(jen.Id("b")))
+					} else if isEnum(typ) {
+						g.Id(values).Dot("Set").Call(jen.Lit(orig), stringFor(typ, jen.Id("opts").Dot(q.ID)))
 					} else {
-						g.Id("q").Dot("Set").Call(jen.Lit(orig), stringFor(typ, jen.Op("*").Id("opts").Dot(q.ID)))
+						g.Id(values).Dot("Set").Call(jen.Lit(orig), stringFor(typ, jen.Op("*").Id("opts").Dot(q.ID)))
 					}
 				})
 			case pkg.Multi:
@@ -329,19 +615,25 @@ func setOptQueryArgs(g *jen.Group, errRet []jen.Code, qDefined bool, args []pkg.
 						if t, ok := st.Type.(*pkg.IdentType); ok && t.Marshal {
 							g.List(jen.Id("b"), jen.Err()).Op(":=").Id("v").Dot("MarshalText").Call()
 							g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(errRet...))
-							g.Id("q").Dot("Add").Call(jen.Lit(orig), jen.String().Params(jen.Id("b")))
+							g.Id(values).Dot("Add").Call(jen.Lit(orig), jen.String().Params(jen.Id("b")))
 						} else {
-							g.Id("q").Dot("Add").Call(jen.Lit(orig), stringFor(st.Type, jen.Id("v")))
+							g.Id(values).Dot("Add").Call(jen.Lit(orig), stringFor(st.Type, jen.Id("v")))
 						}
 					})
 				})
+			case pkg.CSV, pkg.SSV, pkg.TSV, pkg.Pipes:
+				st := typ.(*pkg.SliceType)
+				g.If(jen.Id("opts").Dot(q.ID).Op("!=").Nil()).BlockFunc(func(g *jen.Group) {
+					joined := joinValues(g, errRet, st, jen.Op("*").Id("opts").Dot(q.ID), "strs", q.Collection)
+					g.Id(values).Dot("Set").Call(jen.Lit(orig), joined)
+				})
 			default:
 				panic("unhandled collection format")
 			}
 		}
 	})
 
-	return jen.Id("q")
+	return jen.Id(values)
 }
 
 func setHeaderArgs(g *jen.Group, errRet []jen.Code, args []pkg.Param) {
@@ -427,8 +719,45 @@ func errSelectFunc(m *pkg.Method) jen.Code {
 	})
 }
 
+// separators join the values of delimited collections.
+var separators = map[pkg.Collection]string{
+	pkg.CSV:   ",",
+	pkg.SSV:   " ",
+	pkg.TSV:   "\t",
+	pkg.Pipes: "|",
+}
+
+// joinValues returns the values of the slice v, of type st, joined by the
+// separator of the collection format cf. Values that aren't strings are
+// converted into a slice named strs first.
+func joinValues(g *jen.Group, errRet []jen.Code, st *pkg.SliceType, v jen.Code, strs string, cf pkg.Collection) jen.Code {
+	sep := jen.Lit(separators[cf])
+
+	it, ok := st.Type.(*pkg.IdentType)
+	if ok && !it.Marshal && it.Qualifier == "" && (it.Name == "" || it.Name == "string") {
+		return jen.Qual("strings", "Join").Call(v, sep)
+	}
+
+	g.Id(strs).Op(":=").Make(jen.Index().String(), jen.Lit(0), jen.Len(v))
+	g.For(jen.List(jen.Id("_"), jen.Id("v")).Op(":=").Range().Add(v)).BlockFunc(func(g *jen.Group) {
+		if ok && it.Marshal {
+			g.List(jen.Id("b"), jen.Err()).Op(":=").Id("v").Dot("MarshalText").Call()
+			g.If(jen.Err().Op("!=").Nil()).Block(jen.Return(errRet...))
+			g.Id(strs).Op("=").Append(jen.Id(strs), jen.String().Params(jen.Id("b")))
+		} else {
+			g.Id(strs).Op("=").Append(jen.Id(strs), stringFor(st.Type, jen.Id("v")))
+		}
+	})
+
+	return jen.Qual("strings", "Join").Call(jen.Id(strs), sep)
+}
+
 // stringFor converts basic IdentTypes to  strings
 func stringFor(typ pkg.Type, id jen.Code) jen.Code {
+	if isBytes(typ) {
+		return jen.Qual("encoding/base64", "StdEncoding").Dot("EncodeToString").Call(id)
+	}
+
 	it, ok := typ.(*pkg.IdentType)
 	if !ok {
 		panic("unknown type for string conversion")
@@ -437,11 +766,54 @@ func stringFor(typ pkg.Type, id jen.Code) jen.Code {
 	switch it.Name {
 	case "int":
 		return jen.Qual("strconv", "Itoa").Call(id)
+	case "int8", "int16", "int32":
+		return jen.Qual("strconv", "FormatInt").Call(jen.Int64().Call(id), jen.Lit(10))
+	case "int64":
+		return jen.Qual("strconv", "FormatInt").Call(id, jen.Lit(10))
+	case "uint", "uint8", "uint16", "uint32":
+		return jen.Qual("strconv", "FormatUint").Call(jen.Uint64().Call(id), jen.Lit(10))
+	case "uint64":
+		return jen.Qual("strconv", "FormatUint").Call(id, jen.Lit(10))
+	case "float32":
+		return jen.Qual("strconv", "FormatFloat").Call(jen.Float64().Call(id), jen.LitRune('f'), jen.Lit(-1), jen.Lit(32))
 	case "float64":
 		return jen.Qual("strconv", "FormatFloat").Call(id, jen.LitRune('f'), jen.Lit(-1), jen.Lit(64))
 	case "bool":
 		return jen.Qual("strconv", "FormatBool").Call(id)
-	default: // treat as string
-		return id
+	default:
+		if isEnum(it) {
+			return jen.Add(id).Dot("String").Call()
+		}
+		return id // treat as string
+	}
+}
+
+// isEnum reports if typ is an enum type, with a String method. Other
+// declared types that are not structs are inlined by the mutator, so any
+// remaining local, non builtin type is an enum.
+func isEnum(typ pkg.Type) bool {
+	it, ok := typ.(*pkg.IdentType)
+	if !ok || it.Qualifier != "" {
+		return false
 	}
+
+	switch it.Name {
+	case "", "string", "bool", "float32", "float64",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64":
+		return false
+	default:
+		return !it.Marshal
+	}
+}
+
+// isBytes reports if typ is a byte slice, encoded as base64.
+func isBytes(typ pkg.Type) bool {
+	st, ok := typ.(*pkg.SliceType)
+	if !ok {
+		return false
+	}
+
+	it, ok := st.Type.(*pkg.IdentType)
+	return ok && it.Qualifier == "" && it.Name == "byte"
 }
@@ -3,12 +3,91 @@ package writer
 import (
 	"fmt"
 	"go/format"
+	"strings"
 	"testing"
 
 	"github.com/dave/jennifer/jen"
 	"github.com/jbowes/oag/pkg"
 )
 
+func TestSetPathArgs(t *testing.T) {
+	tcs := []struct {
+		name string
+		path string
+		in   []pkg.Param
+		out  string
+	}{
+		{"no args", "/pets", nil, `
+				p := "/pets"
+			`,
+		},
+		{"string arg", "/pets/%s",
+			[]pkg.Param{{ID: "id", Arg: "id", Type: &pkg.IdentType{Name: "string"}}},
+			`
+				p := fmt.Sprintf("/pets/%s", id)
+			`,
+		},
+		{"int arg", "/pets/%s",
+			[]pkg.Param{{ID: "id", Arg: "id", Type: &pkg.IdentType{Name: "int"}}},
+			`
+				p := fmt.Sprintf("/pets/%s", strconv.Itoa(id))
+			`,
+		},
+		{"bytes arg", "/pets/%s",
+			[]pkg.Param{{ID: "id", Arg: "id", Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "byte"}}}},
+			`
+				p := fmt.Sprintf("/pets/%s", base64.StdEncoding.EncodeToString(id))
+			`,
+		},
+		{"marshal", "/pets/%s",
+			[]pkg.Param{{ID: "id", Arg: "id", Type: &pkg.IdentType{Marshal: true}}},
+			`
+				idBytes, err := id.MarshalText()
+				if err != nil {
+					return
+				}
+
+				p := fmt.Sprintf("/pets/%s", string(idBytes))
+			`,
+		},
+		{"keyword marshal", "/pets/%s",
+			[]pkg.Param{{ID: "type", Arg: "petsType", Type: &pkg.IdentType{Name: "Date", Marshal: true}}},
+			`
+				petsTypeBytes, err := petsType.MarshalText()
+				if err != nil {
+					return
+				}
+
+				p := fmt.Sprintf("/pets/%s", string(petsTypeBytes))
+			`,
+		},
+		{"csv collection", "/pets/%s",
+			[]pkg.Param{{ID: "ids", Arg: "ids", Collection: pkg.CSV, Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "int"}}}},
+			`
+				idsStrings := make([]string, 0, len(ids))
+				for _, v := range ids {
+					idsStrings = append(idsStrings, strconv.Itoa(v))
+				}
+				p := fmt.Sprintf("/pets/%s", strings.Join(idsStrings, ","))
+			`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			spa := jen.Id("v").Op("=").Func().Params().BlockFunc(func(g *jen.Group) {
+				setPathArgs(g, nil, tc.path, tc.in)
+			})
+
+			out := fmt.Sprintf("%#v", spa)
+			formatted, _ := format.Source([]byte("v = func() {" + tc.out + "}"))
+			if out != string(formatted) {
+				t.Error("got:", out, "expected:", tc.out)
+			}
+		})
+	}
+}
+
 func TestSetQueryArgs(t *testing.T) {
 	tcs := []struct {
 		name string
@@ -52,6 +131,18 @@ func TestSetQueryArgs(t *testing.T) {
     			q.Set("arg", string(argBytes))
 			`,
 		},
+		{"keyword marshal",
+			[]pkg.Param{{ID: "range", Arg: "petsRange", Orig: "range", Type: &pkg.IdentType{Qualifier: "time", Name: "Time", Marshal: true}}},
+			`
+
+				q := make(url.Values)
+				petsRangeBytes, err := petsRange.MarshalText()
+				if err != nil {
+					return
+				}
+				q.Set("range", string(petsRangeBytes))
+			`,
+		},
 		{"space after martial, not between regular",
 			[]pkg.Param{
 				{ID: "arg1", Arg: "arg1", Type: &pkg.IdentType{Marshal: true}},
@@ -97,12 +188,54 @@ func TestSetQueryArgs(t *testing.T) {
 				}
 			`,
 		},
+
+		{"csv collection string arg",
+			[]pkg.Param{{ID: "arg", Arg: "arg", Collection: pkg.CSV, Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "string"}}}},
+			`
+
+				q := make(url.Values)
+				q.Set("arg", strings.Join(arg, ","))
+			`,
+		},
+
+		{"delimited collections",
+			[]pkg.Param{
+				{ID: "arg1", Arg: "arg1", Collection: pkg.SSV, Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "int64"}}},
+				{ID: "arg2", Arg: "arg2", Collection: pkg.TSV, Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "Status"}}},
+				{ID: "arg3", Arg: "arg3", Collection: pkg.Pipes, Type: &pkg.SliceType{Type: &pkg.IdentType{Marshal: true}}},
+			},
+			`
+
+				q := make(url.Values)
+				arg1Strings := make([]string, 0, len(arg1))
+				for _, v := range arg1 {
+					arg1Strings = append(arg1Strings, strconv.FormatInt(v, 10))
+				}
+				q.Set("arg1", strings.Join(arg1Strings, " "))
+
+				arg2Strings := make([]string, 0, len(arg2))
+				for _, v := range arg2 {
+					arg2Strings = append(arg2Strings, v.String())
+				}
+				q.Set("arg2", strings.Join(arg2Strings, "\t"))
+
+				arg3Strings := make([]string, 0, len(arg3))
+				for _, v := range arg3 {
+					b, err := v.MarshalText()
+					if err != nil {
+						return
+					}
+					arg3Strings = append(arg3Strings, string(b))
+				}
+				q.Set("arg3", strings.Join(arg3Strings, "|"))
+			`,
+		},
 	}
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			sqa := jen.Id("v").Op("=").Func().Params().BlockFunc(func(g *jen.Group) {
-				setQueryArgs(g, nil, tc.in)
+				setQueryArgs(g, nil, "q", tc.in)
 			})
 
 			out := fmt.Sprintf("%#v", sqa)
@@ -134,6 +267,19 @@ func TestSetOptQueryArgs(t *testing.T) {
 				}
 			`,
 		},
+		{"enum arg",
+			[]pkg.Field{{ID: "arg", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "Status"}}}},
+			`
+
+				var q url.Values
+				if opts != nil {
+					q = make(url.Values)
+					if opts.arg != nil {
+						q.Set("arg", opts.arg.String())
+					}
+				}
+			`,
+		},
 		{"different name",
 			[]pkg.Field{{ID: "arg", Orig: "arg_thing", Type: &pkg.PointerType{Type: &pkg.IdentType{}}}},
 			`
@@ -229,12 +375,37 @@ func TestSetOptQueryArgs(t *testing.T) {
 				}
 			`,
 		},
+
+		{"csv collection",
+			[]pkg.Field{
+				{ID: "arg1", Collection: pkg.CSV, Type: &pkg.PointerType{Type: &pkg.SliceType{Type: &pkg.IdentType{}}}},
+				{ID: "arg2", Collection: pkg.CSV, Type: &pkg.PointerType{Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "bool"}}}},
+			},
+			`
+
+				var q url.Values
+				if opts != nil {
+					q = make(url.Values)
+					if opts.arg1 != nil {
+						q.Set("arg1", strings.Join(*opts.arg1, ","))
+					}
+
+					if opts.arg2 != nil {
+						strs := make([]string, 0, len(*opts.arg2))
+						for _, v := range *opts.arg2 {
+							strs = append(strs, strconv.FormatBool(v))
+						}
+						q.Set("arg2", strings.Join(strs, ","))
+					}
+				}
+			`,
+		},
 	}
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			soqa := jen.Id("v").Op("=").Func().Params().BlockFunc(func(g *jen.Group) {
-				setOptQueryArgs(g, nil, false, tc.in)
+				setOptQueryArgs(g, nil, "q", false, tc.in)
 			})
 
 			out := fmt.Sprintf("%#v", soqa)
@@ -308,6 +479,111 @@ func TestSetHeaderArgs(t *testing.T) {
 	}
 }
 
+func TestSetResult(t *testing.T) {
+	event := &pkg.TypeDecl{Name: "Event", Discriminator: &pkg.Discriminator{Property: "kind"}}
+	decls := []pkg.TypeDecl{{Name: "ReplaceResult", Type: &pkg.StructType{Fields: []pkg.Field{
+		{ID: "Status", Type: &pkg.IdentType{Name: "int"}},
+		{ID: "OK", Type: &pkg.PointerType{Type: &pkg.IdentType{Name: "Pet"}}},
+		{ID: "Created", Type: &pkg.IdentType{Name: "Event"}},
+		{ID: "Accepted", Type: &pkg.SliceType{Type: &pkg.IdentType{Name: "Event"}}},
+	}}}}
+
+	tcs := []struct {
+		name    string
+		poly    polymorphism
+		results []pkg.Result
+		out     string
+	}{
+		{"bodies", nil, []pkg.Result{{Code: 200, Field: "OK"}, {Code: 201, Field: "Created"}}, `
+			resp, err := c.backend.DoStream(ctx, req, nil)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			result := ReplaceResult{Status: resp.StatusCode}
+			var v interface{}
+			switch resp.StatusCode {
+			case 200:
+				v = &result.OK
+			case 201:
+				v = &result.Created
+			}
+			if v != nil {
+				dec := json.NewDecoder(resp.Body)
+				if err := dec.Decode(v); err != nil {
+					return nil, err
+				}
+			}
+
+			return &result, nil
+		`},
+		{"polymorphic bodies", polymorphism{"Event": event}, []pkg.Result{{Code: 200, Field: "OK"}, {Code: 201, Field: "Created"}, {Code: 202, Field: "Accepted"}}, `
+			resp, err := c.backend.DoStream(ctx, req, nil)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			result := ReplaceResult{Status: resp.StatusCode}
+			var v interface{}
+			switch resp.StatusCode {
+			case 200:
+				v = &result.OK
+			case 201:
+				var val eventJSON
+				if err := json.NewDecoder(resp.Body).Decode(&val); err != nil {
+					return nil, err
+				}
+				result.Created = val.Event
+			case 202:
+				var vals []eventJSON
+				if err := json.NewDecoder(resp.Body).Decode(&vals); err != nil {
+					return nil, err
+				}
+				for _, val := range vals {
+					result.Accepted = append(result.Accepted, val.Event)
+				}
+			}
+			if v != nil {
+				dec := json.NewDecoder(resp.Body)
+				if err := dec.Decode(v); err != nil {
+					return nil, err
+				}
+			}
+
+			return &result, nil
+		`},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			m := pkg.Method{
+				Receiver: struct {
+					ID   string
+					Arg  string
+					Type string
+				}{ID: "c"},
+				Return: []pkg.Type{
+					&pkg.PointerType{Type: &pkg.IdentType{Name: "ReplaceResult"}},
+					&pkg.IdentType{Name: "error"},
+				},
+				Results: tc.results,
+			}
+
+			sr := jen.Id("v").Op("=").Func().Params().BlockFunc(func(g *jen.Group) {
+				setResult(g, &m, []jen.Code{jen.Nil(), jen.Err()}, []jen.Code{jen.Nil(), jen.Nil()}, decls, tc.poly)
+			})
+
+			out := fmt.Sprintf("%#v", sr)
+			formatted, _ := format.Source([]byte("v = func() {" + tc.out + "}"))
+			if out != string(formatted) {
+				t.Error("got:", out, "expected:", string(formatted))
+			}
+		})
+	}
+}
+
 func TestErrSelectFunc(t *testing.T) {
 	tcs := []struct {
 		name string
@@ -411,7 +687,13 @@ func TestStringFor(t *testing.T) {
 		{"string", &pkg.IdentType{Name: "string"}, "x"},
 		{"bool", &pkg.IdentType{Name: "bool"}, "strconv.FormatBool(x)"},
 		{"int", &pkg.IdentType{Name: "int"}, "strconv.Itoa(x)"},
+		{"int32", &pkg.IdentType{Name: "int32"}, "strconv.FormatInt(int64(x), 10)"},
+		{"int64", &pkg.IdentType{Name: "int64"}, "strconv.FormatInt(x, 10)"},
+		{"uint32", &pkg.IdentType{Name: "uint32"}, "strconv.FormatUint(uint64(x), 10)"},
+		{"float32", &pkg.IdentType{Name: "float32"}, "strconv.FormatFloat(float64(x), 'f', -1, 32)"},
 		{"float64", &pkg.IdentType{Name: "float64"}, "strconv.FormatFloat(x, 'f', -1, 64)"},
+		{"bytes", &pkg.SliceType{Type: &pkg.IdentType{Name: "byte"}}, "base64.StdEncoding.EncodeToString(x)"},
+		{"enum", &pkg.IdentType{Name: "Status"}, "x.String()"},
 	}
 
 	for _, tc := range tcs {
@@ -426,3 +708,44 @@ func TestStringFor(t *testing.T) {
 	}
 
 }
+
+func TestSetClient(t *testing.T) {
+	clients := []pkg.Client{
+		{Name: "PetsClient"},
+		{Name: "OrgsClient"},
+		{Name: "OrgsReposClient", Parent: "OrgsClient", Field: "Repos"},
+		{Name: "OrgsReposIssuesClient", Parent: "OrgsReposClient", Field: "Issues"},
+	}
+
+	tcs := []struct {
+		name   string
+		client int
+		out    string
+	}{
+		{"leaf", 0, `
+				c.Pets = (*PetsClient)(&c.common)
+			`,
+		},
+		{"nested", 1, `
+				c.Orgs = &OrgsClient{endpoint: &c.common}
+				c.Orgs.Repos = &OrgsReposClient{endpoint: &c.common}
+				c.Orgs.Repos.Issues = (*OrgsReposIssuesClient)(&c.common)
+			`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &clients[tc.client]
+			sc := jen.Id("v").Op("=").Func().Params().BlockFunc(func(g *jen.Group) {
+				setClient(g, jen.Id("c").Dot(strings.TrimSuffix(c.Name, "Client")), c, clients)
+			})
+
+			out := fmt.Sprintf("%#v", sc)
+			formatted, _ := format.Source([]byte("v = func() {" + tc.out + "}"))
+			if out != string(formatted) {
+				t.Error("got:", out, "expected:", string(formatted))
+			}
+		})
+	}
+}